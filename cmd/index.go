@@ -0,0 +1,383 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+
+	"github.com/killallgit/ryan/pkg/embeddings"
+	"github.com/killallgit/ryan/pkg/indexer"
+	"github.com/killallgit/ryan/pkg/logger"
+	"github.com/killallgit/ryan/pkg/retrieval"
+	"github.com/killallgit/ryan/pkg/vectorstore"
+	"github.com/spf13/cobra"
+)
+
+// progressWidth is the number of cells in the progress bar
+const progressWidth = 30
+
+var indexCmd = &cobra.Command{
+	Use:   "index [paths...]",
+	Short: "Embed files into the vector store for knowledge_search",
+	Long: `Walk the given paths (default: the current directory), honouring .gitignore,
+and embed new and changed files into the persistent vector store. Chunks of
+deleted files are removed. A manifest of content hashes next to the vectors
+keeps re-runs incremental.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		status, _ := cmd.Flags().GetBool("status")
+		clearIndex, _ := cmd.Flags().GetBool("clear")
+
+		if err := logger.Init(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error initializing logger: %v\n", err)
+			os.Exit(1)
+		}
+		defer logger.Close()
+
+		if len(args) == 0 {
+			args = []string{"."}
+		}
+
+		vsConfig := vectorstore.LoadConfig()
+		if !vsConfig.Enabled || !vsConfig.Persistence.Enabled {
+			fmt.Fprintln(os.Stderr, "Error: indexing needs vectorstore.enabled and vectorstore.persistence.enabled set to true")
+			os.Exit(1)
+		}
+		manifestPath := indexer.ManifestPath(vsConfig.Persistence.Path, vsConfig.CollectionName)
+
+		// Status only reads the manifest, so it works without the embedding service
+		var store vectorstore.VectorStore
+		if !status {
+			vsConfig.Embedding.Progress = embedThroughput.set
+			embedder, err := embeddings.NewEmbedder(vsConfig.Embedding)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error initializing embedder: %v\n", err)
+				os.Exit(1)
+			}
+			store, err = vectorstore.NewVectorStore(vsConfig, embedder)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error opening vector store: %v\n", err)
+				os.Exit(1)
+			}
+			defer store.Close()
+		}
+
+		ix, err := indexer.New(store, manifestPath, retrieval.DocumentConfig{})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		switch {
+		case clearIndex:
+			if err := ix.Clear(ctx); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Cleared collection %s\n", vsConfig.CollectionName)
+
+		case status:
+			if err := printIndexStatus(ix, vsConfig, args); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+		default:
+			if isTerminal(os.Stderr) {
+				ix.Progress = printProgress
+			}
+			stats, err := ix.Index(ctx, args)
+			if ix.Progress != nil {
+				fmt.Fprintln(os.Stderr)
+			}
+			fmt.Printf("Indexed %d new, %d changed, %d removed, %d unchanged file(s); embedded %d chunk(s)",
+				stats.Added, stats.Updated, stats.Removed, stats.Unchanged, stats.Chunks)
+			if progress := embedThroughput.get(); progress.Embedded+progress.Cached > 0 {
+				fmt.Printf(" (%d from cache, %.1f/s)", progress.Cached, progress.Rate())
+			}
+			fmt.Println()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+var indexExportCmd = &cobra.Command{
+	Use:   "export <file>",
+	Short: "Write the index to a compressed snapshot other checkouts can import",
+	Long: `Write every indexed document of the collection with its metadata and vector,
+the embedding model and dimensions, and the index manifest to a gzip-compressed
+snapshot. Paths in the manifest are stored relative to the current directory,
+so run this from the repository root.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		vsConfig, _, store, ix := openIndex()
+		defer logger.Close()
+		defer store.Close()
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		if err := exportIndex(ctx, vsConfig, store, ix, args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var indexImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Load a snapshot written by ryan index export without re-embedding",
+	Long: `Add the documents and vectors of a snapshot to the collection and take over
+its manifest, with paths resolved against the current directory, so later runs
+of ryan index only embed files that differ from the snapshot. The snapshot must
+have been embedded with the configured embedding provider and model.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		replace, _ := cmd.Flags().GetBool("replace")
+		force, _ := cmd.Flags().GetBool("force")
+
+		vsConfig, embedder, store, ix := openIndex()
+		defer logger.Close()
+		defer store.Close()
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		if err := importIndex(ctx, vsConfig, embedder, store, ix, args[0], replace, force); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// openIndex opens the configured vector store and its indexer, exiting on failure
+func openIndex() (vectorstore.Config, embeddings.Embedder, vectorstore.VectorStore, *indexer.Indexer) {
+	if err := logger.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing logger: %v\n", err)
+		os.Exit(1)
+	}
+
+	vsConfig := vectorstore.LoadConfig()
+	if !vsConfig.Enabled || !vsConfig.Persistence.Enabled {
+		fmt.Fprintln(os.Stderr, "Error: indexing needs vectorstore.enabled and vectorstore.persistence.enabled set to true")
+		os.Exit(1)
+	}
+
+	embedder, err := embeddings.NewEmbedder(vsConfig.Embedding)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing embedder: %v\n", err)
+		os.Exit(1)
+	}
+	store, err := vectorstore.NewVectorStore(vsConfig, embedder)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening vector store: %v\n", err)
+		os.Exit(1)
+	}
+
+	ix, err := indexer.New(store, indexer.ManifestPath(vsConfig.Persistence.Path, vsConfig.CollectionName), retrieval.DocumentConfig{})
+	if err != nil {
+		store.Close()
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return vsConfig, embedder, store, ix
+}
+
+// exportIndex writes the collection and its manifest to a snapshot at path
+func exportIndex(ctx context.Context, vsConfig vectorstore.Config, store vectorstore.VectorStore, ix *indexer.Indexer, path string) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	manifest, err := json.Marshal(ix.Manifest().Portable(wd))
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	// Write next to the target and rename, so a failed export leaves no partial file
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	defer os.Remove(tmp)
+
+	header, err := vectorstore.ExportSnapshot(ctx, store, file, vectorstore.SnapshotHeader{
+		Collection:        vsConfig.CollectionName,
+		Store:             vsConfig.Provider,
+		EmbeddingProvider: vsConfig.Embedding.Provider,
+		EmbeddingModel:    vsConfig.Embedding.ModelName(),
+		Manifest:          manifest,
+	})
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	fmt.Printf("Exported %d chunk(s) of %d file(s) to %s (%s, %d dimensions)\n",
+		header.Documents, len(ix.Manifest().Files), path, header.EmbedderName(), header.Dimensions)
+	return nil
+}
+
+// importIndex loads the snapshot at path into the collection. An existing
+// index is only replaced when replace is set; force skips the model check.
+func importIndex(ctx context.Context, vsConfig vectorstore.Config, embedder embeddings.Embedder, store vectorstore.VectorStore, ix *indexer.Indexer, path string, replace, force bool) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer file.Close()
+
+	count, err := store.Count(ctx)
+	if err != nil {
+		return err
+	}
+	if count > 0 && !replace {
+		return fmt.Errorf("collection %s already has %d chunk(s); use --replace to discard them", vsConfig.CollectionName, count)
+	}
+
+	// Read the whole snapshot first, so a truncated or corrupt file is
+	// reported before --replace discards the current index
+	if _, err := vectorstore.VerifySnapshot(ctx, file); err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var manifest *indexer.Manifest
+	header, err := vectorstore.ImportSnapshot(ctx, store, file, func(header vectorstore.SnapshotHeader) error {
+		dimensions := embedder.GetDimensions()
+		err := header.CheckCompatible(vsConfig.Embedding.Provider, vsConfig.Embedding.ModelName(), dimensions, force)
+		if err != nil && header.Dimensions == dimensions {
+			return fmt.Errorf("%w (use --force to import anyway)", err)
+		}
+		if err != nil {
+			return err
+		}
+
+		manifest = indexer.NewManifest()
+		if len(header.Manifest) > 0 {
+			if manifest, err = indexer.DecodeManifest(header.Manifest); err != nil {
+				return fmt.Errorf("snapshot %w", err)
+			}
+		}
+		if count > 0 {
+			return ix.Clear(ctx)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := ix.Replace(manifest.Resolve(wd)); err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %d chunk(s) of %d file(s) from %s (%s, %d dimensions, exported %s)\n",
+		header.Documents, len(manifest.Files), path, header.EmbedderName(), header.Dimensions, header.Created.Local().Format("2006-01-02 15:04"))
+	return nil
+}
+
+// printIndexStatus describes the index and what a run over paths would change
+func printIndexStatus(ix *indexer.Indexer, vsConfig vectorstore.Config, paths []string) error {
+	manifest := ix.Manifest()
+	fmt.Printf("Collection: %s (%s)\n", vsConfig.CollectionName, vsConfig.Persistence.Path)
+	fmt.Printf("Indexed:    %d file(s), %d chunk(s)\n", len(manifest.Files), manifest.Chunks())
+	if last := manifest.LastIndexed(); !last.IsZero() {
+		fmt.Printf("Updated:    %s\n", last.Format("2006-01-02 15:04"))
+	}
+
+	plan, err := ix.Plan(paths)
+	if err != nil {
+		return err
+	}
+	if plan.Pending() == 0 {
+		fmt.Printf("Up to date: %s\n", strings.Join(paths, ", "))
+		return nil
+	}
+
+	fmt.Printf("Pending:    %d new, %d changed, %d removed\n", len(plan.New), len(plan.Changed), len(plan.Removed))
+	for _, group := range []struct {
+		mark  string
+		paths []string
+	}{{"+", plan.New}, {"~", plan.Changed}, {"-", plan.Removed}} {
+		for _, path := range group.paths {
+			fmt.Printf("  %s %s\n", group.mark, indexer.DisplayPath(path))
+		}
+	}
+	return nil
+}
+
+// embedThroughput holds the latest totals reported by the embedder
+var embedThroughput latestProgress
+
+type latestProgress struct {
+	mu       sync.Mutex
+	progress embeddings.EmbedProgress
+}
+
+func (l *latestProgress) set(progress embeddings.EmbedProgress) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.progress = progress
+}
+
+func (l *latestProgress) get() embeddings.EmbedProgress {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.progress
+}
+
+// printProgress redraws a progress bar on stderr
+func printProgress(done, total int, path string) {
+	filled := progressWidth * done / total
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressWidth-filled)
+
+	name := indexer.DisplayPath(path)
+	if runes := []rune(name); len(runes) > 40 {
+		name = "..." + string(runes[len(runes)-37:])
+	}
+	rate := ""
+	if progress := embedThroughput.get(); progress.Elapsed > 0 {
+		rate = fmt.Sprintf(" %.1f chunks/s", progress.Rate())
+	}
+	fmt.Fprintf(os.Stderr, "\r\033[K[%s] %d/%d%s %s", bar, done, total, rate, name)
+}
+
+// isTerminal reports whether f is attached to a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func init() {
+	rootCmd.AddCommand(indexCmd)
+
+	indexCmd.Flags().Bool("status", false, "show what is indexed and what a run would change")
+	indexCmd.Flags().Bool("clear", false, "remove every indexed document and the manifest")
+	indexCmd.MarkFlagsMutuallyExclusive("status", "clear")
+
+	indexCmd.AddCommand(indexExportCmd, indexImportCmd)
+	indexImportCmd.Flags().Bool("replace", false, "discard the current index of the collection first")
+	indexImportCmd.Flags().Bool("force", false, "import even if the snapshot was embedded with another model")
+}
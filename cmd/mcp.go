@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/killallgit/ryan/pkg/config"
+	"github.com/killallgit/ryan/pkg/logger"
+	"github.com/killallgit/ryan/pkg/mcp"
+	"github.com/killallgit/ryan/pkg/tokens"
+	"github.com/killallgit/ryan/pkg/tools/output"
+	"github.com/killallgit/ryan/pkg/tools/registry"
+	"github.com/spf13/cobra"
+)
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Model Context Protocol commands",
+}
+
+var mcpServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve Ryan's tools to MCP clients over stdio",
+	Long: `Expose the enabled tools over the Model Context Protocol on stdin/stdout.
+Tool calls are checked against the same ACL as in the chat. With --agent, an
+"ask_ryan" tool that runs a full agent turn is also offered.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		skipPermissions, _ := cmd.Flags().GetBool("skip-permissions")
+		exposeAgent, _ := cmd.Flags().GetBool("agent")
+
+		if err := logger.Init(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error initializing logger: %v\n", err)
+			os.Exit(1)
+		}
+		defer logger.Close()
+
+		registerUserTools()
+		mcpManager := startMCP()
+		defer mcpManager.Close()
+
+		serverTools := registry.Global().GetEnabled(config.Global, skipPermissions)
+
+		// Cap served tool output as in the chat; read_output pages through the rest
+		if settings := config.Global; settings.Tools.Enabled && settings.Tools.Output.Enabled {
+			counter, err := tokens.NewTokenCounter(settings.Ollama.DefaultModel)
+			if err != nil {
+				logger.Warn("Could not initialize token counter, estimating tokens: %v", err)
+			}
+			governor := output.NewGovernor(output.DefaultDir(fmt.Sprintf("mcp_%d", time.Now().UnixNano())), settings.Tools.Output.MaxTokens, counter.CountTokens)
+			defer governor.Close()
+			serverTools = append(governor.WrapAll(serverTools), output.NewReadOutputTool(governor))
+		}
+
+		if exposeAgent {
+			llm, err := createLLM()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error creating LLM: %v\n", err)
+				os.Exit(1)
+			}
+			reactAgent, err := createReactAgent(llm, false, skipPermissions)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error creating ReAct agent: %v\n", err)
+				os.Exit(1)
+			}
+			defer reactAgent.Close()
+			serverTools = append(serverTools, mcp.NewAgentTool(reactAgent))
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+
+		logger.Info("Serving %d tool(s) over MCP stdio", len(serverTools))
+		if err := mcp.NewServer(serverTools).Serve(ctx, os.Stdin, os.Stdout); err != nil && ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mcpCmd)
+	mcpCmd.AddCommand(mcpServeCmd)
+
+	mcpServeCmd.Flags().Bool("agent", false, `also expose an "ask_ryan" tool that runs a full agent turn`)
+}
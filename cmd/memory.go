@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/killallgit/ryan/pkg/memory"
+	"github.com/spf13/cobra"
+)
+
+var memoryCmd = &cobra.Command{
+	Use:   "memory",
+	Short: "Manage long-term memories of this project",
+	Long: `Long-term memory (memory.long_term.enabled) keeps finished turns and facts
+saved with the remember tool, and recalls relevant ones in later sessions.`,
+}
+
+var memoryListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the remembered turns and facts, newest first",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		facts, _ := cmd.Flags().GetBool("facts")
+
+		longTerm := openLongTermMemory()
+		defer longTerm.Close()
+
+		entries, err := longTerm.List(context.Background())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		shown := 0
+		for _, entry := range entries {
+			if facts && entry.Kind != memory.KindFact {
+				continue
+			}
+			summary := strings.Join(strings.Fields(entry.Content), " ")
+			if runes := []rune(summary); len(runes) > 100 {
+				summary = string(runes[:97]) + "..."
+			}
+			fmt.Printf("%s  %s  %-8s  %s\n", entry.ID, entry.Created.Format("2006-01-02 15:04"), entry.Kind, summary)
+			shown++
+		}
+		if shown == 0 {
+			fmt.Println("No memories recorded")
+		}
+	},
+}
+
+var memoryForgetCmd = &cobra.Command{
+	Use:   "forget [ids...]",
+	Short: "Forget memories by id, or all of them with --all",
+	Run: func(cmd *cobra.Command, args []string) {
+		all, _ := cmd.Flags().GetBool("all")
+		if all == (len(args) > 0) {
+			fmt.Fprintln(os.Stderr, "Error: give memory ids or --all")
+			os.Exit(1)
+		}
+
+		longTerm := openLongTermMemory()
+		defer longTerm.Close()
+
+		ctx := context.Background()
+		if all {
+			n, err := longTerm.ForgetAll(ctx)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Forgot %d memories\n", n)
+			return
+		}
+
+		if err := longTerm.Forget(ctx, args...); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Forgot %d memories\n", len(args))
+	},
+}
+
+// openLongTermMemory opens the project's memories without an embedder, which listing and forgetting do not need
+func openLongTermMemory() *memory.LongTermMemory {
+	dbPath, err := memory.DatabasePath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	longTerm, err := memory.NewLongTermMemory(dbPath, memory.CurrentProject(), nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return longTerm
+}
+
+func init() {
+	rootCmd.AddCommand(memoryCmd)
+	memoryCmd.AddCommand(memoryListCmd, memoryForgetCmd)
+
+	memoryListCmd.Flags().Bool("facts", false, "only show facts saved with the remember tool")
+	memoryForgetCmd.Flags().Bool("all", false, "forget every memory of this project")
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/killallgit/ryan/pkg/checkpoint"
+	"github.com/spf13/cobra"
+)
+
+var rewindCmd = &cobra.Command{
+	Use:   "rewind [turn]",
+	Short: "Restore files to their state before an agent turn",
+	Long: `Restore files modified by the agent to their state before the given turn.
+Without a turn, lists the recorded checkpoints. Defaults to the most recent session.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		sessionID, _ := cmd.Flags().GetString("session")
+		listSessions, _ := cmd.Flags().GetBool("sessions")
+
+		root := checkpoint.DefaultDir()
+		sessions, err := checkpoint.ListSessions(root)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing checkpoints: %v\n", err)
+			os.Exit(1)
+		}
+
+		if listSessions {
+			if len(sessions) == 0 {
+				fmt.Println("No checkpoints recorded")
+				return
+			}
+			for _, s := range sessions {
+				fmt.Printf("%s  %s  %d turn(s)\n", s.ID, s.Modified.Format("2006-01-02 15:04"), s.Turns)
+			}
+			return
+		}
+
+		if sessionID == "" {
+			if len(sessions) == 0 {
+				fmt.Println("No checkpoints recorded")
+				return
+			}
+			sessionID = sessions[0].ID
+		}
+
+		store, err := checkpoint.NewStore(root, sessionID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening checkpoints: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(args) == 0 {
+			turns, err := store.Turns()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error listing checkpoints: %v\n", err)
+				os.Exit(1)
+			}
+			if len(turns) == 0 {
+				fmt.Printf("No checkpoints recorded for session %s\n", sessionID)
+				return
+			}
+			fmt.Printf("Session %s:\n", sessionID)
+			for _, turn := range turns {
+				fmt.Printf("  %s\n", turn.Summary())
+			}
+			return
+		}
+
+		turn, err := strconv.Atoi(args[0])
+		if err != nil || turn < 1 {
+			fmt.Fprintf(os.Stderr, "Error: turn must be a positive number\n")
+			os.Exit(1)
+		}
+
+		restored, err := store.Rewind(turn)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Restored %d file(s) to before turn %d:\n", len(restored), turn)
+		for _, path := range restored {
+			fmt.Printf("  %s\n", path)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rewindCmd)
+
+	rewindCmd.Flags().StringP("session", "s", "", "session to rewind (defaults to the most recent)")
+	rewindCmd.Flags().Bool("sessions", false, "list sessions with checkpoints")
+}
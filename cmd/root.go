@@ -1,14 +1,20 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/killallgit/ryan/pkg/agent"
 	"github.com/killallgit/ryan/pkg/config"
 	"github.com/killallgit/ryan/pkg/headless"
 	"github.com/killallgit/ryan/pkg/logger"
+	"github.com/killallgit/ryan/pkg/mcp"
 	"github.com/killallgit/ryan/pkg/ollama"
+	"github.com/killallgit/ryan/pkg/plugin"
+	ryantools "github.com/killallgit/ryan/pkg/tools"
+	"github.com/killallgit/ryan/pkg/tools/registry"
 	"github.com/killallgit/ryan/pkg/tui"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -27,6 +33,13 @@ var rootCmd = &cobra.Command{
 		headlessMode, _ := cmd.Flags().GetBool("headless")
 		continueHistory, _ := cmd.Flags().GetBool("continue")
 		skipPermissions, _ := cmd.Flags().GetBool("skip-permissions")
+		outputFormatValue, _ := cmd.Flags().GetString("output-format")
+
+		outputFormat, err := headless.ParseOutputFormat(outputFormatValue)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
 
 		// Initialize logger
 		if err := logger.Init(); err != nil {
@@ -42,6 +55,11 @@ var rootCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		// Register custom, plugin and MCP tools before the agent collects its tools
+		registerUserTools()
+		mcpManager := startMCP()
+		defer mcpManager.Close()
+
 		// Create the ReAct agent to be used by both modes
 		// Pass skipPermissions to the agent creation
 		reactAgent, err := createReactAgent(llm, continueHistory, skipPermissions)
@@ -53,7 +71,7 @@ var rootCmd = &cobra.Command{
 
 		// Check if running in headless mode
 		if headlessMode {
-			runHeadless(reactAgent, promptValue, continueHistory)
+			runHeadless(reactAgent, promptValue, continueHistory, outputFormat)
 		} else {
 			runTUI(reactAgent, continueHistory)
 		}
@@ -79,19 +97,52 @@ func createLLM() (llms.Model, error) {
 	}
 }
 
+// registerUserTools registers the command tools declared under tools.custom and the
+// executables in the plugin directory. Invalid tools are reported and skipped.
+func registerUserTools() {
+	if _, err := ryantools.RegisterCustomTools(registry.Global(), config.Global.Tools.Custom); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	if !config.Global.Tools.Enabled || !config.Global.Tools.Plugins.Enabled {
+		return
+	}
+	dir := config.Global.Tools.Plugins.Dir
+	if dir == "" {
+		dir = plugin.DefaultDir()
+	}
+	timeout := time.Duration(config.Global.Tools.Plugins.Timeout) * time.Second
+	if _, err := plugin.Register(context.Background(), registry.Global(), dir, timeout); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// startMCP connects to the configured MCP servers. Servers that fail to start are reported and skipped.
+func startMCP() *mcp.Manager {
+	manager := mcp.NewManager(registry.Global())
+	if len(config.Global.MCP.Servers) == 0 {
+		return manager
+	}
+
+	if err := manager.Start(context.Background(), config.Global.MCP.Servers); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	return manager
+}
+
 // createReactAgent creates a ReAct agent with the given configuration
 func createReactAgent(llm llms.Model, continueHistory, skipPermissions bool) (agent.Agent, error) {
 	return agent.NewReactAgentWithOptions(llm, continueHistory, skipPermissions)
 }
 
-func runHeadless(reactAgent agent.Agent, prompt string, continueHistory bool) {
+func runHeadless(reactAgent agent.Agent, prompt string, continueHistory bool, format headless.OutputFormat) {
 	// Use provided prompt or default
 	if prompt == "" {
 		prompt = "hello"
 	}
 
 	// Simply run the headless mode - no terminal manipulation needed
-	if err := headless.RunHeadlessWithOptions(reactAgent, prompt, continueHistory); err != nil {
+	if err := headless.RunHeadlessWithFormat(reactAgent, prompt, continueHistory, format); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -127,6 +178,7 @@ func init() {
 	rootCmd.PersistentFlags().StringP("prompt", "p", "", "execute a prompt directly without entering TUI")
 	rootCmd.PersistentFlags().BoolP("headless", "H", false, "run without TUI (requires --prompt)")
 	rootCmd.PersistentFlags().Bool("skip-permissions", false, "skip all ACL permission checks for tools")
+	rootCmd.PersistentFlags().String("output-format", "text", "headless output format: text or json")
 }
 
 func initConfig() {
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/killallgit/ryan/pkg/agent"
+	"github.com/killallgit/ryan/pkg/config"
+	"github.com/killallgit/ryan/pkg/logger"
+	"github.com/killallgit/ryan/pkg/tools/acl"
+	"github.com/killallgit/ryan/pkg/tools/registry"
+	"github.com/spf13/cobra"
+)
+
+var systemPromptCmd = &cobra.Command{
+	Use:   "system-prompt",
+	Short: "Print the system prompt the agent would run with",
+	Long: `Assemble the system prompt as a chat session would, with the registered
+tools, permissions and overrides, and print it. Tools a session adds itself,
+such as knowledge_search and read_output, are not listed. Sections are
+rendered from <name>.tmpl files in the prompts directory next to the settings
+file, then ~/.ryan/prompts, then the built-in defaults; an empty file removes
+a section. Sections: identity, environment, git, tools, permissions,
+instructions.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		skipPermissions, _ := cmd.Flags().GetBool("skip-permissions")
+
+		if err := logger.Init(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error initializing logger: %v\n", err)
+			os.Exit(1)
+		}
+		defer logger.Close()
+
+		if !config.Global.Prompt.System.Enabled {
+			fmt.Fprintln(os.Stderr, "The system prompt is disabled; see prompt.system.enabled")
+			return
+		}
+
+		registerUserTools()
+		mcpManager := startMCP()
+		defer mcpManager.Close()
+
+		agentTools := registry.Global().GetEnabled(config.Global, skipPermissions)
+		systemPrompt, _, err := agent.BuildSystemPrompts(agentTools, acl.NewPermissionManagerWithBypass(skipPermissions))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error building system prompt: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(systemPrompt)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(systemPromptCmd)
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/killallgit/ryan/pkg/config"
+	"github.com/killallgit/ryan/pkg/logger"
+	"github.com/killallgit/ryan/pkg/tools/registry"
+	"github.com/spf13/cobra"
+)
+
+var toolsCmd = &cobra.Command{
+	Use:   "tools",
+	Short: "List the tools available to the agent",
+	Long:  `List the enabled built-in tools, custom tools from tools.custom and tools from MCP servers.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := logger.Init(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error initializing logger: %v\n", err)
+			os.Exit(1)
+		}
+		defer logger.Close()
+
+		registerUserTools()
+		mcpManager := startMCP()
+		defer mcpManager.Close()
+
+		enabled := registry.Global().GetEnabled(config.Global, true)
+		if len(enabled) == 0 {
+			fmt.Println("No tools enabled")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, tool := range enabled {
+			fmt.Fprintf(w, "%s\t%s\n", tool.Name(), summarizeDescription(tool.Description(), 80))
+		}
+		w.Flush()
+	},
+}
+
+// summarizeDescription returns the first sentence of a description, truncated to max runes
+func summarizeDescription(description string, max int) string {
+	summary := strings.TrimSpace(description)
+	if i := strings.Index(summary, ". "); i >= 0 {
+		summary = summary[:i+1]
+	}
+	if runes := []rune(summary); len(runes) > max {
+		summary = string(runes[:max-3]) + "..."
+	}
+	return summary
+}
+
+func init() {
+	rootCmd.AddCommand(toolsCmd)
+}
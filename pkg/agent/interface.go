@@ -3,6 +3,7 @@ package agent
 import (
 	"context"
 
+	"github.com/killallgit/ryan/pkg/retrieval"
 	"github.com/killallgit/ryan/pkg/stream/core"
 )
 
@@ -29,5 +30,12 @@ type Agent interface {
 	Close() error
 }
 
+// CitationHandler is implemented by stream handlers that want the sources a
+// response drew on. OnCitations is called just before OnComplete, and only
+// when passages were retrieved.
+type CitationHandler interface {
+	OnCitations(citations []retrieval.Citation)
+}
+
 // Compile-time check that ReactAgent implements Agent interface
 var _ Agent = (*ReactAgent)(nil)
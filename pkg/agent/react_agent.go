@@ -3,9 +3,13 @@ package agent
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/killallgit/ryan/pkg/checkpoint"
 	"github.com/killallgit/ryan/pkg/config"
 	"github.com/killallgit/ryan/pkg/embeddings"
 	"github.com/killallgit/ryan/pkg/logger"
@@ -15,7 +19,9 @@ import (
 	"github.com/killallgit/ryan/pkg/stream/core"
 	"github.com/killallgit/ryan/pkg/stream/providers"
 	"github.com/killallgit/ryan/pkg/tokens"
-	_ "github.com/killallgit/ryan/pkg/tools" // Import for init() registration
+	ryantools "github.com/killallgit/ryan/pkg/tools"
+	"github.com/killallgit/ryan/pkg/tools/acl"
+	"github.com/killallgit/ryan/pkg/tools/output"
 	"github.com/killallgit/ryan/pkg/tools/registry"
 	"github.com/killallgit/ryan/pkg/vectorstore"
 	"github.com/tmc/langchaingo/agents"
@@ -24,6 +30,22 @@ import (
 	"github.com/tmc/langchaingo/tools"
 )
 
+// executorToolsPrompt follows the system prompt in the executor's prompt. It
+// stands in for the tool list of langchaingo's default prefix, which the
+// system prompt replaces, and is rendered by the executor.
+const executorToolsPrompt = `
+
+TOOLS:
+------
+
+You have access to the following tools:
+
+{{.tool_descriptions}}`
+
+// longTermSaveTimeout bounds how long remembering a finished turn may take, and so
+// how long Close waits for it
+const longTermSaveTimeout = 30 * time.Second
+
 // ReactAgent is a LangChain ReAct-based agent implementation
 // It wraps LangChain's conversational agent with an executor for handling requests
 type ReactAgent struct {
@@ -43,9 +65,27 @@ type ReactAgent struct {
 	vectorStore vectorstore.VectorStore
 	retriever   *retrieval.Retriever
 	augmenter   *retrieval.Augmenter
+	citations   *retrieval.CitationSet
 
 	// Prompt template for formatting inputs
 	promptTemplate prompt.Template
+
+	// Describes the environment, tools and permissions; empty when disabled
+	systemPrompt string
+
+	// The same without tools and permissions, for ExecuteStream, which cannot call tools
+	streamSystemPrompt string
+
+	// File checkpoints recorded per turn
+	checkpoints *checkpoint.Store
+
+	// Limits large tool results and stores the full text
+	output *output.Governor
+
+	// Remembers turns across sessions and recalls relevant ones
+	longTerm  *memory.LongTermMemory
+	saves     sync.WaitGroup
+	sessionID string
 }
 
 // NewReactAgent creates a new executor-based agent with an injected LLM
@@ -101,13 +141,65 @@ func NewReactAgentWithSessionAndOptions(llm llms.Model, sessionID string, skipPe
 	// Get configuration settings
 	settings := config.Get()
 
+	// Record pre-images of files modified by tools so turns can be rewound
+	checkpoints, err := checkpoint.NewStore(checkpoint.DefaultDir(), sessionID)
+	if err != nil {
+		logger.Warn("Could not initialize checkpoints: %v", err)
+		checkpoints = nil
+	}
+
 	// Get enabled tools from registry based on configuration
 	agentTools := registry.Global().GetEnabled(settings, skipPermissions)
+	for _, tool := range agentTools {
+		if recorder, ok := tool.(ryantools.CheckpointRecorder); ok {
+			recorder.SetCheckpoints(checkpoints)
+		}
+	}
+
+	// Mirror the session's todo list into the observable execution state
+	state := NewExecutionState()
+	if todos, err := mem.Todos().Get(); err == nil {
+		state.SetTodos(todos)
+	} else {
+		logger.Warn("Could not load todos: %v", err)
+	}
+	mem.Todos().OnChange(state.SetTodos)
+
+	// Todo tools are bound to the session, so they are created here rather than by the registry
+	if settings.Tools.Enabled && settings.Tools.Todo.Enabled {
+		agentTools = append(agentTools,
+			ryantools.NewTodoWriteTool(mem.Todos()),
+			ryantools.NewTodoReadTool(mem.Todos()),
+		)
+		logger.Debug("Added todo tools")
+	}
+
+	// Long-term memory shares the session database but needs an embedder of its own
+	var longTerm *memory.LongTermMemory
+	if settings.Memory.LongTerm.Enabled {
+		longTerm, err = newLongTermMemory()
+		if err != nil {
+			logger.Warn("Could not initialize long-term memory: %v", err)
+		} else if settings.Tools.Enabled {
+			agentTools = append(agentTools, ryantools.NewRememberTool(longTerm, sessionID))
+			logger.Debug("Added remember tool")
+		}
+	}
+
+	// Initialize token counter
+	modelName := settings.Ollama.DefaultModel
+	tokenCounter, err := tokens.NewTokenCounter(modelName)
+	if err != nil {
+		// Don't fail if token counter can't be initialized, just log warning
+		logger.Warn("Could not initialize token counter: %v", err)
+		tokenCounter = nil
+	}
 
 	// Initialize RAG components if enabled
 	var vectorStore vectorstore.VectorStore
 	var retriever *retrieval.Retriever
 	var augmenter *retrieval.Augmenter
+	var citations *retrieval.CitationSet
 
 	if settings.VectorStore.Enabled {
 		logger.Info("Vector store is enabled, initializing RAG components")
@@ -117,18 +209,11 @@ func NewReactAgentWithSessionAndOptions(llm llms.Model, sessionID string, skipPe
 		logger.Debug("Vector store config - Provider: %s, Collection: %s", vsConfig.Provider, vsConfig.CollectionName)
 
 		// Create embedder based on configuration
-		var embedder embeddings.Embedder
-		if vsConfig.Embedding.Provider == "ollama" {
-			embedConfig := embeddings.OllamaConfig{
-				Endpoint: vsConfig.Embedding.Endpoint,
-				Model:    vsConfig.Embedding.Model,
-			}
-			embedder, err = embeddings.NewOllamaEmbedder(embedConfig)
-			if err != nil {
-				logger.Warn("Could not initialize embedder: %v", err)
-			} else {
-				logger.Debug("Initialized Ollama embedder with model: %s", vsConfig.Embedding.Model)
-			}
+		embedder, err := embeddings.NewEmbedder(vsConfig.Embedding)
+		if err != nil {
+			logger.Warn("Could not initialize embedder: %v", err)
+		} else {
+			logger.Debug("Initialized %s embedder with model: %s", vsConfig.Embedding.Provider, vsConfig.Embedding.Model)
 		}
 
 		// Create vector store if embedder is available
@@ -139,38 +224,82 @@ func NewReactAgentWithSessionAndOptions(llm llms.Model, sessionID string, skipPe
 			} else {
 				logger.Info("Vector store initialized successfully")
 				// Create retriever
-				retriever = retrieval.NewRetriever(vectorStore, retrieval.Config{
+				retrieverConfig := retrieval.Config{
 					MaxDocuments:     vsConfig.Retrieval.K,
 					ScoreThreshold:   vsConfig.Retrieval.ScoreThreshold,
 					MaxContextLength: vsConfig.Retrieval.MaxContextLength,
-				})
+					VectorWeight:     settings.VectorStore.Retrieval.Hybrid.VectorWeight,
+					KeywordWeight:    settings.VectorStore.Retrieval.Hybrid.KeywordWeight,
+					MMR:              settings.VectorStore.Retrieval.MMR.Enabled,
+					MMRLambda:        settings.VectorStore.Retrieval.MMR.Lambda,
+				}
+				if settings.VectorStore.Retrieval.Rerank.Enabled {
+					retrieverConfig.Reranker = retrieval.NewLLMReranker(llm)
+				}
+				retriever = retrieval.NewRetriever(vectorStore, retrieverConfig)
+
+				// Augmentation and knowledge_search share one numbering so the model can cite passages as [n]
+				citations = retrieval.NewCitationSet()
 				logger.Debug("Retriever created with K=%d, threshold=%f", vsConfig.Retrieval.K, vsConfig.Retrieval.ScoreThreshold)
 
-				// Create augmenter
-				maxContextLength := vsConfig.Retrieval.MaxContextLength
-				if maxContextLength == 0 {
-					maxContextLength = 4000 // Default fallback
+				// Blanket augmentation is opt-in; otherwise the agent searches when it needs to
+				if settings.VectorStore.Retrieval.AutoAugment {
+					maxContextLength := vsConfig.Retrieval.MaxContextLength
+					if maxContextLength == 0 {
+						maxContextLength = 4000 // Default fallback
+					}
+					augmenterConfig := retrieval.AugmenterConfig{
+						MaxContextLength: maxContextLength,
+						Citations:        citations,
+					}
+					// Follow-ups such as "and how is it tested?" are searched for as standalone queries
+					if rewrite := settings.VectorStore.Retrieval.Rewrite; rewrite.Enabled {
+						augmenterConfig.Rewriter = retrieval.NewQueryRewriter(llm, retrieval.RewriteConfig{
+							HyDE:       rewrite.HyDE,
+							MultiQuery: rewrite.MultiQuery,
+							History:    rewrite.History,
+						})
+						augmenterConfig.History = mem.GetMessages
+					}
+					augmenter = retrieval.NewAugmenter(retriever, augmenterConfig)
+					logger.Debug("Augmenter created with max context length: %d", maxContextLength)
 				}
-				augmenter = retrieval.NewAugmenter(retriever, retrieval.AugmenterConfig{
-					MaxContextLength: maxContextLength,
-				})
-				logger.Debug("Augmenter created with max context length: %d", maxContextLength)
-
-				// Add retriever as a LangChain tool if available
-				if retriever != nil {
-					lcRetriever := retrieval.NewLangChainRetriever(retriever)
-					// Note: We would need to create a tool wrapper here if we want to use it as a tool
-					// For now, we'll use it directly in Execute/ExecuteStream
-					_ = lcRetriever // Suppress unused variable warning
+
+				if settings.Tools.Enabled {
+					agentTools = append(agentTools, retrieval.NewKnowledgeSearchToolWithCitations(retriever, citations))
+					logger.Debug("Added knowledge_search tool")
 				}
 			}
 		}
 	}
 
+	// Cap large tool results; the full text stays readable through read_output
+	var governor *output.Governor
+	if settings.Tools.Enabled && settings.Tools.Output.Enabled {
+		governor = output.NewGovernor(output.DefaultDir(sessionID), settings.Tools.Output.MaxTokens, tokenCounter.CountTokens)
+		agentTools = append(governor.WrapAll(agentTools), output.NewReadOutputTool(governor))
+		logger.Debug("Tool output limited to %d tokens", settings.Tools.Output.MaxTokens)
+	}
+
+	// Describe the environment, tools and permissions in place of the generic agent prompt
+	var agentOptions []agents.Option
+	var systemPrompt, streamSystemPrompt string
+	if settings.Prompt.System.Enabled {
+		permissions := acl.NewPermissionManagerWithBypass(skipPermissions)
+		systemPrompt, streamSystemPrompt, err = BuildSystemPrompts(agentTools, permissions)
+		if err != nil {
+			logger.Warn("Could not build system prompt: %v", err)
+		} else {
+			agentOptions = append(agentOptions, agents.WithPromptPrefix(escapeGoTemplate(systemPrompt)+executorToolsPrompt))
+			logger.Debug("System prompt assembled (%d characters)", len(systemPrompt))
+		}
+	}
+
 	// Create the agent - using a conversational agent with tools
 	agent := agents.NewConversationalAgent(
 		llm,
 		agentTools,
+		agentOptions...,
 	)
 
 	// Create a LangChain memory wrapper around our SQLite history
@@ -190,28 +319,109 @@ func NewReactAgentWithSessionAndOptions(llm llms.Model, sessionID string, skipPe
 		agents.WithMemory(lcMem),
 	)
 
-	// Initialize token counter
-	modelName := settings.Ollama.DefaultModel
-	tokenCounter, err := tokens.NewTokenCounter(modelName)
+	return &ReactAgent{
+		llm:                llm,
+		executor:           executor,
+		memory:             mem,
+		tools:              agentTools,
+		tokenCounter:       tokenCounter,
+		tokensSent:         0,
+		tokensRecv:         0,
+		state:              state,
+		vectorStore:        vectorStore,
+		retriever:          retriever,
+		augmenter:          augmenter,
+		citations:          citations,
+		checkpoints:        checkpoints,
+		output:             governor,
+		longTerm:           longTerm,
+		sessionID:          sessionID,
+		systemPrompt:       systemPrompt,
+		streamSystemPrompt: streamSystemPrompt,
+	}, nil
+}
+
+// BuildSystemPrompts describes the environment, agentTools and permissions,
+// with section overrides from the settings directory and ~/.ryan/prompts. The
+// second prompt leaves the tools and permissions out, for turns without tools.
+func BuildSystemPrompts(agentTools []tools.Tool, permissions *acl.PermissionManager) (string, string, error) {
+	promptConfig := prompt.SystemPromptConfig{ProjectDir: config.BuildSettingsPath("prompts")}
+	if home, err := os.UserHomeDir(); err == nil {
+		promptConfig.UserDir = filepath.Join(home, ".ryan", "prompts")
+	}
+	builder := prompt.NewSystemPromptBuilder(promptConfig)
+
+	env := prompt.DetectEnvironment(context.Background(), config.Get().Prompt.System.GitStatusLines)
+	withoutTools, err := builder.Build(env)
 	if err != nil {
-		// Don't fail if token counter can't be initialized, just log warning
-		logger.Warn("Could not initialize token counter: %v", err)
-		tokenCounter = nil
+		return "", "", err
 	}
 
-	return &ReactAgent{
-		llm:          llm,
-		executor:     executor,
-		memory:       mem,
-		tools:        agentTools,
-		tokenCounter: tokenCounter,
-		tokensSent:   0,
-		tokensRecv:   0,
-		state:        NewExecutionState(),
-		vectorStore:  vectorStore,
-		retriever:    retriever,
-		augmenter:    augmenter,
-	}, nil
+	for _, tool := range agentTools {
+		env.Tools = append(env.Tools, prompt.NewToolInfo(tool.Name(), tool.Description()))
+	}
+	env.Permissions = prompt.PermissionInfo{
+		Allowed:  permissions.AllowedPatterns(),
+		Bypassed: permissions.Bypassed(),
+	}
+	withTools, err := builder.Build(env)
+	if err != nil {
+		return "", "", err
+	}
+	return withTools, withoutTools, nil
+}
+
+// escapeGoTemplate keeps text literal inside the agent's Go-template prompt
+func escapeGoTemplate(text string) string {
+	return strings.ReplaceAll(text, "{{", `{{"{{"}}`)
+}
+
+// newLongTermMemory opens the project's long-term memory with the configured embedder
+func newLongTermMemory() (*memory.LongTermMemory, error) {
+	dbPath, err := memory.DatabasePath()
+	if err != nil {
+		return nil, err
+	}
+	embedder, err := embeddings.NewEmbedder(vectorstore.LoadConfig().Embedding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize embedder: %w", err)
+	}
+	return memory.NewLongTermMemory(dbPath, memory.CurrentProject(), embedder)
+}
+
+// recallMemories prepends memories from earlier sessions relevant to prompt to actualPrompt
+func (e *ReactAgent) recallMemories(ctx context.Context, prompt, actualPrompt string) string {
+	if e.longTerm == nil {
+		return actualPrompt
+	}
+	settings := config.Get().Memory.LongTerm
+	entries, err := e.longTerm.Recall(ctx, prompt, settings.K, float32(settings.MinScore), e.sessionID)
+	if err != nil {
+		logger.Warn("Could not recall memories: %v", err)
+		return actualPrompt
+	}
+	if len(entries) == 0 {
+		return actualPrompt
+	}
+	logger.Debug("Recalled %d memories from earlier sessions", len(entries))
+	return memory.FormatRecollections(entries) + "\n" + actualPrompt
+}
+
+// rememberExchange stores a completed turn in long-term memory in the
+// background, so embedding it does not hold up the response; Close waits for it
+func (e *ReactAgent) rememberExchange(prompt, response string) {
+	if e.longTerm == nil || strings.TrimSpace(prompt) == "" || strings.TrimSpace(response) == "" {
+		return
+	}
+	e.saves.Add(1)
+	go func() {
+		defer e.saves.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), longTermSaveTimeout)
+		defer cancel()
+		if _, err := e.longTerm.RememberExchange(ctx, e.sessionID, prompt, response); err != nil {
+			logger.Warn("Could not remember exchange: %v", err)
+		}
+	}()
 }
 
 // Execute handles a request and returns a response
@@ -224,6 +434,12 @@ func (e *ReactAgent) Execute(ctx context.Context, prompt string) (string, error)
 		e.state.SetPhase(PhaseThinking)
 	}
 
+	// Start a new checkpoint turn for files modified while handling this prompt
+	e.checkpoints.BeginTurn(prompt)
+
+	// Number retrieved passages from 1 again for this turn
+	e.citations.Reset()
+
 	// Format prompt using template if configured
 	actualPrompt := prompt
 	if e.promptTemplate != nil {
@@ -250,6 +466,7 @@ func (e *ReactAgent) Execute(ctx context.Context, prompt string) (string, error)
 			logger.Debug("Prompt augmented successfully")
 		}
 	}
+	actualPrompt = e.recallMemories(ctx, prompt, actualPrompt)
 
 	// Count input tokens
 	if e.tokenCounter != nil {
@@ -312,9 +529,21 @@ func (e *ReactAgent) Execute(ctx context.Context, prompt string) (string, error)
 		logger.Warn("Could not add assistant message to memory: %v", err)
 	}
 
+	e.recordCitations(response)
+	e.rememberExchange(prompt, response)
+
 	return response, nil
 }
 
+// recordCitations marks which retrieved passages the response cites and keeps them in the execution state
+func (e *ReactAgent) recordCitations(response string) []retrieval.Citation {
+	citations := retrieval.MarkCited(e.citations.List(), response)
+	if e.state != nil && len(citations) > 0 {
+		e.state.SetCitations(citations)
+	}
+	return citations
+}
+
 // ExecuteStream handles a request with streaming response
 func (e *ReactAgent) ExecuteStream(ctx context.Context, prompt string, handler core.Handler) error {
 	logger.Debug("ExecuteStream called with prompt: %s", prompt)
@@ -325,6 +554,12 @@ func (e *ReactAgent) ExecuteStream(ctx context.Context, prompt string, handler c
 		e.state.SetPhase(PhaseThinking)
 	}
 
+	// Start a new checkpoint turn for files modified while handling this prompt
+	e.checkpoints.BeginTurn(prompt)
+
+	// Number retrieved passages from 1 again for this turn
+	e.citations.Reset()
+
 	// Format prompt using template if configured
 	actualPrompt := prompt
 	if e.promptTemplate != nil {
@@ -351,6 +586,7 @@ func (e *ReactAgent) ExecuteStream(ctx context.Context, prompt string, handler c
 			logger.Debug("Prompt augmented successfully (streaming)")
 		}
 	}
+	actualPrompt = e.recallMemories(ctx, prompt, actualPrompt)
 
 	// Count input tokens
 	if e.tokenCounter != nil {
@@ -364,8 +600,12 @@ func (e *ReactAgent) ExecuteStream(ctx context.Context, prompt string, handler c
 	// Create a LangChain streaming source using the agent's LLM
 	source := providers.NewLangChainSource(e.llm)
 
-	// Build conversation messages from memory
+	// Build conversation messages from memory, after the system prompt. The
+	// stream goes straight to the model, so its prompt offers no tools.
 	messages := []core.Message{}
+	if e.streamSystemPrompt != "" {
+		messages = append(messages, core.Message{Role: "system", Content: e.streamSystemPrompt})
+	}
 
 	// Add conversation history if available
 	if e.memory != nil {
@@ -394,6 +634,7 @@ func (e *ReactAgent) ExecuteStream(ctx context.Context, prompt string, handler c
 		inner:      handler,
 		memory:     e.memory,
 		prompt:     actualPrompt,
+		original:   prompt,
 		agent:      e,
 		buffer:     "",
 		lastTokens: 0,
@@ -408,6 +649,7 @@ type tokenAndMemoryHandler struct {
 	inner      core.Handler
 	memory     *memory.Memory
 	prompt     string
+	original   string // The prompt as the user wrote it, before augmentation
 	agent      *ReactAgent
 	buffer     string
 	lastTokens int
@@ -458,6 +700,14 @@ func (h *tokenAndMemoryHandler) OnComplete(finalContent string) error {
 		// Add assistant response
 		_ = h.memory.AddAssistantMessage(finalContent)
 	}
+	h.agent.rememberExchange(h.original, finalContent)
+
+	// Report the passages behind the response to handlers that show sources
+	if citations := h.agent.recordCitations(finalContent); len(citations) > 0 {
+		if citationHandler, ok := h.inner.(CitationHandler); ok {
+			citationHandler.OnCitations(citations)
+		}
+	}
 
 	return h.inner.OnComplete(finalContent)
 }
@@ -508,12 +758,25 @@ func (e *ReactAgent) Close() error {
 		}
 	}
 
+	if e.output != nil {
+		if err := e.output.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove saved tool output: %w", err))
+		}
+	}
+
 	if e.vectorStore != nil {
 		if err := e.vectorStore.Close(); err != nil {
 			errs = append(errs, fmt.Errorf("failed to close vector store: %w", err))
 		}
 	}
 
+	if e.longTerm != nil {
+		e.saves.Wait()
+		if err := e.longTerm.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close long-term memory: %w", err))
+		}
+	}
+
 	if len(errs) > 0 {
 		return fmt.Errorf("errors during close: %v", errs)
 	}
@@ -538,6 +801,11 @@ func (e *ReactAgent) GetRetriever() *retrieval.Retriever {
 	return e.retriever
 }
 
+// GetCheckpoints returns the checkpoint store for the agent's session
+func (e *ReactAgent) GetCheckpoints() *checkpoint.Store {
+	return e.checkpoints
+}
+
 // SetPromptTemplate sets a custom prompt template for the agent
 func (e *ReactAgent) SetPromptTemplate(template prompt.Template) {
 	e.promptTemplate = template
@@ -568,6 +836,11 @@ func (e *ReactAgent) FormatPrompt(input string, additionalVars map[string]any) (
 	return e.promptTemplate.Format(vars)
 }
 
+// SystemPrompt returns the assembled system prompt, empty when it is disabled
+func (e *ReactAgent) SystemPrompt() string {
+	return e.systemPrompt
+}
+
 // GetExecutionState returns a snapshot of the current execution state
 func (e *ReactAgent) GetExecutionState() ExecutionStateSnapshot {
 	if e.state == nil {
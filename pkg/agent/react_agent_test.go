@@ -4,15 +4,23 @@ import (
 	"context"
 	"errors"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
 
 	"github.com/killallgit/ryan/pkg/config"
+	"github.com/killallgit/ryan/pkg/embeddings"
+	"github.com/killallgit/ryan/pkg/memory"
+	"github.com/killallgit/ryan/pkg/retrieval"
+	"github.com/killallgit/ryan/pkg/tools/acl"
+	"github.com/killallgit/ryan/pkg/vectorstore"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/prompts"
+	"github.com/tmc/langchaingo/tools"
 )
 
 // MockLLM implements a fake LLM for testing
@@ -23,6 +31,7 @@ type MockLLM struct {
 	generateError  error
 	streamError    error
 	streamCallback func(string)
+	lastPrompt     string // Text of the messages of the last call
 }
 
 func NewMockLLM(responses []string) *MockLLM {
@@ -47,6 +56,16 @@ func (m *MockLLM) GenerateContent(ctx context.Context, messages []llms.MessageCo
 	// Get the response
 	responseText := m.responses[m.index]
 
+	var text strings.Builder
+	for _, msg := range messages {
+		for _, part := range msg.Parts {
+			if textPart, ok := part.(llms.TextContent); ok {
+				text.WriteString(textPart.Text)
+			}
+		}
+	}
+	m.lastPrompt = text.String()
+
 	// Check if this looks like an agent call by examining the messages
 	isAgentCall := false
 	for _, msg := range messages {
@@ -396,6 +415,14 @@ func TestReactAgentWithRAG(t *testing.T) {
 
 	// RAG components might be nil if Ollama isn't available
 	// This is expected behavior - the agent should work without RAG
+	if agent.retriever != nil {
+		names := make([]string, len(agent.tools))
+		for i, tool := range agent.tools {
+			names[i] = tool.Name()
+		}
+		assert.Contains(t, names, "knowledge_search")
+		assert.Nil(t, agent.augmenter, "prompts are only augmented when auto_augment is set")
+	}
 }
 
 // TestReactAgentClose tests resource cleanup
@@ -526,6 +553,100 @@ func TestTokenAndMemoryHandler(t *testing.T) {
 	handler.OnError(errors.New("test error"))
 }
 
+// citationStreamHandler records the citations reported before completion
+type citationStreamHandler struct {
+	testStreamHandler
+	citations []retrieval.Citation
+	completed bool
+	late      bool // Citations arrived after OnComplete
+}
+
+func (h *citationStreamHandler) OnCitations(citations []retrieval.Citation) {
+	h.late = h.completed
+	h.citations = citations
+}
+
+func TestTokenAndMemoryHandlerReportsCitations(t *testing.T) {
+	viper.Reset()
+	viper.Set("vectorstore.enabled", false)
+
+	agent, err := NewReactAgent(NewMockLLM([]string{"test"}))
+	require.NoError(t, err)
+	defer agent.Close()
+
+	agent.citations = retrieval.NewCitationSet()
+	for _, id := range []string{"a", "b"} {
+		agent.citations.Add(vectorstore.SearchResult{Document: vectorstore.Document{
+			ID: id, Content: id, Metadata: map[string]interface{}{"source": id + ".go", "start_line": "1", "end_line": "9"},
+		}})
+	}
+
+	inner := &citationStreamHandler{}
+	inner.onComplete = func(string) error {
+		inner.completed = true
+		return nil
+	}
+	handler := &tokenAndMemoryHandler{inner: inner, memory: agent.memory, prompt: "q", agent: agent}
+	require.NoError(t, handler.OnComplete("Tools are registered at startup [2]."))
+
+	require.Len(t, inner.citations, 2)
+	assert.False(t, inner.late, "citations arrive before completion")
+	assert.False(t, inner.citations[0].Cited)
+	assert.True(t, inner.citations[1].Cited)
+	assert.Equal(t, "b.go:1-9", inner.citations[1].Location())
+	assert.Equal(t, inner.citations, agent.GetExecutionState().Citations)
+}
+
+// blockingEmbedder holds every embedding until release is closed
+type blockingEmbedder struct {
+	embeddings.Embedder
+	release chan struct{}
+}
+
+func (e *blockingEmbedder) EmbedText(ctx context.Context, text string) ([]float32, error) {
+	<-e.release
+	return e.Embedder.EmbedText(ctx, text)
+}
+
+func (e *blockingEmbedder) EmbedTexts(ctx context.Context, texts []string) ([][]float32, error) {
+	<-e.release
+	return e.Embedder.EmbedTexts(ctx, texts)
+}
+
+func TestTokenAndMemoryHandlerRemembersInBackground(t *testing.T) {
+	viper.Reset()
+	viper.Set("vectorstore.enabled", false)
+
+	agent, err := NewReactAgent(NewMockLLM([]string{"test"}))
+	require.NoError(t, err)
+	defer agent.Close()
+
+	embedder := &blockingEmbedder{Embedder: embeddings.NewLocalEmbedder(64), release: make(chan struct{})}
+	agent.longTerm, err = memory.NewLongTermMemory(filepath.Join(t.TempDir(), "memory.db"), "/src/ryan", embedder)
+	require.NoError(t, err)
+
+	completed := make(chan struct{})
+	inner := &testStreamHandler{onComplete: func(string) error {
+		close(completed)
+		return nil
+	}}
+	handler := &tokenAndMemoryHandler{inner: inner, memory: agent.memory, prompt: "q", original: "where are sessions kept", agent: agent}
+	require.NoError(t, handler.OnComplete("In SQLite."))
+
+	select {
+	case <-completed:
+	default:
+		t.Fatal("completion waited for the exchange to be embedded")
+	}
+
+	close(embedder.release)
+	agent.saves.Wait()
+	entries, err := agent.longTerm.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Contains(t, entries[0].Content, "Assistant: In SQLite.")
+}
+
 // BenchmarkReactAgentExecute benchmarks the Execute method
 func BenchmarkReactAgentExecute(b *testing.B) {
 	viper.Reset()
@@ -605,3 +726,63 @@ func TestReactAgentWithInvalidConfig(t *testing.T) {
 		assert.Nil(t, agent.GetRetriever())
 	}
 }
+
+func TestReactAgentSystemPrompt(t *testing.T) {
+	viper.Reset()
+	viper.Set("vectorstore.enabled", false)
+	viper.Set("tools.enabled", true)
+	viper.Set("prompt.system.enabled", true)
+	require.NoError(t, config.Load())
+
+	mockLLM := NewMockLLM([]string{"Hello", "Hello"})
+	agent, err := NewReactAgent(mockLLM)
+	require.NoError(t, err)
+	defer agent.Close()
+
+	systemPrompt := agent.SystemPrompt()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	assert.Contains(t, systemPrompt, "Working directory: "+wd)
+	for _, tool := range agent.tools {
+		assert.Contains(t, systemPrompt, "- "+tool.Name()+":")
+	}
+
+	// The executor's prompt starts with the system prompt in place of the generic
+	// prefix; the mock's reply may not parse, but the prompt is what matters here
+	_, err = agent.Execute(context.Background(), "hi")
+	if err != nil {
+		assert.Contains(t, err.Error(), "unable to parse agent output")
+	}
+	assert.True(t, strings.HasPrefix(mockLLM.lastPrompt, systemPrompt), "prompt: %s", mockLLM.lastPrompt)
+	assert.NotContains(t, mockLLM.lastPrompt, "Assistant is a large language model")
+	assert.Contains(t, mockLLM.lastPrompt, "You have access to the following tools", "tool descriptions are kept")
+
+	// Streamed turns cannot call tools, so their prompt does not offer any
+	require.NoError(t, agent.ExecuteStream(context.Background(), "hi", &testStreamHandler{}))
+	assert.Contains(t, mockLLM.lastPrompt, "Working directory: "+wd)
+	assert.Contains(t, mockLLM.lastPrompt, "No tools are available")
+	assert.NotContains(t, mockLLM.lastPrompt, "Tools available to you")
+	assert.NotContains(t, mockLLM.lastPrompt, "only run calls matching these patterns")
+}
+
+func TestBuildSystemPrompts(t *testing.T) {
+	viper.Reset()
+	require.NoError(t, config.Load())
+
+	withTools, withoutTools, err := BuildSystemPrompts([]tools.Tool{tools.Calculator{}}, acl.NewPermissionManagerWithBypass(true))
+	require.NoError(t, err)
+	assert.Contains(t, withTools, "- calculator:")
+	assert.Contains(t, withTools, "Permission checks are disabled")
+	assert.Contains(t, withoutTools, "No tools are available")
+	assert.NotContains(t, withoutTools, "calculator")
+}
+
+func TestEscapeGoTemplate(t *testing.T) {
+	text := "Use {{.Name}} and {{ braces }} as written"
+	template := prompts.NewPromptTemplate(escapeGoTemplate(text), nil)
+	template.TemplateFormat = prompts.TemplateFormatGoTemplate
+
+	formatted, err := template.Format(map[string]any{})
+	require.NoError(t, err)
+	assert.Equal(t, text, formatted)
+}
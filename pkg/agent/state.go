@@ -3,6 +3,9 @@ package agent
 import (
 	"sync"
 	"time"
+
+	"github.com/killallgit/ryan/pkg/memory"
+	"github.com/killallgit/ryan/pkg/retrieval"
 )
 
 // ExecutionState represents the current state of agent execution
@@ -21,6 +24,12 @@ type ExecutionState struct {
 	// Agent's current reasoning/thought process
 	CurrentThought string `json:"current_thought,omitempty"`
 
+	// Agent's task list for the session, kept across requests
+	Todos []memory.Todo `json:"todos,omitempty"`
+
+	// Retrieved passages the model was given for this request
+	Citations []retrieval.Citation `json:"citations,omitempty"`
+
 	// Timestamp of last update
 	LastUpdated time.Time `json:"last_updated"`
 }
@@ -142,6 +151,22 @@ func (s *ExecutionState) SetThought(thought string) {
 	s.LastUpdated = time.Now()
 }
 
+// SetTodos updates the agent's task list
+func (s *ExecutionState) SetTodos(todos []memory.Todo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Todos = append([]memory.Todo{}, todos...)
+	s.LastUpdated = time.Now()
+}
+
+// SetCitations records the passages behind the current response
+func (s *ExecutionState) SetCitations(citations []retrieval.Citation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Citations = append([]retrieval.Citation{}, citations...)
+	s.LastUpdated = time.Now()
+}
+
 // GetSnapshot returns a snapshot of the current state
 func (s *ExecutionState) GetSnapshot() ExecutionStateSnapshot {
 	s.mu.RLock()
@@ -162,19 +187,27 @@ func (s *ExecutionState) GetSnapshot() ExecutionStateSnapshot {
 	snapshot.ToolHistory = make([]ToolExecution, len(s.ToolHistory))
 	copy(snapshot.ToolHistory, s.ToolHistory)
 
+	// Copy todos
+	snapshot.Todos = append([]memory.Todo{}, s.Todos...)
+
+	// Copy citations
+	snapshot.Citations = append([]retrieval.Citation{}, s.Citations...)
+
 	return snapshot
 }
 
 // ExecutionStateSnapshot is a thread-safe copy of the execution state
 type ExecutionStateSnapshot struct {
-	Phase          ExecutionPhase  `json:"phase"`
-	CurrentTool    *ToolExecution  `json:"current_tool,omitempty"`
-	ToolHistory    []ToolExecution `json:"tool_history"`
-	CurrentThought string          `json:"current_thought,omitempty"`
-	LastUpdated    time.Time       `json:"last_updated"`
+	Phase          ExecutionPhase       `json:"phase"`
+	CurrentTool    *ToolExecution       `json:"current_tool,omitempty"`
+	ToolHistory    []ToolExecution      `json:"tool_history"`
+	CurrentThought string               `json:"current_thought,omitempty"`
+	Todos          []memory.Todo        `json:"todos,omitempty"`
+	Citations      []retrieval.Citation `json:"citations,omitempty"`
+	LastUpdated    time.Time            `json:"last_updated"`
 }
 
-// Reset clears the execution state
+// Reset clears the execution state for a new request; todos are kept
 func (s *ExecutionState) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -183,6 +216,7 @@ func (s *ExecutionState) Reset() {
 	s.CurrentTool = nil
 	s.ToolHistory = make([]ToolExecution, 0)
 	s.CurrentThought = ""
+	s.Citations = nil
 	s.LastUpdated = time.Now()
 }
 
@@ -0,0 +1,371 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/killallgit/ryan/pkg/config"
+	"github.com/killallgit/ryan/pkg/logger"
+)
+
+const (
+	manifestFile = "manifest.json"
+	filesDir     = "files"
+)
+
+// FileRecord is the pre-image of a file captured before it was first modified in a turn
+type FileRecord struct {
+	Path    string      `json:"path"`
+	Existed bool        `json:"existed"`
+	Mode    os.FileMode `json:"mode,omitempty"`
+	Blob    string      `json:"blob,omitempty"`
+}
+
+// Turn contains the files modified during a single agent turn
+type Turn struct {
+	Number  int          `json:"turn"`
+	Prompt  string       `json:"prompt"`
+	Created time.Time    `json:"created"`
+	Files   []FileRecord `json:"files"`
+}
+
+// Session summarizes the checkpoints stored for a session
+type Session struct {
+	ID       string
+	Turns    int
+	Modified time.Time
+}
+
+// Store records file pre-images per session and turn under a checkpoint directory
+type Store struct {
+	mu        sync.Mutex
+	dir       string
+	sessionID string
+	turn      int
+	prompt    string
+	recorded  map[string]bool
+}
+
+// DefaultDir returns the checkpoint directory inside the settings directory
+func DefaultDir() string {
+	return config.BuildSettingsPath("checkpoints")
+}
+
+// NewStore opens the checkpoint store for a session, continuing its turn numbering.
+// Nothing is written until a file is recorded.
+func NewStore(root, sessionID string) (*Store, error) {
+	s := &Store{
+		dir:       filepath.Join(root, sessionID),
+		sessionID: sessionID,
+		recorded:  make(map[string]bool),
+	}
+
+	turns, err := s.turnNumbers()
+	if err != nil {
+		return nil, err
+	}
+	if len(turns) > 0 {
+		s.turn = turns[len(turns)-1]
+	}
+
+	return s, nil
+}
+
+// SessionID returns the session the store records checkpoints for
+func (s *Store) SessionID() string {
+	return s.sessionID
+}
+
+// BeginTurn starts a new turn; files recorded afterwards belong to it
+func (s *Store) BeginTurn(prompt string) int {
+	if s == nil {
+		return 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.turn++
+	s.prompt = prompt
+	s.recorded = make(map[string]bool)
+	logger.Debug("Checkpoint turn %d started for session %s", s.turn, s.sessionID)
+	return s.turn
+}
+
+// Record captures the current content of a file before it is modified.
+// Only the first modification of a file within a turn is recorded.
+func (s *Store) Record(path string) error {
+	if s == nil {
+		return nil
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Files modified outside an agent turn get a turn of their own
+	if s.turn == 0 {
+		s.turn = 1
+	}
+	if s.recorded[abs] {
+		return nil
+	}
+
+	turnDir := filepath.Join(s.dir, strconv.Itoa(s.turn))
+	turn, err := readTurn(turnDir)
+	if os.IsNotExist(err) {
+		turn = &Turn{Number: s.turn, Prompt: s.prompt, Created: time.Now()}
+	} else if err != nil {
+		return err
+	}
+
+	record := FileRecord{Path: abs}
+	stat, err := os.Stat(abs)
+	switch {
+	case os.IsNotExist(err):
+		// The file will be created; rewinding removes it
+	case err != nil:
+		return fmt.Errorf("failed to stat %s: %w", abs, err)
+	case !stat.Mode().IsRegular():
+		return fmt.Errorf("%s is not a regular file", abs)
+	default:
+		data, err := os.ReadFile(abs)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", abs, err)
+		}
+
+		blob := strconv.Itoa(len(turn.Files))
+		if err := os.MkdirAll(filepath.Join(turnDir, filesDir), 0755); err != nil {
+			return fmt.Errorf("failed to create checkpoint directory: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(turnDir, filesDir, blob), data, 0600); err != nil {
+			return fmt.Errorf("failed to store pre-image of %s: %w", abs, err)
+		}
+		record.Existed = true
+		record.Mode = stat.Mode().Perm()
+		record.Blob = blob
+	}
+
+	turn.Files = append(turn.Files, record)
+	if err := writeTurn(turnDir, turn); err != nil {
+		return err
+	}
+
+	s.recorded[abs] = true
+	logger.Debug("Recorded checkpoint of %s for turn %d", abs, s.turn)
+	return nil
+}
+
+// Turns returns the turns that modified files, oldest first
+func (s *Store) Turns() ([]Turn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	numbers, err := s.turnNumbers()
+	if err != nil {
+		return nil, err
+	}
+
+	turns := make([]Turn, 0, len(numbers))
+	for _, n := range numbers {
+		turn, err := readTurn(filepath.Join(s.dir, strconv.Itoa(n)))
+		if err != nil {
+			return nil, err
+		}
+		turns = append(turns, *turn)
+	}
+	return turns, nil
+}
+
+// Rewind restores every file modified in the given turn or later to its state
+// before that turn, then discards those checkpoints. It returns the restored paths.
+func (s *Store) Rewind(turn int) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	numbers, err := s.turnNumbers()
+	if err != nil {
+		return nil, err
+	}
+
+	// The earliest pre-image of each file at or after the turn is its state before the turn
+	type preImage struct {
+		record FileRecord
+		dir    string
+	}
+	images := make(map[string]preImage)
+	var order []string
+	var turnDirs []string
+
+	for _, n := range numbers {
+		if n < turn {
+			continue
+		}
+		dir := filepath.Join(s.dir, strconv.Itoa(n))
+		t, err := readTurn(dir)
+		if err != nil {
+			return nil, err
+		}
+		turnDirs = append(turnDirs, dir)
+		for _, record := range t.Files {
+			if _, seen := images[record.Path]; seen {
+				continue
+			}
+			images[record.Path] = preImage{record: record, dir: dir}
+			order = append(order, record.Path)
+		}
+	}
+
+	if len(turnDirs) == 0 {
+		return nil, fmt.Errorf("no checkpoints found at or after turn %d", turn)
+	}
+
+	for _, path := range order {
+		image := images[path]
+		if err := restore(image.record, image.dir); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, dir := range turnDirs {
+		if err := os.RemoveAll(dir); err != nil {
+			logger.Warn("Failed to remove checkpoint %s: %v", dir, err)
+		}
+	}
+	s.recorded = make(map[string]bool)
+
+	sort.Strings(order)
+	logger.Info("Rewound %d file(s) to before turn %d", len(order), turn)
+	return order, nil
+}
+
+// turnNumbers returns the stored turn numbers in ascending order
+func (s *Store) turnNumbers() ([]int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint directory: %w", err)
+	}
+
+	var numbers []int
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if n, err := strconv.Atoi(entry.Name()); err == nil {
+			numbers = append(numbers, n)
+		}
+	}
+	sort.Ints(numbers)
+	return numbers, nil
+}
+
+// restore writes a pre-image back, or removes a file that did not exist
+func restore(record FileRecord, turnDir string) error {
+	if !record.Existed {
+		if err := os.Remove(record.Path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", record.Path, err)
+		}
+		return nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(turnDir, filesDir, record.Blob))
+	if err != nil {
+		return fmt.Errorf("failed to read pre-image of %s: %w", record.Path, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(record.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(record.Path, data, record.Mode); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", record.Path, err)
+	}
+	return os.Chmod(record.Path, record.Mode)
+}
+
+func readTurn(dir string) (*Turn, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return nil, err
+	}
+	var turn Turn
+	if err := json.Unmarshal(data, &turn); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint manifest in %s: %w", dir, err)
+	}
+	return &turn, nil
+}
+
+func writeTurn(dir string, turn *Turn) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	data, err := json.MarshalIndent(turn, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, manifestFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint manifest: %w", err)
+	}
+	return nil
+}
+
+// ListSessions returns the sessions with checkpoints, most recently modified first
+func ListSessions(root string) ([]Session, error) {
+	entries, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint directory: %w", err)
+	}
+
+	var sessions []Session
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		store := &Store{dir: filepath.Join(root, entry.Name()), sessionID: entry.Name()}
+		numbers, err := store.turnNumbers()
+		if err != nil || len(numbers) == 0 {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		modified := info.ModTime()
+		if latest, err := os.Stat(filepath.Join(store.dir, strconv.Itoa(numbers[len(numbers)-1]), manifestFile)); err == nil {
+			modified = latest.ModTime()
+		}
+		sessions = append(sessions, Session{ID: entry.Name(), Turns: len(numbers), Modified: modified})
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Modified.After(sessions[j].Modified)
+	})
+	return sessions, nil
+}
+
+// Summary describes a turn on a single line
+func (t Turn) Summary() string {
+	prompt := strings.Join(strings.Fields(t.Prompt), " ")
+	if len(prompt) > 60 {
+		prompt = prompt[:57] + "..."
+	}
+	if prompt == "" {
+		prompt = "(no prompt)"
+	}
+	return fmt.Sprintf("turn %d  %s  %d file(s)  %s", t.Number, t.Created.Format("2006-01-02 15:04"), len(t.Files), prompt)
+}
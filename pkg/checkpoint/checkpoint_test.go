@@ -0,0 +1,124 @@
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreRecordAndRewind(t *testing.T) {
+	root := t.TempDir()
+	work := t.TempDir()
+
+	existing := filepath.Join(work, "main.go")
+	created := filepath.Join(work, "sub", "new.go")
+	require.NoError(t, os.WriteFile(existing, []byte("v0"), 0640))
+
+	store, err := NewStore(root, "session_1")
+	require.NoError(t, err)
+
+	// Turn 1 modifies an existing file twice; only the first pre-image is kept
+	assert.Equal(t, 1, store.BeginTurn("first change"))
+	require.NoError(t, store.Record(existing))
+	require.NoError(t, os.WriteFile(existing, []byte("v1"), 0640))
+	require.NoError(t, store.Record(existing))
+	require.NoError(t, os.WriteFile(existing, []byte("v1b"), 0640))
+
+	// Turn 2 has no file changes and is not stored
+	store.BeginTurn("just a question")
+
+	// Turn 3 modifies the file again and creates a new one
+	store.BeginTurn("second change")
+	require.NoError(t, store.Record(existing))
+	require.NoError(t, os.WriteFile(existing, []byte("v2"), 0640))
+	require.NoError(t, store.Record(created))
+	require.NoError(t, os.MkdirAll(filepath.Dir(created), 0755))
+	require.NoError(t, os.WriteFile(created, []byte("new"), 0644))
+
+	turns, err := store.Turns()
+	require.NoError(t, err)
+	require.Len(t, turns, 2)
+	assert.Equal(t, 1, turns[0].Number)
+	assert.Equal(t, "first change", turns[0].Prompt)
+	assert.Len(t, turns[0].Files, 1)
+	assert.Equal(t, 3, turns[1].Number)
+	assert.Len(t, turns[1].Files, 2)
+	assert.Contains(t, turns[1].Summary(), "2 file(s)")
+
+	t.Run("rewind latest turn", func(t *testing.T) {
+		restored, err := store.Rewind(3)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{existing, created}, restored)
+
+		data, err := os.ReadFile(existing)
+		require.NoError(t, err)
+		assert.Equal(t, "v1b", string(data))
+		assert.NoFileExists(t, created)
+
+		turns, err := store.Turns()
+		require.NoError(t, err)
+		assert.Len(t, turns, 1)
+	})
+
+	t.Run("rewind to before the first turn", func(t *testing.T) {
+		_, err := store.Rewind(1)
+		require.NoError(t, err)
+
+		data, err := os.ReadFile(existing)
+		require.NoError(t, err)
+		assert.Equal(t, "v0", string(data))
+
+		info, err := os.Stat(existing)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+	})
+
+	t.Run("nothing left to rewind", func(t *testing.T) {
+		_, err := store.Rewind(1)
+		assert.Error(t, err)
+	})
+}
+
+func TestStoreContinuesTurnNumbering(t *testing.T) {
+	root := t.TempDir()
+	file := filepath.Join(t.TempDir(), "a.txt")
+	require.NoError(t, os.WriteFile(file, []byte("a"), 0644))
+
+	store, err := NewStore(root, "default_project_session")
+	require.NoError(t, err)
+	store.BeginTurn("one")
+	store.BeginTurn("two")
+	require.NoError(t, store.Record(file))
+
+	reopened, err := NewStore(root, "default_project_session")
+	require.NoError(t, err)
+	assert.Equal(t, 3, reopened.BeginTurn("three"))
+
+	sessions, err := ListSessions(root)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "default_project_session", sessions[0].ID)
+	assert.Equal(t, 1, sessions[0].Turns)
+}
+
+func TestNilStoreRecordsNothing(t *testing.T) {
+	var store *Store
+	assert.NoError(t, store.Record("/does/not/matter"))
+	assert.Equal(t, 0, store.BeginTurn("ignored"))
+}
+
+func TestNewStoreWritesNothingUntilRecord(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewStore(root, "empty")
+	require.NoError(t, err)
+	store.BeginTurn("no changes")
+
+	assert.NoDirExists(t, filepath.Join(root, "empty"))
+
+	sessions, err := ListSessions(root)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
@@ -40,6 +40,23 @@ type Settings struct {
 		}
 	}
 
+	// Long-term memory across sessions
+	Memory struct {
+		LongTerm struct {
+			Enabled  bool    // Remember turns and facts and recall them in later sessions
+			K        int     // Memories recalled per prompt
+			MinScore float64 // Least similarity for a memory to be recalled
+		}
+	}
+
+	// System prompt configuration
+	Prompt struct {
+		System struct {
+			Enabled        bool // Replace the generic agent prompt with one describing the environment
+			GitStatusLines int  // Uncommitted changes listed before the rest are counted
+		}
+	}
+
 	// Tools configuration
 	Tools struct {
 		Enabled bool
@@ -47,13 +64,33 @@ type Settings struct {
 			Read  struct{ Enabled bool }
 			Write struct{ Enabled bool }
 		}
+		Patch  struct{ Enabled bool }
+		Todo   struct{ Enabled bool }
 		Git    struct{ Enabled bool }
 		Search struct{ Enabled bool }
-		Web    struct{ Enabled bool }
-		Bash   struct {
+		Web    struct {
+			Enabled             bool
+			AllowedPrivateHosts []string
+		}
+		Bash struct {
 			Enabled bool
 			Timeout int
 		}
+		Output struct {
+			Enabled   bool
+			MaxTokens int
+		}
+		Custom  []CustomTool
+		Plugins struct {
+			Enabled bool
+			Dir     string
+			Timeout int
+		}
+	}
+
+	// MCP configuration
+	MCP struct {
+		Servers map[string]MCPServer
 	}
 
 	// Vector store configuration
@@ -67,16 +104,42 @@ type Settings struct {
 			Enabled bool
 			Path    string
 		}
+		SQLite struct {
+			Quantize bool // Rank by int8 vectors, then rescore the best candidates exactly
+		}
 		Embedding struct {
-			Provider string
-			Model    string
-			Endpoint string
-			APIKey   string
+			Provider    string
+			Model       string
+			Endpoint    string
+			APIKey      string
+			Dimensions  int  // Vector size of the local provider
+			BatchSize   int  // Texts per embedding request
+			Concurrency int  // Embedding requests in flight at once
+			Cache       bool // Cache embeddings by content hash in the persistence directory
 		}
 		Retrieval struct {
 			K                int
 			ScoreThreshold   float32
 			MaxContextLength int
+			AutoAugment      bool // Prepend retrieved context to every prompt instead of relying on knowledge_search
+			Hybrid           struct {
+				Enabled       bool    // Keep a BM25 keyword index next to the vectors and fuse both rankings
+				VectorWeight  float64 // Weight of the embedding ranking in the fusion
+				KeywordWeight float64 // Weight of the keyword ranking in the fusion
+			}
+			MMR struct {
+				Enabled bool    // Pick results by maximal marginal relevance to drop near-duplicate chunks
+				Lambda  float64 // Relevance (1) versus diversity (0)
+			}
+			Rerank struct {
+				Enabled bool // Ask the model to score candidate passages against the query
+			}
+			Rewrite struct {
+				Enabled    bool // Rewrite follow-up prompts into standalone queries before augmentation
+				HyDE       bool // Also search with a hypothetical answer passage
+				MultiQuery int  // Alternative phrasings searched as well
+				History    int  // Recent messages shown to the model when rewriting
+			}
 		}
 	}
 
@@ -84,6 +147,35 @@ type Settings struct {
 	ConfigFile string
 }
 
+// MCPServer describes a Model Context Protocol server. Servers with a Command
+// are launched over stdio; servers with a URL use the streamable HTTP transport.
+type MCPServer struct {
+	Command string            `mapstructure:"command"`
+	Args    []string          `mapstructure:"args"`
+	Env     []string          `mapstructure:"env"` // KEY=value pairs added to the server environment
+	URL     string            `mapstructure:"url"`
+	Headers map[string]string `mapstructure:"headers"`
+	Timeout int               `mapstructure:"timeout"` // Seconds per request
+}
+
+// CustomTool declares a project-specific tool that runs a shell command template
+type CustomTool struct {
+	Name        string          `mapstructure:"name"`
+	Description string          `mapstructure:"description"`
+	Command     string          `mapstructure:"command"` // text/template, e.g. "make test TARGET={{.target}}"; strings print shell-quoted
+	Args        []CustomToolArg `mapstructure:"args"`
+	Timeout     int             `mapstructure:"timeout"` // Seconds
+	WorkingDir  string          `mapstructure:"working_dir"`
+}
+
+// CustomToolArg declares an argument of a custom tool
+type CustomToolArg struct {
+	Name        string `mapstructure:"name"`
+	Type        string `mapstructure:"type"` // string, integer, number or boolean
+	Description string `mapstructure:"description"`
+	Required    bool   `mapstructure:"required"`
+}
+
 // Global settings instance
 var Global *Settings
 
@@ -117,7 +209,8 @@ func Init(cfgFile string) error {
 
 	// Read config file if it exists
 	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
+		// Diagnostics go to stderr so stdout stays clean for MCP and headless output
+		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	}
 
 	// Load settings into global struct
@@ -145,15 +238,32 @@ func setDefaults() {
 	viper.SetDefault("langchain.tools.max_iterations", 10)
 	viper.SetDefault("langchain.tools.max_retries", 3)
 
+	// Long-term memory defaults
+	viper.SetDefault("memory.long_term.enabled", false)
+	viper.SetDefault("memory.long_term.k", 3)
+	viper.SetDefault("memory.long_term.min_score", 0.5)
+
+	// System prompt defaults
+	viper.SetDefault("prompt.system.enabled", true)
+	viper.SetDefault("prompt.system.git_status_lines", 20)
+
 	// Tool configuration defaults
 	viper.SetDefault("tools.enabled", true)
 	viper.SetDefault("tools.file.read.enabled", true)
 	viper.SetDefault("tools.file.write.enabled", true)
+	viper.SetDefault("tools.patch.enabled", true)
+	viper.SetDefault("tools.todo.enabled", true)
 	viper.SetDefault("tools.git.enabled", true)
 	viper.SetDefault("tools.search.enabled", true)
 	viper.SetDefault("tools.web.enabled", true)
+	viper.SetDefault("tools.web.allowed_private_hosts", []string{})
 	viper.SetDefault("tools.bash.enabled", true)
 	viper.SetDefault("tools.bash.timeout", 30)
+	viper.SetDefault("tools.output.enabled", true)
+	viper.SetDefault("tools.output.max_tokens", 2000)
+	viper.SetDefault("tools.plugins.enabled", true)
+	viper.SetDefault("tools.plugins.dir", "")
+	viper.SetDefault("tools.plugins.timeout", 60)
 
 	// Vector store defaults
 	viper.SetDefault("vectorstore.enabled", false)
@@ -161,11 +271,27 @@ func setDefaults() {
 	viper.SetDefault("vectorstore.collection.name", "default")
 	viper.SetDefault("vectorstore.persistence.enabled", false)
 	viper.SetDefault("vectorstore.persistence.path", "./data/vectors")
+	viper.SetDefault("vectorstore.sqlite.quantize", false)
 	viper.SetDefault("vectorstore.embedding.provider", "ollama")
 	viper.SetDefault("vectorstore.embedding.model", "nomic-embed-text")
+	viper.SetDefault("vectorstore.embedding.dimensions", 512)
+	viper.SetDefault("vectorstore.embedding.batch_size", 32)
+	viper.SetDefault("vectorstore.embedding.concurrency", 4)
+	viper.SetDefault("vectorstore.embedding.cache", true)
 	viper.SetDefault("vectorstore.retrieval.k", 4)
 	viper.SetDefault("vectorstore.retrieval.score_threshold", 0.0)
 	viper.SetDefault("vectorstore.retrieval.max_context_length", 4000)
+	viper.SetDefault("vectorstore.retrieval.auto_augment", false)
+	viper.SetDefault("vectorstore.retrieval.hybrid.enabled", true)
+	viper.SetDefault("vectorstore.retrieval.hybrid.vector_weight", 1.0)
+	viper.SetDefault("vectorstore.retrieval.hybrid.keyword_weight", 1.0)
+	viper.SetDefault("vectorstore.retrieval.mmr.enabled", true)
+	viper.SetDefault("vectorstore.retrieval.mmr.lambda", 0.7)
+	viper.SetDefault("vectorstore.retrieval.rerank.enabled", false)
+	viper.SetDefault("vectorstore.retrieval.rewrite.enabled", true)
+	viper.SetDefault("vectorstore.retrieval.rewrite.hyde", false)
+	viper.SetDefault("vectorstore.retrieval.rewrite.multi_query", 0)
+	viper.SetDefault("vectorstore.retrieval.rewrite.history", 6)
 }
 
 // Load loads configuration from viper into the Settings struct
@@ -190,15 +316,42 @@ func Load() error {
 	Global.LangChain.Tools.MaxIterations = viper.GetInt("langchain.tools.max_iterations")
 	Global.LangChain.Tools.MaxRetries = viper.GetInt("langchain.tools.max_retries")
 
+	// Long-term memory settings
+	Global.Memory.LongTerm.Enabled = viper.GetBool("memory.long_term.enabled")
+	Global.Memory.LongTerm.K = viper.GetInt("memory.long_term.k")
+	Global.Memory.LongTerm.MinScore = viper.GetFloat64("memory.long_term.min_score")
+
+	// System prompt settings
+	Global.Prompt.System.Enabled = viper.GetBool("prompt.system.enabled")
+	Global.Prompt.System.GitStatusLines = viper.GetInt("prompt.system.git_status_lines")
+
 	// Tools settings
 	Global.Tools.Enabled = viper.GetBool("tools.enabled")
 	Global.Tools.File.Read.Enabled = viper.GetBool("tools.file.read.enabled")
 	Global.Tools.File.Write.Enabled = viper.GetBool("tools.file.write.enabled")
+	Global.Tools.Patch.Enabled = viper.GetBool("tools.patch.enabled")
+	Global.Tools.Todo.Enabled = viper.GetBool("tools.todo.enabled")
 	Global.Tools.Git.Enabled = viper.GetBool("tools.git.enabled")
 	Global.Tools.Search.Enabled = viper.GetBool("tools.search.enabled")
 	Global.Tools.Web.Enabled = viper.GetBool("tools.web.enabled")
+	Global.Tools.Web.AllowedPrivateHosts = viper.GetStringSlice("tools.web.allowed_private_hosts")
 	Global.Tools.Bash.Enabled = viper.GetBool("tools.bash.enabled")
 	Global.Tools.Bash.Timeout = viper.GetInt("tools.bash.timeout")
+	Global.Tools.Output.Enabled = viper.GetBool("tools.output.enabled")
+	Global.Tools.Output.MaxTokens = viper.GetInt("tools.output.max_tokens")
+	Global.Tools.Plugins.Enabled = viper.GetBool("tools.plugins.enabled")
+	Global.Tools.Plugins.Dir = viper.GetString("tools.plugins.dir")
+	Global.Tools.Plugins.Timeout = viper.GetInt("tools.plugins.timeout")
+	Global.Tools.Custom = nil
+	if err := viper.UnmarshalKey("tools.custom", &Global.Tools.Custom); err != nil {
+		return fmt.Errorf("invalid tools.custom configuration: %w", err)
+	}
+
+	// MCP settings
+	Global.MCP.Servers = map[string]MCPServer{}
+	if err := viper.UnmarshalKey("mcp.servers", &Global.MCP.Servers); err != nil {
+		return fmt.Errorf("invalid mcp.servers configuration: %w", err)
+	}
 
 	// Vector store settings
 	Global.VectorStore.Enabled = viper.GetBool("vectorstore.enabled")
@@ -206,13 +359,29 @@ func Load() error {
 	Global.VectorStore.Collection.Name = viper.GetString("vectorstore.collection.name")
 	Global.VectorStore.Persistence.Enabled = viper.GetBool("vectorstore.persistence.enabled")
 	Global.VectorStore.Persistence.Path = viper.GetString("vectorstore.persistence.path")
+	Global.VectorStore.SQLite.Quantize = viper.GetBool("vectorstore.sqlite.quantize")
 	Global.VectorStore.Embedding.Provider = viper.GetString("vectorstore.embedding.provider")
 	Global.VectorStore.Embedding.Model = viper.GetString("vectorstore.embedding.model")
 	Global.VectorStore.Embedding.Endpoint = viper.GetString("vectorstore.embedding.endpoint")
 	Global.VectorStore.Embedding.APIKey = viper.GetString("vectorstore.embedding.api_key")
+	Global.VectorStore.Embedding.Dimensions = viper.GetInt("vectorstore.embedding.dimensions")
+	Global.VectorStore.Embedding.BatchSize = viper.GetInt("vectorstore.embedding.batch_size")
+	Global.VectorStore.Embedding.Concurrency = viper.GetInt("vectorstore.embedding.concurrency")
+	Global.VectorStore.Embedding.Cache = viper.GetBool("vectorstore.embedding.cache")
 	Global.VectorStore.Retrieval.K = viper.GetInt("vectorstore.retrieval.k")
 	Global.VectorStore.Retrieval.ScoreThreshold = float32(viper.GetFloat64("vectorstore.retrieval.score_threshold"))
 	Global.VectorStore.Retrieval.MaxContextLength = viper.GetInt("vectorstore.retrieval.max_context_length")
+	Global.VectorStore.Retrieval.AutoAugment = viper.GetBool("vectorstore.retrieval.auto_augment")
+	Global.VectorStore.Retrieval.Hybrid.Enabled = viper.GetBool("vectorstore.retrieval.hybrid.enabled")
+	Global.VectorStore.Retrieval.Hybrid.VectorWeight = viper.GetFloat64("vectorstore.retrieval.hybrid.vector_weight")
+	Global.VectorStore.Retrieval.Hybrid.KeywordWeight = viper.GetFloat64("vectorstore.retrieval.hybrid.keyword_weight")
+	Global.VectorStore.Retrieval.MMR.Enabled = viper.GetBool("vectorstore.retrieval.mmr.enabled")
+	Global.VectorStore.Retrieval.MMR.Lambda = viper.GetFloat64("vectorstore.retrieval.mmr.lambda")
+	Global.VectorStore.Retrieval.Rerank.Enabled = viper.GetBool("vectorstore.retrieval.rerank.enabled")
+	Global.VectorStore.Retrieval.Rewrite.Enabled = viper.GetBool("vectorstore.retrieval.rewrite.enabled")
+	Global.VectorStore.Retrieval.Rewrite.HyDE = viper.GetBool("vectorstore.retrieval.rewrite.hyde")
+	Global.VectorStore.Retrieval.Rewrite.MultiQuery = viper.GetInt("vectorstore.retrieval.rewrite.multi_query")
+	Global.VectorStore.Retrieval.Rewrite.History = viper.GetInt("vectorstore.retrieval.rewrite.history")
 
 	return nil
 }
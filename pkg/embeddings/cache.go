@@ -0,0 +1,115 @@
+package embeddings
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	_ "github.com/mattn/go-sqlite3" // sqlite3 driver
+)
+
+// Cache stores embeddings in SQLite keyed by model and content hash, so text
+// that was embedded before is not sent to the embedding service again
+type Cache struct {
+	db *sql.DB
+}
+
+// NewCache opens or creates the embedding cache at path
+func NewCache(path string) (*Cache, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create embedding cache directory: %w", err)
+	}
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?mode=rwc&_journal_mode=WAL&_busy_timeout=5000", path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open embedding cache: %w", err)
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS embeddings (
+		model TEXT NOT NULL,
+		hash TEXT NOT NULL,
+		vector BLOB NOT NULL,
+		PRIMARY KEY (model, hash)
+	)`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create embedding cache table: %w", err)
+	}
+
+	return &Cache{db: db}, nil
+}
+
+// Get returns the cached embedding of each text for model, nil where there is none
+func (c *Cache) Get(model string, texts []string) ([][]float32, error) {
+	stmt, err := c.db.Prepare(`SELECT vector FROM embeddings WHERE model = ? AND hash = ?`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedding cache: %w", err)
+	}
+	defer stmt.Close()
+
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		var blob []byte
+		err := stmt.QueryRow(model, contentHash(text)).Scan(&blob)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read embedding cache: %w", err)
+		}
+		vectors[i] = decodeVector(blob)
+	}
+	return vectors, nil
+}
+
+// Put stores the embedding of each text for model
+func (c *Cache) Put(model string, texts []string, vectors [][]float32) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to write embedding cache: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT OR REPLACE INTO embeddings (model, hash, vector) VALUES (?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to write embedding cache: %w", err)
+	}
+	defer stmt.Close()
+
+	for i, text := range texts {
+		if _, err := stmt.Exec(model, contentHash(text), encodeVector(vectors[i])); err != nil {
+			return fmt.Errorf("failed to write embedding cache: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Close closes the cache database
+func (c *Cache) Close() error {
+	return c.db.Close()
+}
+
+func contentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+func encodeVector(v []float32) []byte {
+	buf := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(x))
+	}
+	return buf
+}
+
+func decodeVector(buf []byte) []float32 {
+	v := make([]float32, len(buf)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return v
+}
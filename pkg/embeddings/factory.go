@@ -0,0 +1,36 @@
+package embeddings
+
+import "fmt"
+
+// NewEmbedder creates the embedder for the configured provider
+func NewEmbedder(config Config) (Embedder, error) {
+	switch config.Provider {
+	case "ollama":
+		embedder, err := NewOllamaEmbedder(OllamaConfig{
+			Endpoint:    config.Endpoint,
+			Model:       config.Model,
+			BatchSize:   config.BatchSize,
+			Concurrency: config.Concurrency,
+			CachePath:   config.CachePath,
+			Progress:    config.Progress,
+		})
+		if err != nil {
+			// Return a nil interface rather than a typed nil pointer
+			return nil, err
+		}
+		return embedder, nil
+	case "local":
+		return NewLocalEmbedder(config.Dimensions), nil
+	default:
+		return nil, fmt.Errorf("unsupported embedding provider: %s", config.Provider)
+	}
+}
+
+// ModelName returns the model whose vectors the configured provider produces,
+// empty for the local embedder, which has no model
+func (c Config) ModelName() string {
+	if c.Provider == "local" {
+		return ""
+	}
+	return c.Model
+}
@@ -21,7 +21,7 @@ type Embedder interface {
 
 // Config contains configuration for embedders
 type Config struct {
-	// Provider name (e.g., "ollama", "openai")
+	// Provider name ("ollama" or "local")
 	Provider string
 
 	// Model name for embeddings
@@ -33,6 +33,21 @@ type Config struct {
 	// API key (if applicable)
 	APIKey string
 
+	// Dimensions of the vectors, for providers that let it be chosen (0 for the default)
+	Dimensions int
+
+	// BatchSize is how many texts are sent per request (0 for the provider default)
+	BatchSize int
+
+	// Concurrency is how many requests may run at once (0 for the provider default)
+	Concurrency int
+
+	// CachePath is the embedding cache database (empty disables caching)
+	CachePath string
+
+	// Progress is called as texts are embedded
+	Progress func(EmbedProgress)
+
 	// Additional provider-specific options
 	Options map[string]interface{}
 }
@@ -0,0 +1,166 @@
+package embeddings
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// defaultLocalDimensions is the vector size of the local embedder when none is configured
+const defaultLocalDimensions = 512
+
+// Feature weights of the local embedder. Whole words carry the meaning;
+// word pairs add a little phrase order and character trigrams let related
+// forms such as "index", "indexer" and "indexing" share features.
+const (
+	localWordWeight    = 1.0
+	localBigramWeight  = 0.5
+	localTrigramWeight = 0.25
+)
+
+// localStopWords are too common to say anything about a text
+var localStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "for": true, "from": true, "in": true, "is": true, "it": true, "of": true,
+	"on": true, "or": true, "that": true, "the": true, "this": true, "to": true, "was": true,
+	"with": true,
+}
+
+// LocalEmbedder creates embeddings in process from hashed word, word-pair and
+// character-trigram counts. It needs no model server, so retrieval works
+// offline and in CI, at the cost of matching words rather than meaning.
+type LocalEmbedder struct {
+	dimensions int
+}
+
+// NewLocalEmbedder creates a local embedder producing vectors of the given size (default 512)
+func NewLocalEmbedder(dimensions int) *LocalEmbedder {
+	if dimensions <= 0 {
+		dimensions = defaultLocalDimensions
+	}
+	return &LocalEmbedder{dimensions: dimensions}
+}
+
+// EmbedText creates an embedding for a single text
+func (e *LocalEmbedder) EmbedText(ctx context.Context, text string) ([]float32, error) {
+	features := make(map[string]float64)
+	words := localWords(text)
+	for i, word := range words {
+		features["w:"+word] += localWordWeight
+		if i > 0 {
+			features["b:"+words[i-1]+" "+word] += localBigramWeight
+		}
+		padded := []rune("^" + word + "$")
+		for j := 0; j+3 <= len(padded); j++ {
+			features["t:"+string(padded[j:j+3])] += localTrigramWeight
+		}
+	}
+
+	// Sum in a fixed order so the same text always gives the same bits
+	names := make([]string, 0, len(features))
+	for feature := range features {
+		names = append(names, feature)
+	}
+	sort.Strings(names)
+
+	// Hash every feature into the vector with a sign, so collisions cancel out
+	// rather than pile up, and damp repeated features logarithmically
+	embedding := make([]float32, e.dimensions)
+	for _, feature := range names {
+		count := features[feature]
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		weight := 1 + math.Log(count)
+		if count < 1 {
+			weight = count
+		}
+		if sum>>63 == 1 {
+			weight = -weight
+		}
+		embedding[sum%uint64(e.dimensions)] += float32(weight)
+	}
+
+	var norm float64
+	for _, x := range embedding {
+		norm += float64(x) * float64(x)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range embedding {
+			embedding[i] *= scale
+		}
+	}
+	return embedding, nil
+}
+
+// EmbedTexts creates embeddings for multiple texts
+func (e *LocalEmbedder) EmbedTexts(ctx context.Context, texts []string) ([][]float32, error) {
+	results := make([][]float32, len(texts))
+	for i, text := range texts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		embedding, err := e.EmbedText(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = embedding
+	}
+	return results, nil
+}
+
+// GetDimensions returns the dimensionality of the embeddings
+func (e *LocalEmbedder) GetDimensions() int {
+	return e.dimensions
+}
+
+// Close releases any resources
+func (e *LocalEmbedder) Close() error {
+	return nil
+}
+
+// localWords lowercases text into words, splitting code identifiers such as
+// "newHTTPServer" or "max_tokens" into their parts and dropping stop words
+func localWords(text string) []string {
+	var words []string
+	for _, field := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		for _, part := range SplitIdentifier(field) {
+			part = strings.ToLower(part)
+			if !localStopWords[part] {
+				words = append(words, part)
+			}
+		}
+	}
+	return words
+}
+
+// SplitIdentifier splits a code identifier into its parts at underscores,
+// lower-to-upper and letter-digit boundaries, and before the last capital of
+// an acronym ("HTTPServer" -> HTTP, Server)
+func SplitIdentifier(word string) []string {
+	var parts []string
+	for _, segment := range strings.Split(word, "_") {
+		runes := []rune(segment)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			prev, cur := runes[i-1], runes[i]
+			boundary := unicode.IsLower(prev) && unicode.IsUpper(cur) ||
+				unicode.IsLetter(prev) != unicode.IsLetter(cur) ||
+				unicode.IsUpper(prev) && unicode.IsUpper(cur) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if boundary {
+				parts = append(parts, string(runes[start:i]))
+				start = i
+			}
+		}
+		if start < len(runes) {
+			parts = append(parts, string(runes[start:]))
+		}
+	}
+	return parts
+}
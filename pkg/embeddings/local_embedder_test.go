@@ -0,0 +1,86 @@
+package embeddings
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func similarity(t *testing.T, e Embedder, a, b string) float64 {
+	vectors, err := e.EmbedTexts(context.Background(), []string{a, b})
+	require.NoError(t, err)
+	var dot float64
+	for i := range vectors[0] {
+		dot += float64(vectors[0][i]) * float64(vectors[1][i])
+	}
+	return dot
+}
+
+func TestLocalEmbedder(t *testing.T) {
+	embedder := NewLocalEmbedder(0)
+	assert.Equal(t, defaultLocalDimensions, embedder.GetDimensions())
+
+	vector, err := embedder.EmbedText(context.Background(), "Sessions are stored in SQLite")
+	require.NoError(t, err)
+	require.Len(t, vector, defaultLocalDimensions)
+	var norm float64
+	for _, x := range vector {
+		norm += float64(x) * float64(x)
+	}
+	assert.InDelta(t, 1, math.Sqrt(norm), 1e-5)
+
+	again, err := embedder.EmbedText(context.Background(), "Sessions are stored in SQLite")
+	require.NoError(t, err)
+	assert.Equal(t, vector, again, "embeddings are deterministic")
+
+	// Colliding features are summed in a fixed order, so results are bit-identical
+	small := NewLocalEmbedder(4)
+	text := "the quick brown fox jumps over the lazy dog while newHTTPServer handles max_tokens"
+	first, err := small.EmbedText(context.Background(), text)
+	require.NoError(t, err)
+	for i := 0; i < 20; i++ {
+		next, err := small.EmbedText(context.Background(), text)
+		require.NoError(t, err)
+		require.Equal(t, first, next)
+	}
+
+	empty, err := embedder.EmbedText(context.Background(), "the and of")
+	require.NoError(t, err)
+	assert.Len(t, empty, defaultLocalDimensions, "text of only stop words still has a vector")
+}
+
+func TestLocalEmbedderSimilarity(t *testing.T) {
+	embedder := NewLocalEmbedder(256)
+
+	related := similarity(t, embedder, "where are chat sessions stored", "Sessions are stored in a SQLite database")
+	unrelated := similarity(t, embedder, "where are chat sessions stored", "Install the binary with go install")
+	assert.Greater(t, related, unrelated)
+
+	// Identifiers match the words they are made of
+	code := similarity(t, embedder, "permission manager bypass", "func NewPermissionManagerWithBypass(bypass bool) *PermissionManager")
+	other := similarity(t, embedder, "permission manager bypass", "func NewTokenCounter(model string) *TokenCounter")
+	assert.Greater(t, code, other)
+
+	// Word forms share character trigrams
+	assert.Greater(t, similarity(t, embedder, "indexing", "indexer"), similarity(t, embedder, "indexing", "session"))
+}
+
+func TestNewEmbedderLocal(t *testing.T) {
+	embedder, err := NewEmbedder(Config{Provider: "local", Dimensions: 64})
+	require.NoError(t, err)
+	assert.Equal(t, 64, embedder.GetDimensions())
+
+	_, err = NewEmbedder(Config{Provider: "unknown"})
+	assert.ErrorContains(t, err, "unsupported embedding provider")
+}
+
+func TestSplitIdentifier(t *testing.T) {
+	assert.Equal(t, []string{"new", "HTTP", "Server"}, SplitIdentifier("newHTTPServer"))
+	assert.Equal(t, []string{"utf", "8"}, SplitIdentifier("utf8"))
+	assert.Equal(t, []string{"plain"}, SplitIdentifier("plain"))
+	assert.Equal(t, []string{"max", "Tokens", "2"}, SplitIdentifier("_max__Tokens2"))
+	assert.Equal(t, []string{"max", "tokens"}, localWords("the max_tokens"))
+}
@@ -4,19 +4,45 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/killallgit/ryan/pkg/logger"
+	"golang.org/x/sync/errgroup"
+)
+
+// Defaults for the batched embedding pipeline
+const (
+	defaultOllamaBatchSize   = 32
+	defaultOllamaConcurrency = 4
+	defaultOllamaMaxRetries  = 3
+	ollamaRetryBackoff       = 250 * time.Millisecond
 )
 
-// OllamaEmbedder implements Embedder using Ollama's embedding API
+// OllamaEmbedder implements Embedder using Ollama's embedding API. Texts are
+// sent in batches to /api/embed, several batches at a time, and the results
+// can be cached so unchanged text is never embedded twice.
 type OllamaEmbedder struct {
-	endpoint   string
-	model      string
-	dimensions int
-	client     *http.Client
+	endpoint    string
+	model       string
+	dimensions  int
+	client      *http.Client
+	batchSize   int
+	concurrency int
+	maxRetries  int
+	cache       *Cache
+	progress    func(EmbedProgress)
+	legacy      atomic.Bool // The server has no /api/embed, so texts go to /api/embeddings one by one
+
+	mu    sync.Mutex
+	stats EmbedProgress
 }
 
 // OllamaConfig contains configuration for OllamaEmbedder
@@ -29,6 +55,36 @@ type OllamaConfig struct {
 
 	// Timeout for API requests
 	Timeout time.Duration
+
+	// BatchSize is how many texts are sent per request (default 32)
+	BatchSize int
+
+	// Concurrency is how many requests may run at once (default 4)
+	Concurrency int
+
+	// MaxRetries is how often a failed request is retried with backoff (default 3)
+	MaxRetries int
+
+	// CachePath is the embedding cache database (empty disables caching)
+	CachePath string
+
+	// Progress is called after each batch with the running totals
+	Progress func(EmbedProgress)
+}
+
+// EmbedProgress reports the running totals of an embedder
+type EmbedProgress struct {
+	Embedded int           // Texts sent to the embedding service
+	Cached   int           // Texts answered from the cache
+	Elapsed  time.Duration // Time spent embedding
+}
+
+// Rate returns the texts embedded or answered from the cache per second
+func (p EmbedProgress) Rate() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Embedded+p.Cached) / p.Elapsed.Seconds()
 }
 
 // NewOllamaEmbedder creates a new Ollama embedder
@@ -36,14 +92,28 @@ func NewOllamaEmbedder(config OllamaConfig) (*OllamaEmbedder, error) {
 	if config.Endpoint == "" {
 		return nil, fmt.Errorf("endpoint not provided in config")
 	}
+	return newOllamaEmbedder(config)
+}
 
+// newOllamaEmbedder applies defaults, opens the cache and probes the model's dimensions
+func newOllamaEmbedder(config OllamaConfig) (*OllamaEmbedder, error) {
 	if config.Model == "" {
 		config.Model = "nomic-embed-text"
 	}
-
 	if config.Timeout == 0 {
 		config.Timeout = 30 * time.Second
 	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultOllamaBatchSize
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = defaultOllamaConcurrency
+	}
+	if config.MaxRetries < 0 {
+		config.MaxRetries = 0
+	} else if config.MaxRetries == 0 {
+		config.MaxRetries = defaultOllamaMaxRetries
+	}
 
 	embedder := &OllamaEmbedder{
 		endpoint: config.Endpoint,
@@ -51,6 +121,9 @@ func NewOllamaEmbedder(config OllamaConfig) (*OllamaEmbedder, error) {
 		client: &http.Client{
 			Timeout: config.Timeout,
 		},
+		batchSize:   config.BatchSize,
+		concurrency: config.Concurrency,
+		maxRetries:  config.MaxRetries,
 	}
 
 	// Get model dimensions by creating a test embedding
@@ -59,9 +132,19 @@ func NewOllamaEmbedder(config OllamaConfig) (*OllamaEmbedder, error) {
 
 	testEmbed, err := embedder.EmbedText(ctx, "test")
 	if err != nil {
-		return nil, fmt.Errorf("failed to get embedding dimensions: %w", err)
+		return nil, fmt.Errorf("failed to get embedding dimensions from %s: %w", config.Endpoint, err)
 	}
 	embedder.dimensions = len(testEmbed)
+	embedder.stats = EmbedProgress{}
+	embedder.progress = config.Progress
+
+	if config.CachePath != "" {
+		cache, err := NewCache(config.CachePath)
+		if err != nil {
+			return nil, err
+		}
+		embedder.cache = cache
+	}
 
 	return embedder, nil
 }
@@ -78,74 +161,239 @@ func (e *OllamaEmbedder) EmbedText(ctx context.Context, text string) ([]float32,
 	return embeddings[0], nil
 }
 
-// EmbedTexts creates embeddings for multiple texts
+// EmbedTexts creates embeddings for multiple texts. Cached texts are answered
+// from the cache; the rest are embedded in concurrent batches and cached.
 func (e *OllamaEmbedder) EmbedTexts(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	started := time.Now()
+	var accounted time.Duration
+
 	results := make([][]float32, len(texts))
+	if e.cache != nil {
+		cached, err := e.cache.Get(e.model, texts)
+		if err != nil {
+			logger.Warn("Embedding cache unavailable: %v", err)
+		} else {
+			results = cached
+		}
+	}
 
-	// Ollama API typically handles one text at a time for embeddings
+	var missing []int
+	for i, vector := range results {
+		if vector == nil {
+			missing = append(missing, i)
+		}
+	}
+	e.record(len(texts)-len(missing), 0, started, &accounted)
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(e.concurrency)
+	for start := 0; start < len(missing); start += e.batchSize {
+		batch := missing[start:min(start+e.batchSize, len(missing))]
+		group.Go(func() error {
+			batchTexts := make([]string, len(batch))
+			for j, i := range batch {
+				batchTexts[j] = texts[i]
+			}
+
+			vectors, err := e.embedBatch(groupCtx, batchTexts)
+			if err != nil {
+				return fmt.Errorf("failed to embed texts %d-%d: %w", batch[0], batch[len(batch)-1], err)
+			}
+			for j, i := range batch {
+				results[i] = vectors[j]
+			}
+
+			if e.cache != nil {
+				if err := e.cache.Put(e.model, batchTexts, vectors); err != nil {
+					logger.Warn("Failed to cache embeddings: %v", err)
+				}
+			}
+			e.record(0, len(batch), started, &accounted)
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// record adds to the running totals and reports them. Elapsed grows by the
+// time since started not yet accounted for, so concurrent batches of one call
+// are not counted twice.
+func (e *OllamaEmbedder) record(cached, embedded int, started time.Time, accounted *time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Since(started)
+	e.stats.Cached += cached
+	e.stats.Embedded += embedded
+	e.stats.Elapsed += now - *accounted
+	*accounted = now
+	if e.progress != nil && (cached > 0 || embedded > 0) {
+		e.progress(e.stats)
+	}
+}
+
+// Progress returns the running totals of the embedder
+func (e *OllamaEmbedder) Progress() EmbedProgress {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.stats
+}
+
+// embedBatch embeds texts with one /api/embed request, retrying transient failures.
+// Servers without /api/embed are asked for one text at a time instead.
+func (e *OllamaEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if e.legacy.Load() {
+		return e.embedEach(ctx, texts)
+	}
+
+	var vectors [][]float32
+	err := e.withRetry(ctx, func() error {
+		var err error
+		vectors, err = e.embedRequest(ctx, texts)
+		return err
+	})
+	if errors.Is(err, errEmbedNotFound) {
+		logger.Debug("Ollama has no /api/embed, embedding texts one at a time")
+		e.legacy.Store(true)
+		return e.embedEach(ctx, texts)
+	}
+	return vectors, err
+}
+
+// embedEach embeds texts through the single-text /api/embeddings endpoint
+func (e *OllamaEmbedder) embedEach(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
 	for i, text := range texts {
-		embedding, err := e.embedSingle(ctx, text)
+		err := e.withRetry(ctx, func() error {
+			var err error
+			vectors[i], err = e.embedSingle(ctx, text)
+			return err
+		})
 		if err != nil {
-			return nil, fmt.Errorf("failed to embed text %d: %w", i, err)
+			return nil, err
 		}
-		results[i] = embedding
 	}
+	return vectors, nil
+}
 
-	return results, nil
+// withRetry runs call until it succeeds, fails permanently or runs out of retries,
+// doubling the wait after each attempt
+func (e *OllamaEmbedder) withRetry(ctx context.Context, call func() error) error {
+	backoff := ollamaRetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := call()
+		var status *statusError
+		permanent := errors.As(err, &status) && !status.retryable()
+		if err == nil || permanent || errors.Is(err, errEmbedNotFound) || attempt >= e.maxRetries {
+			return err
+		}
+
+		logger.Debug("Embedding request failed (attempt %d of %d), retrying in %s: %v", attempt+1, e.maxRetries+1, backoff, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
 }
 
-// embedSingle creates an embedding for a single text using Ollama API
-func (e *OllamaEmbedder) embedSingle(ctx context.Context, text string) ([]float32, error) {
-	// Prepare request
-	reqBody := map[string]interface{}{
-		"model":  e.model,
-		"prompt": text,
+// errEmbedNotFound means the server does not have the batch endpoint
+var errEmbedNotFound = errors.New("embed endpoint not found")
+
+// statusError is an unsuccessful API response
+type statusError struct {
+	code int
+	body string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("API error (status %d): %s", e.code, e.body)
+}
+
+// retryable reports whether the request may succeed if sent again
+func (e *statusError) retryable() bool {
+	return e.code == http.StatusTooManyRequests || e.code >= http.StatusInternalServerError
+}
+
+// embedRequest embeds texts with one request to /api/embed
+func (e *OllamaEmbedder) embedRequest(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := e.post(ctx, "/api/embed", map[string]interface{}{
+		"model": e.model,
+		"input": texts,
+	})
+	if err != nil {
+		var status *statusError
+		if errors.As(err, &status) && status.code == http.StatusNotFound && !strings.Contains(status.body, "model") {
+			return nil, errEmbedNotFound
+		}
+		return nil, err
+	}
+
+	var result struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
+	if len(result.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(result.Embeddings))
+	}
+	return result.Embeddings, nil
+}
 
-	jsonData, err := json.Marshal(reqBody)
+// post sends a JSON request and returns the body of a successful response
+func (e *OllamaEmbedder) post(ctx context.Context, path string, payload interface{}) ([]byte, error) {
+	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	// Create request
-	req, err := http.NewRequestWithContext(ctx, "POST", e.endpoint+"/api/embeddings", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", e.endpoint+path, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	// Send request
 	resp, err := e.client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	// Read response
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
-
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, &statusError{code: resp.StatusCode, body: string(body)}
+	}
+	return body, nil
+}
+
+// embedSingle creates an embedding for a single text using the /api/embeddings endpoint
+func (e *OllamaEmbedder) embedSingle(ctx context.Context, text string) ([]float32, error) {
+	body, err := e.post(ctx, "/api/embeddings", map[string]interface{}{
+		"model":  e.model,
+		"prompt": text,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Parse response
 	var result struct {
-		Embedding []float64 `json:"embedding"`
+		Embedding []float32 `json:"embedding"`
 	}
 	if err := json.Unmarshal(body, &result); err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
-
-	// Convert float64 to float32
-	embedding := make([]float32, len(result.Embedding))
-	for i, v := range result.Embedding {
-		embedding[i] = float32(v)
-	}
-
-	return embedding, nil
+	return result.Embedding, nil
 }
 
 // GetDimensions returns the dimensionality of the embeddings
@@ -156,6 +404,9 @@ func (e *OllamaEmbedder) GetDimensions() int {
 // Close releases any resources
 func (e *OllamaEmbedder) Close() error {
 	// HTTP client doesn't need explicit closing
+	if e.cache != nil {
+		return e.cache.Close()
+	}
 	return nil
 }
 
@@ -175,31 +426,5 @@ func NewOllamaEmbedderForTesting(config OllamaConfig) (*OllamaEmbedder, error) {
 		config.Endpoint = ollamaHost
 	}
 
-	if config.Model == "" {
-		config.Model = "nomic-embed-text"
-	}
-
-	if config.Timeout == 0 {
-		config.Timeout = 30 * time.Second
-	}
-
-	embedder := &OllamaEmbedder{
-		endpoint: config.Endpoint,
-		model:    config.Model,
-		client: &http.Client{
-			Timeout: config.Timeout,
-		},
-	}
-
-	// Get model dimensions by creating a test embedding
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	testEmbed, err := embedder.EmbedText(ctx, "test")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get embedding dimensions from %s: %w", config.Endpoint, err)
-	}
-	embedder.dimensions = len(testEmbed)
-
-	return embedder, nil
+	return newOllamaEmbedder(config)
 }
@@ -1,9 +1,18 @@
 package embeddings
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestOllamaEmbedderConfig(t *testing.T) {
@@ -34,3 +43,180 @@ func TestOllamaEmbedderConfig(t *testing.T) {
 		assert.Nil(t, embedder)
 	})
 }
+
+// fakeOllama serves /api/embed and /api/embeddings, embedding each text as its length
+type fakeOllama struct {
+	mu           sync.Mutex
+	batches      [][]string
+	single       int
+	failures     int  // Requests to fail with a 503 before succeeding
+	noBatch      bool // Answer /api/embed with 404 like older servers
+	inFlight     int
+	peakInFlight int
+}
+
+func (f *fakeOllama) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Input  []string `json:"input"`
+		Prompt string   `json:"prompt"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+
+	f.mu.Lock()
+	f.inFlight++
+	f.peakInFlight = max(f.peakInFlight, f.inFlight)
+	fail := f.failures > 0
+	if fail {
+		f.failures--
+	}
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.inFlight--
+		f.mu.Unlock()
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	switch {
+	case fail:
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	case r.URL.Path == "/api/embed" && f.noBatch:
+		http.NotFound(w, r)
+	case r.URL.Path == "/api/embed":
+		f.mu.Lock()
+		f.batches = append(f.batches, req.Input)
+		f.mu.Unlock()
+		vectors := make([][]float32, len(req.Input))
+		for i, text := range req.Input {
+			vectors[i] = []float32{float32(len(text)), 1}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"embeddings": vectors})
+	default:
+		f.mu.Lock()
+		f.single++
+		f.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"embedding": []float32{float32(len(req.Prompt)), 1}})
+	}
+}
+
+func (f *fakeOllama) requests() (batches [][]string, single int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]string(nil), f.batches...), f.single
+}
+
+func testTexts(n int) []string {
+	texts := make([]string, n)
+	for i := range texts {
+		texts[i] = strings.Repeat("x", i+1)
+	}
+	return texts
+}
+
+func TestOllamaEmbedderBatches(t *testing.T) {
+	fake := &fakeOllama{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	var reports []EmbedProgress
+	var mu sync.Mutex
+	embedder, err := NewOllamaEmbedder(OllamaConfig{
+		Endpoint:    server.URL,
+		BatchSize:   4,
+		Concurrency: 2,
+		Progress: func(p EmbedProgress) {
+			mu.Lock()
+			reports = append(reports, p)
+			mu.Unlock()
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, embedder.GetDimensions())
+
+	texts := testTexts(10)
+	vectors, err := embedder.EmbedTexts(context.Background(), texts)
+	require.NoError(t, err)
+	for i, vector := range vectors {
+		assert.Equal(t, float32(i+1), vector[0], "vectors stay in input order")
+	}
+
+	batches, _ := fake.requests()
+	require.Len(t, batches, 4, "the dimension probe and three batches")
+	for _, batch := range batches[1:] {
+		assert.LessOrEqual(t, len(batch), 4)
+	}
+	assert.LessOrEqual(t, fake.peakInFlight, 2)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, reports, 3)
+	last := reports[len(reports)-1]
+	assert.Equal(t, 10, last.Embedded)
+	assert.Positive(t, last.Rate())
+	assert.Equal(t, last, embedder.Progress())
+}
+
+func TestOllamaEmbedderRetriesAndFallback(t *testing.T) {
+	fake := &fakeOllama{failures: 2}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	// Transient failures are retried
+	embedder, err := NewOllamaEmbedder(OllamaConfig{Endpoint: server.URL})
+	require.NoError(t, err)
+	_, err = embedder.EmbedTexts(context.Background(), testTexts(3))
+	require.NoError(t, err)
+
+	// Running out of retries returns the error
+	fake.failures = 10
+	embedder.maxRetries = 1
+	_, err = embedder.EmbedTexts(context.Background(), testTexts(3))
+	assert.ErrorContains(t, err, "status 503")
+
+	// Servers without /api/embed are asked one text at a time
+	fake.failures = 0
+	fake.noBatch = true
+	legacy, err := NewOllamaEmbedder(OllamaConfig{Endpoint: server.URL})
+	require.NoError(t, err)
+	vectors, err := legacy.EmbedTexts(context.Background(), testTexts(3))
+	require.NoError(t, err)
+	assert.Equal(t, float32(3), vectors[2][0])
+	_, single := fake.requests()
+	assert.Equal(t, 4, single)
+}
+
+func TestOllamaEmbedderCache(t *testing.T) {
+	fake := &fakeOllama{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	cachePath := filepath.Join(t.TempDir(), "embeddings.db")
+	ctx := context.Background()
+
+	embedder, err := NewOllamaEmbedder(OllamaConfig{Endpoint: server.URL, CachePath: cachePath})
+	require.NoError(t, err)
+	want, err := embedder.EmbedTexts(ctx, testTexts(5))
+	require.NoError(t, err)
+	require.NoError(t, embedder.Close())
+	before, _ := fake.requests()
+
+	// A new embedder on the same cache only embeds text it has not seen
+	embedder, err = NewOllamaEmbedder(OllamaConfig{Endpoint: server.URL, CachePath: cachePath})
+	require.NoError(t, err)
+	defer embedder.Close()
+	got, err := embedder.EmbedTexts(ctx, append(testTexts(5), "new text"))
+	require.NoError(t, err)
+	assert.Equal(t, want, got[:5])
+
+	after, _ := fake.requests()
+	require.Len(t, after, len(before)+2, "the probe and one batch")
+	assert.Equal(t, []string{"new text"}, after[len(after)-1])
+	assert.Equal(t, EmbedProgress{Embedded: 1, Cached: 5, Elapsed: embedder.Progress().Elapsed}, embedder.Progress())
+
+	// The cache is keyed by model
+	cache, err := NewCache(cachePath)
+	require.NoError(t, err)
+	defer cache.Close()
+	vectors, err := cache.Get("other-model", testTexts(1))
+	require.NoError(t, err)
+	assert.Nil(t, vectors[0])
+}
@@ -1,9 +1,47 @@
 package headless
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
+
 	"github.com/killallgit/ryan/pkg/logger"
+	"github.com/killallgit/ryan/pkg/retrieval"
+)
+
+// OutputFormat selects how headless mode prints the response
+type OutputFormat string
+
+const (
+	// OutputText streams the response as plain text
+	OutputText OutputFormat = "text"
+	// OutputJSON prints a single JSON object once the response is complete
+	OutputJSON OutputFormat = "json"
 )
 
+// ParseOutputFormat validates an --output-format value
+func ParseOutputFormat(value string) (OutputFormat, error) {
+	switch format := OutputFormat(value); format {
+	case OutputText, OutputJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown output format %q (use text or json)", value)
+	}
+}
+
+// Result is the JSON document printed with --output-format json
+type Result struct {
+	Response  string               `json:"response"`
+	Citations []retrieval.Citation `json:"citations"`
+	Tokens    TokenUsage           `json:"tokens"`
+}
+
+// TokenUsage counts the tokens exchanged for a headless run
+type TokenUsage struct {
+	Sent     int `json:"sent"`
+	Received int `json:"received"`
+}
+
 // Output handles console output for headless mode
 type Output struct{}
 
@@ -16,3 +54,28 @@ func NewOutput() *Output {
 func (o *Output) Error(msg string) {
 	logger.Error(msg)
 }
+
+// Sources prints footnotes for the passages a response cites
+func (o *Output) Sources(citations []retrieval.Citation) {
+	printed := false
+	for _, citation := range citations {
+		if !citation.Cited {
+			continue
+		}
+		if !printed {
+			fmt.Print("\n\nSources:")
+			printed = true
+		}
+		fmt.Printf("\n  [%d] %s", citation.Number, citation.Location())
+	}
+}
+
+// JSON prints a result as indented JSON on stdout
+func (o *Output) JSON(result Result) error {
+	if result.Citations == nil {
+		result.Citations = []retrieval.Citation{}
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
@@ -16,12 +16,17 @@ func RunHeadless(agent agent.Agent, prompt string) error {
 
 // RunHeadlessWithOptions executes a single prompt in headless mode with options
 func RunHeadlessWithOptions(agent agent.Agent, prompt string, continueHistory bool) error {
+	return RunHeadlessWithFormat(agent, prompt, continueHistory, OutputText)
+}
+
+// RunHeadlessWithFormat executes a single prompt in headless mode and prints the result in format
+func RunHeadlessWithFormat(agent agent.Agent, prompt string, continueHistory bool, format OutputFormat) error {
 	if prompt == "" {
 		return fmt.Errorf("prompt cannot be empty in headless mode")
 	}
 
 	// Create runner (internal implementation detail)
-	runner, err := newRunnerWithOptions(agent, continueHistory)
+	runner, err := newRunnerWithOptions(agent, continueHistory, format)
 	if err != nil {
 		return fmt.Errorf("failed to initialize headless mode: %w", err)
 	}
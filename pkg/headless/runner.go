@@ -26,21 +26,23 @@ type runConfig struct {
 	historyPath     string
 	showThinking    bool
 	continueHistory bool
+	outputFormat    OutputFormat
 }
 
 // newRunner creates a new headless runner with injected agent
 func newRunner(agent agent.Agent) (*runner, error) {
-	return newRunnerWithOptions(agent, false)
+	return newRunnerWithOptions(agent, false, OutputText)
 }
 
 // newRunnerWithOptions creates a new headless runner with injected agent and options
-func newRunnerWithOptions(agent agent.Agent, continueHistory bool) (*runner, error) {
+func newRunnerWithOptions(agent agent.Agent, continueHistory bool, format OutputFormat) (*runner, error) {
 	// Setup configuration using config helper
 	settings := config.Get()
 	cfg := &runConfig{
 		historyPath:     config.BuildSettingsPath("chat_history.json"),
 		showThinking:    settings.ShowThinking,
 		continueHistory: continueHistory,
+		outputFormat:    format,
 	}
 
 	// Create chat manager
@@ -112,7 +114,7 @@ func (r *runner) run(ctx context.Context, prompt string) error {
 	}
 
 	// Create a stream handler that prints to console and collects content
-	streamHandler := newHeadlessStreamHandler()
+	streamHandler := newHeadlessStreamHandler(r.config.outputFormat == OutputJSON)
 
 	// Use agent to generate streaming response
 	generateErr := r.agent.ExecuteStream(ctx, prompt, streamHandler)
@@ -141,9 +143,21 @@ func (r *runner) run(ctx context.Context, prompt string) error {
 		return fmt.Errorf("failed to end streaming: %w", err)
 	}
 
-	// Print token summary
-	fmt.Printf("\n[Tokens - Sent: %d, Received: %d, Total: %d]\n",
-		r.tokensSent, r.tokensRecv, r.tokensSent+r.tokensRecv)
+	if r.config.outputFormat == OutputJSON {
+		if err := r.output.JSON(Result{
+			Response:  finalContent,
+			Citations: streamHandler.GetCitations(),
+			Tokens:    TokenUsage{Sent: r.tokensSent, Received: r.tokensRecv},
+		}); err != nil {
+			return fmt.Errorf("failed to write JSON output: %w", err)
+		}
+	} else {
+		r.output.Sources(streamHandler.GetCitations())
+
+		// Print token summary
+		fmt.Printf("\n[Tokens - Sent: %d, Received: %d, Total: %d]\n",
+			r.tokensSent, r.tokensRecv, r.tokensSent+r.tokensRecv)
+	}
 
 	// Log completion for debugging
 	logger.Debug("Response complete (tokens: %d)", responseTokens)
@@ -4,18 +4,25 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+
+	"github.com/killallgit/ryan/pkg/agent"
+	"github.com/killallgit/ryan/pkg/retrieval"
 )
 
 // headlessStreamHandler handles streaming output for headless mode
 // It prints chunks to stdout and accumulates the content
 type headlessStreamHandler struct {
-	content strings.Builder
-	mu      sync.Mutex
+	content   strings.Builder
+	citations []retrieval.Citation
+	quiet     bool // Accumulate without printing, for JSON output
+	mu        sync.Mutex
 }
 
+var _ agent.CitationHandler = (*headlessStreamHandler)(nil)
+
 // newHeadlessStreamHandler creates a handler for headless streaming output
-func newHeadlessStreamHandler() *headlessStreamHandler {
-	return &headlessStreamHandler{}
+func newHeadlessStreamHandler(quiet bool) *headlessStreamHandler {
+	return &headlessStreamHandler{quiet: quiet}
 }
 
 // OnChunk prints chunk to stdout and accumulates it
@@ -24,7 +31,9 @@ func (h *headlessStreamHandler) OnChunk(chunk []byte) error {
 	defer h.mu.Unlock()
 
 	// Print to stdout for immediate output
-	fmt.Print(string(chunk))
+	if !h.quiet {
+		fmt.Print(string(chunk))
+	}
 
 	// Also accumulate for final content
 	h.content.Write(chunk)
@@ -44,6 +53,13 @@ func (h *headlessStreamHandler) OnComplete(finalContent string) error {
 	return nil
 }
 
+// OnCitations keeps the sources behind the response
+func (h *headlessStreamHandler) OnCitations(citations []retrieval.Citation) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.citations = citations
+}
+
 // OnError handles streaming errors
 func (h *headlessStreamHandler) OnError(err error) {
 	// In headless mode, errors are handled by the runner
@@ -56,3 +72,10 @@ func (h *headlessStreamHandler) GetContent() string {
 	defer h.mu.Unlock()
 	return h.content.String()
 }
+
+// GetCitations returns the sources reported for the response
+func (h *headlessStreamHandler) GetCitations() []retrieval.Citation {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.citations
+}
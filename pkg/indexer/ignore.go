@@ -0,0 +1,113 @@
+package indexer
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreRule is one pattern from a .gitignore file
+type ignoreRule struct {
+	base     string // Directory holding the .gitignore
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool // Pattern contains a slash, so it matches from base rather than at any depth
+}
+
+// ignoreRules applies .gitignore patterns in order; later rules override earlier ones
+type ignoreRules []ignoreRule
+
+// loadIgnoreFile returns the rules extended with those of dir/.gitignore, if it exists
+func (r ignoreRules) loadIgnoreFile(dir string) ignoreRules {
+	file, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return r
+	}
+	defer file.Close()
+
+	// Copy so sibling directories never share appended rules
+	r = append(ignoreRules{}, r...)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{base: dir}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		line = strings.TrimPrefix(line, `\`)
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		rule.pattern = line
+		r = append(r, rule)
+	}
+	return r
+}
+
+// ignored reports whether a path is excluded by the rules
+func (r ignoreRules) ignored(absPath string, isDir bool) bool {
+	ignored := false
+	for _, rule := range r {
+		if rule.matches(absPath, isDir) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+func (rule ignoreRule) matches(absPath string, isDir bool) bool {
+	if rule.dirOnly && !isDir {
+		return false
+	}
+
+	rel, err := filepath.Rel(rule.base, absPath)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+
+	if rule.anchored {
+		return matchSegments(strings.Split(rule.pattern, "/"), strings.Split(rel, "/"))
+	}
+	matched, _ := path.Match(rule.pattern, path.Base(rel))
+	return matched
+}
+
+// matchSegments matches path segments against pattern segments where ** spans any number of segments
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for skip := 0; skip <= len(segments); skip++ {
+				if matchSegments(pattern[1:], segments[skip:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], segments[0]); !matched {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
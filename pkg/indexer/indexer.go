@@ -0,0 +1,289 @@
+// Package indexer ingests files into the vector store, chunked by file type.
+// A manifest of content hashes makes re-runs incremental: only new and changed
+// files are embedded, and chunks of files that disappeared are deleted.
+package indexer
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/killallgit/ryan/pkg/logger"
+	"github.com/killallgit/ryan/pkg/retrieval"
+	"github.com/killallgit/ryan/pkg/vectorstore"
+)
+
+// Indexer keeps a vector store in sync with files on disk
+type Indexer struct {
+	store        vectorstore.VectorStore
+	manifest     *Manifest
+	manifestPath string
+	docConfig    retrieval.DocumentConfig
+	skip         map[string]bool
+
+	// Progress is called after each file is embedded
+	Progress func(done, total int, path string)
+}
+
+// Plan is what an index run would do for a set of paths. Paths are absolute.
+type Plan struct {
+	New       []string
+	Changed   []string
+	Unchanged []string
+	Removed   []string
+}
+
+// Pending returns the number of files an index run would embed or delete
+func (p *Plan) Pending() int {
+	return len(p.New) + len(p.Changed) + len(p.Removed)
+}
+
+// Stats summarizes an index run
+type Stats struct {
+	Added     int
+	Updated   int
+	Removed   int
+	Unchanged int
+	Chunks    int // Chunks embedded in this run
+}
+
+// New creates an indexer for store whose manifest lives at manifestPath. The
+// store may be nil when only planning. The manifest's directory is never indexed.
+func New(store vectorstore.VectorStore, manifestPath string, docConfig retrieval.DocumentConfig) (*Indexer, error) {
+	manifestPath, err := filepath.Abs(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := LoadManifest(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Indexer{
+		store:        store,
+		manifest:     manifest,
+		manifestPath: manifestPath,
+		docConfig:    docConfig,
+		skip:         map[string]bool{filepath.Dir(manifestPath): true},
+	}, nil
+}
+
+// Manifest returns the indexed state
+func (ix *Indexer) Manifest() *Manifest {
+	return ix.manifest
+}
+
+// Plan compares the files under paths with the manifest
+func (ix *Indexer) Plan(paths []string) (*Plan, error) {
+	plan := &Plan{}
+	seen := make(map[string]bool)
+	var roots []string
+
+	for _, root := range paths {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, abs)
+
+		err = walkFiles(abs, ix.skip, func(path string) error {
+			if seen[path] {
+				return nil
+			}
+			seen[path] = true
+
+			hash, err := hashFile(path)
+			if err != nil {
+				logger.Warn("Skipping %s: %v", path, err)
+				return nil
+			}
+			entry, ok := ix.manifest.Files[path]
+			switch {
+			case !ok:
+				plan.New = append(plan.New, path)
+			case entry.Hash != hash:
+				plan.Changed = append(plan.Changed, path)
+			default:
+				plan.Unchanged = append(plan.Unchanged, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk %s: %w", root, err)
+		}
+	}
+
+	// Indexed files under the walked paths that no longer exist or are now ignored
+	for path := range ix.manifest.Files {
+		if !seen[path] && underAny(path, roots) {
+			plan.Removed = append(plan.Removed, path)
+		}
+	}
+
+	sort.Strings(plan.New)
+	sort.Strings(plan.Changed)
+	sort.Strings(plan.Unchanged)
+	sort.Strings(plan.Removed)
+	return plan, nil
+}
+
+// Index embeds new and changed files under paths and deletes chunks of removed
+// ones. The manifest is saved even when the run stops early, so an interrupted
+// run resumes where it left off.
+func (ix *Indexer) Index(ctx context.Context, paths []string) (stats Stats, err error) {
+	if ix.store == nil {
+		return stats, fmt.Errorf("vector store not initialized")
+	}
+
+	plan, err := ix.Plan(paths)
+	if err != nil {
+		return stats, err
+	}
+	stats.Unchanged = len(plan.Unchanged)
+
+	defer func() {
+		// Stores with a keyword index write it together with the manifest so the two stay in step
+		if flusher, ok := ix.store.(interface{ Flush() error }); ok {
+			if flushErr := flusher.Flush(); flushErr != nil && err == nil {
+				err = flushErr
+			}
+		}
+		if saveErr := ix.manifest.Save(ix.manifestPath); saveErr != nil && err == nil {
+			err = saveErr
+		}
+	}()
+
+	for _, path := range plan.Removed {
+		if err := ix.store.DeleteDocuments(ctx, ix.manifest.Files[path].Chunks); err != nil {
+			return stats, fmt.Errorf("failed to delete chunks of %s: %w", path, err)
+		}
+		delete(ix.manifest.Files, path)
+		stats.Removed++
+	}
+
+	work := append(append([]string{}, plan.New...), plan.Changed...)
+	sort.Strings(work)
+
+	for i, path := range work {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+
+		_, existed := ix.manifest.Files[path]
+		chunks, err := ix.indexFile(ctx, path)
+		if err != nil {
+			return stats, err
+		}
+		stats.Chunks += chunks
+
+		if existed {
+			stats.Updated++
+		} else {
+			stats.Added++
+		}
+		if ix.Progress != nil {
+			ix.Progress(i+1, len(work), path)
+		}
+	}
+
+	return stats, nil
+}
+
+// indexFile replaces the chunks of one file and records it in the manifest
+func (ix *Indexer) indexFile(ctx context.Context, path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var docs []vectorstore.Document
+	if strings.TrimSpace(string(data)) != "" {
+		config := ix.docConfig
+		config.IDPrefix = pathID(path)
+		manager := retrieval.NewDocumentManager(config)
+		docs = manager.ChunkFile(path, string(data), map[string]interface{}{
+			"source": DisplayPath(path),
+		})
+	}
+
+	if entry, ok := ix.manifest.Files[path]; ok {
+		if err := ix.store.DeleteDocuments(ctx, entry.Chunks); err != nil {
+			return 0, fmt.Errorf("failed to delete old chunks of %s: %w", path, err)
+		}
+	}
+	if len(docs) > 0 {
+		if err := ix.store.AddDocuments(ctx, docs); err != nil {
+			return 0, fmt.Errorf("failed to embed %s: %w", path, err)
+		}
+	}
+
+	ids := make([]string, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.ID
+	}
+	ix.manifest.Files[path] = FileEntry{
+		Hash:      hashBytes(data),
+		Chunks:    ids,
+		IndexedAt: time.Now(),
+	}
+	return len(docs), nil
+}
+
+// Clear deletes every indexed document and the manifest
+func (ix *Indexer) Clear(ctx context.Context) error {
+	if ix.store == nil {
+		return fmt.Errorf("vector store not initialized")
+	}
+	if err := ix.store.Clear(ctx); err != nil {
+		return fmt.Errorf("failed to clear vector store: %w", err)
+	}
+
+	ix.manifest.Files = make(map[string]FileEntry)
+	if err := os.Remove(ix.manifestPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove manifest: %w", err)
+	}
+	return nil
+}
+
+// Replace swaps the manifest for one describing documents added to the store
+// by other means, such as a snapshot import, and saves it
+func (ix *Indexer) Replace(manifest *Manifest) error {
+	ix.manifest = manifest
+	return ix.manifest.Save(ix.manifestPath)
+}
+
+// DisplayPath returns path relative to the working directory when it is inside it
+func DisplayPath(path string) string {
+	return retrieval.SourcePath(path)
+}
+
+func hashFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return hashBytes(data), nil
+}
+
+func hashBytes(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+// pathID keeps chunk IDs unique when two files have the same content
+func pathID(path string) string {
+	return hashBytes([]byte(path))[:12]
+}
+
+func underAny(path string, roots []string) bool {
+	for _, root := range roots {
+		if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
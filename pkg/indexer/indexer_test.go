@@ -0,0 +1,191 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/killallgit/ryan/pkg/embeddings"
+	"github.com/killallgit/ryan/pkg/retrieval"
+	"github.com/killallgit/ryan/pkg/vectorstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func relPaths(t *testing.T, root string, paths []string) []string {
+	t.Helper()
+	rel := make([]string, len(paths))
+	for i, path := range paths {
+		r, err := filepath.Rel(root, path)
+		require.NoError(t, err)
+		rel[i] = filepath.ToSlash(r)
+	}
+	return rel
+}
+
+func TestWalkHonoursGitignore(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, ".git"), 0755))
+	writeFiles(t, root, map[string]string{
+		".gitignore":          "*.log\n/build/\nvendor\n!keep.log\ndocs/**/draft.md\n",
+		".git/config":         "[core]",
+		".ryan/settings.yaml": "secret: value",
+		"main.go":             "package main",
+		"debug.log":           "noise",
+		"keep.log":            "kept",
+		"build/out.txt":       "artifact",
+		"pkg/build/file.go":   "package build",
+		"pkg/vendor/lib.go":   "package lib",
+		"pkg/.gitignore":      "generated.go\n",
+		"pkg/generated.go":    "package pkg",
+		"pkg/real.go":         "package pkg",
+		"docs/a/b/draft.md":   "draft",
+		"docs/a/final.md":     "final",
+		"image.png":           "\x89PNG\x00\x00",
+		"empty.txt":           "",
+		"data/vectors/x.gob":  "skipped directory",
+		"other/.gitignore":    "real.go\n",
+		"other/unaffected.go": "package other",
+	})
+
+	var got []string
+	skip := map[string]bool{filepath.Join(root, "data", "vectors"): true}
+	require.NoError(t, walkFiles(root, skip, func(path string) error {
+		got = append(got, path)
+		return nil
+	}))
+
+	assert.ElementsMatch(t, []string{
+		".gitignore",
+		"main.go",
+		"keep.log",
+		"pkg/build/file.go",
+		"pkg/.gitignore",
+		"pkg/real.go",
+		"docs/a/final.md",
+		"other/.gitignore",
+		"other/unaffected.go",
+	}, relPaths(t, root, got))
+
+	// Walking a subdirectory still applies the repository's ignores
+	got = nil
+	require.NoError(t, walkFiles(filepath.Join(root, "pkg"), nil, func(path string) error {
+		got = append(got, path)
+		return nil
+	}))
+	assert.ElementsMatch(t, []string{"pkg/build/file.go", "pkg/.gitignore", "pkg/real.go"}, relPaths(t, root, got))
+}
+
+func TestIndexIsIncremental(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"a.md":       "# Alpha\nSessions are stored in SQLite.",
+		"b.go":       "package b\n\nfunc B() {}",
+		"same/c.txt": "identical content",
+		"same/d.txt": "identical content",
+	})
+
+	store, err := vectorstore.NewChromemStore(vectorstore.ChromemConfig{Embedder: embeddings.NewMockEmbedder(16)})
+	require.NoError(t, err)
+	ctx := context.Background()
+	manifestPath := ManifestPath(t.TempDir(), "default")
+
+	ix, err := New(store, manifestPath, retrieval.DocumentConfig{})
+	require.NoError(t, err)
+	var progress []int
+	ix.Progress = func(done, total int, path string) { progress = append(progress, done) }
+
+	stats, err := ix.Index(ctx, []string{root})
+	require.NoError(t, err)
+	assert.Equal(t, Stats{Added: 4, Chunks: 5}, stats, "b.go has a header and a function chunk")
+	assert.Equal(t, []int{1, 2, 3, 4}, progress)
+	count, _ := store.Count(ctx)
+	assert.Equal(t, 5, count, "identical files get distinct chunk IDs")
+
+	// A fresh indexer reads the saved manifest and finds nothing to do
+	ix, err = New(store, manifestPath, retrieval.DocumentConfig{})
+	require.NoError(t, err)
+	stats, err = ix.Index(ctx, []string{root})
+	require.NoError(t, err)
+	assert.Equal(t, Stats{Unchanged: 4}, stats)
+
+	// Change one file, remove another
+	writeFiles(t, root, map[string]string{"a.md": "# Alpha\nSessions moved to Postgres."})
+	require.NoError(t, os.Remove(filepath.Join(root, "b.go")))
+
+	plan, err := ix.Plan([]string{root})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.md"}, relPaths(t, root, plan.Changed))
+	assert.Equal(t, []string{"b.go"}, relPaths(t, root, plan.Removed))
+	assert.Equal(t, 2, plan.Pending())
+
+	// Indexing only a subdirectory never removes files outside it
+	stats, err = ix.Index(ctx, []string{filepath.Join(root, "same")})
+	require.NoError(t, err)
+	assert.Equal(t, Stats{Unchanged: 2}, stats)
+
+	stats, err = ix.Index(ctx, []string{root})
+	require.NoError(t, err)
+	assert.Equal(t, Stats{Updated: 1, Removed: 1, Unchanged: 2, Chunks: 1}, stats)
+	count, _ = store.Count(ctx)
+	assert.Equal(t, 3, count)
+
+	results, err := store.SimilaritySearch(ctx, "sessions", 3)
+	require.NoError(t, err)
+	var contents []string
+	for _, result := range results {
+		contents = append(contents, result.Document.Content)
+	}
+	assert.Contains(t, strings.Join(contents, "\n"), "Postgres")
+	assert.NotContains(t, strings.Join(contents, "\n"), "SQLite")
+
+	assert.Len(t, ix.Manifest().Files, 3)
+	assert.Equal(t, 3, ix.Manifest().Chunks())
+
+	require.NoError(t, ix.Clear(ctx))
+	count, _ = store.Count(ctx)
+	assert.Zero(t, count)
+	assert.NoFileExists(t, manifestPath)
+}
+
+func TestLoadManifestRejectsOtherVersions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "default.manifest.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"version": 99, "files": {}}`), 0644))
+
+	_, err := LoadManifest(path)
+	assert.ErrorContains(t, err, "run ryan index --clear")
+}
+
+func TestManifestPortableRoundTrip(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "repo")
+	outside := filepath.Join(t.TempDir(), "elsewhere.go")
+	manifest := NewManifest()
+	manifest.Files[filepath.Join(root, "pkg", "a.go")] = FileEntry{Hash: "a", Chunks: []string{"a#0"}}
+	manifest.Files[outside] = FileEntry{Hash: "b"}
+
+	portable := manifest.Portable(root)
+	assert.Contains(t, portable.Files, "pkg/a.go")
+	assert.Contains(t, portable.Files, outside, "paths outside the root stay absolute")
+
+	data, err := json.Marshal(portable)
+	require.NoError(t, err)
+	decoded, err := DecodeManifest(data)
+	require.NoError(t, err)
+
+	clone := filepath.Join(t.TempDir(), "clone")
+	resolved := decoded.Resolve(clone)
+	assert.Equal(t, FileEntry{Hash: "a", Chunks: []string{"a#0"}}, resolved.Files[filepath.Join(clone, "pkg", "a.go")])
+	assert.Contains(t, resolved.Files, outside)
+}
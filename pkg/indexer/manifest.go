@@ -0,0 +1,137 @@
+package indexer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// manifestVersion is bumped when the manifest format changes
+const manifestVersion = 1
+
+// Manifest records the content hash and chunk IDs of every indexed file, so
+// re-runs only embed what changed
+type Manifest struct {
+	Version int                  `json:"version"`
+	Files   map[string]FileEntry `json:"files"` // Keyed by absolute path
+}
+
+// FileEntry is the indexed state of one file
+type FileEntry struct {
+	Hash      string    `json:"hash"`
+	Chunks    []string  `json:"chunks"`
+	IndexedAt time.Time `json:"indexed_at"`
+}
+
+// ManifestPath returns where the manifest of a collection is kept, next to its vectors
+func ManifestPath(storeDir, collection string) string {
+	return filepath.Join(storeDir, collection+".manifest.json")
+}
+
+// NewManifest returns an empty manifest
+func NewManifest() *Manifest {
+	return &Manifest{Version: manifestVersion, Files: make(map[string]FileEntry)}
+}
+
+// LoadManifest reads a manifest, returning an empty one if the file does not exist
+func LoadManifest(path string) (*Manifest, error) {
+	manifest := NewManifest()
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	manifest, err = DecodeManifest(data)
+	if err != nil {
+		return nil, fmt.Errorf("manifest %s: %w; run ryan index --clear", path, err)
+	}
+	return manifest, nil
+}
+
+// DecodeManifest parses an encoded manifest, checking its version
+func DecodeManifest(data []byte) (*Manifest, error) {
+	manifest := &Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if manifest.Version != manifestVersion {
+		return nil, fmt.Errorf("manifest has version %d, expected %d", manifest.Version, manifestVersion)
+	}
+	if manifest.Files == nil {
+		manifest.Files = make(map[string]FileEntry)
+	}
+	return manifest, nil
+}
+
+// Save writes the manifest atomically
+func (m *Manifest) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create manifest directory: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// Chunks returns the total number of indexed chunks
+func (m *Manifest) Chunks() int {
+	total := 0
+	for _, entry := range m.Files {
+		total += len(entry.Chunks)
+	}
+	return total
+}
+
+// LastIndexed returns when a file was most recently indexed
+func (m *Manifest) LastIndexed() time.Time {
+	var last time.Time
+	for _, entry := range m.Files {
+		if entry.IndexedAt.After(last) {
+			last = entry.IndexedAt
+		}
+	}
+	return last
+}
+
+// Portable returns a copy of the manifest with paths under root made relative
+// and slash-separated, so it can be used in a checkout elsewhere. Paths
+// outside root are kept as they are.
+func (m *Manifest) Portable(root string) *Manifest {
+	portable := &Manifest{Version: m.Version, Files: make(map[string]FileEntry, len(m.Files))}
+	for path, entry := range m.Files {
+		if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = filepath.ToSlash(rel)
+		}
+		portable.Files[path] = entry
+	}
+	return portable
+}
+
+// Resolve returns a copy of a portable manifest with relative paths joined to root
+func (m *Manifest) Resolve(root string) *Manifest {
+	resolved := &Manifest{Version: m.Version, Files: make(map[string]FileEntry, len(m.Files))}
+	for path, entry := range m.Files {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(root, filepath.FromSlash(path))
+		}
+		resolved.Files[path] = entry
+	}
+	return resolved
+}
@@ -0,0 +1,108 @@
+package indexer
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// maxFileSize skips files too large to be useful as retrieval context
+const maxFileSize = 1024 * 1024
+
+// skippedDirs are never indexed: VCS data, and ryan's own state which may hold credentials
+var skippedDirs = map[string]bool{".git": true, ".ryan": true}
+
+// sniffSize is how much of a file is checked for NUL bytes to detect binaries
+const sniffSize = 8000
+
+// walkFiles calls fn with the absolute path of every indexable file under root.
+// It honours .gitignore files from the enclosing repository down, never enters
+// skippedDirs or any directory in skip, and leaves out large and binary files. A root
+// that is a file is passed to fn as is.
+func walkFiles(root string, skip map[string]bool, fn func(path string) error) error {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		if indexable(root, info) {
+			return fn(root)
+		}
+		return nil
+	}
+
+	rules := map[string]ignoreRules{filepath.Dir(root): ancestorRules(root)}
+
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		parent := rules[filepath.Dir(path)]
+
+		if entry.IsDir() {
+			if path != root && (skippedDirs[entry.Name()] || skip[path] || parent.ignored(path, true)) {
+				return filepath.SkipDir
+			}
+			rules[path] = parent.loadIgnoreFile(path)
+			return nil
+		}
+
+		if !entry.Type().IsRegular() || parent.ignored(path, false) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil || !indexable(path, info) {
+			return nil
+		}
+		return fn(path)
+	})
+}
+
+// ancestorRules loads .gitignore files between the repository top and dir's parent,
+// so indexing a subdirectory still honours the repository's ignores
+func ancestorRules(dir string) ignoreRules {
+	var ancestors []string
+	for current := filepath.Dir(dir); ; current = filepath.Dir(current) {
+		ancestors = append(ancestors, current)
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			break
+		}
+		if filepath.Dir(current) == current {
+			// Not inside a repository; only the walked tree's own ignores apply
+			return nil
+		}
+	}
+
+	var rules ignoreRules
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		rules = rules.loadIgnoreFile(ancestors[i])
+	}
+	return rules
+}
+
+// indexable reports whether a file is small enough and looks like text
+func indexable(path string, info fs.FileInfo) bool {
+	if info.Size() == 0 || info.Size() > maxFileSize {
+		return false
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	head := make([]byte, sniffSize)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return false
+	}
+	return !bytes.Contains(head[:n], []byte{0})
+}
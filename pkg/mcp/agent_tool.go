@@ -0,0 +1,58 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Executor runs a full agent turn
+type Executor interface {
+	Execute(ctx context.Context, prompt string) (string, error)
+}
+
+// AgentTool exposes a whole agent turn as the "ask_ryan" tool
+type AgentTool struct {
+	mu    sync.Mutex
+	agent Executor
+}
+
+// NewAgentTool creates the ask_ryan tool
+func NewAgentTool(agent Executor) *AgentTool {
+	return &AgentTool{agent: agent}
+}
+
+// Name returns the tool name
+func (t *AgentTool) Name() string {
+	return "ask_ryan"
+}
+
+// Description returns the tool description
+func (t *AgentTool) Description() string {
+	return "Ask Ryan, a coding agent with access to this project's files, git history and shell, to carry out a task or answer a question. " +
+		"Ryan keeps its conversation across calls."
+}
+
+// InputSchema returns the JSON schema of the tool arguments
+func (t *AgentTool) InputSchema() json.RawMessage {
+	return json.RawMessage(`{"type":"object","properties":{"prompt":{"type":"string","description":"The task or question for Ryan"}},"required":["prompt"]}`)
+}
+
+// Call runs one agent turn. Turns are serialized because they share the agent's memory.
+func (t *AgentTool) Call(ctx context.Context, input string) (string, error) {
+	var args struct {
+		Prompt string `json:"prompt"`
+	}
+	if err := json.Unmarshal([]byte(input), &args); err != nil {
+		return "", fmt.Errorf("invalid JSON input: %w", err)
+	}
+	if strings.TrimSpace(args.Prompt) == "" {
+		return "", fmt.Errorf("prompt cannot be empty")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.agent.Execute(ctx, args.Prompt)
+}
@@ -0,0 +1,159 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/killallgit/ryan/pkg/config"
+	"github.com/killallgit/ryan/pkg/logger"
+)
+
+// defaultTimeout bounds each request when the server config does not set one
+const defaultTimeout = 60 * time.Second
+
+// Client is a connection to a single MCP server
+type Client struct {
+	name      string
+	transport Transport
+	timeout   time.Duration
+	nextID    atomic.Int64
+
+	serverInfo Implementation
+}
+
+// NewClient creates a client using an established transport
+func NewClient(name string, transport Transport, timeout time.Duration) *Client {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &Client{
+		name:      name,
+		transport: transport,
+		timeout:   timeout,
+	}
+}
+
+// Connect opens the transport described by a server config and performs the initialize handshake
+func Connect(ctx context.Context, name string, server config.MCPServer) (*Client, error) {
+	var transport Transport
+	switch {
+	case server.Command != "":
+		stdio, err := NewStdioTransport(name, server.Command, server.Args, server.Env)
+		if err != nil {
+			return nil, err
+		}
+		transport = stdio
+	case server.URL != "":
+		transport = NewHTTPTransport(name, server.URL, server.Headers)
+	default:
+		return nil, fmt.Errorf("MCP server %s needs a command or a url", name)
+	}
+
+	client := NewClient(name, transport, time.Duration(server.Timeout)*time.Second)
+	if err := client.Initialize(ctx); err != nil {
+		transport.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// Name returns the configured server name
+func (c *Client) Name() string {
+	return c.name
+}
+
+// ServerInfo returns the name and version reported by the server
+func (c *Client) ServerInfo() Implementation {
+	return c.serverInfo
+}
+
+// Initialize negotiates the protocol version and announces the client
+func (c *Client) Initialize(ctx context.Context) error {
+	params := InitializeParams{
+		ProtocolVersion: ProtocolVersion,
+		Capabilities:    map[string]interface{}{},
+		ClientInfo:      ryanInfo,
+	}
+
+	var result InitializeResult
+	if err := c.call(ctx, "initialize", params, &result); err != nil {
+		return fmt.Errorf("failed to initialize MCP server %s: %w", c.name, err)
+	}
+	c.serverInfo = result.ServerInfo
+	logger.Info("Connected to MCP server %s (%s %s, protocol %s)",
+		c.name, result.ServerInfo.Name, result.ServerInfo.Version, result.ProtocolVersion)
+
+	notification := &Request{JSONRPC: jsonRPCVersion, Method: "notifications/initialized"}
+	if err := c.transport.Notify(ctx, notification); err != nil {
+		return fmt.Errorf("failed to initialize MCP server %s: %w", c.name, err)
+	}
+	return nil
+}
+
+// ListTools returns every tool offered by the server, following pagination
+func (c *Client) ListTools(ctx context.Context) ([]ToolDefinition, error) {
+	var all []ToolDefinition
+	cursor := ""
+
+	for {
+		params := map[string]interface{}{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+
+		var page ListToolsResult
+		if err := c.call(ctx, "tools/list", params, &page); err != nil {
+			return nil, fmt.Errorf("failed to list tools from MCP server %s: %w", c.name, err)
+		}
+		all = append(all, page.Tools...)
+
+		if page.NextCursor == "" || page.NextCursor == cursor {
+			return all, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// CallTool invokes a tool with JSON object arguments
+func (c *Client) CallTool(ctx context.Context, name string, arguments json.RawMessage) (*CallToolResult, error) {
+	var result CallToolResult
+	params := CallToolParams{Name: name, Arguments: arguments}
+	if err := c.call(ctx, "tools/call", params, &result); err != nil {
+		return nil, fmt.Errorf("MCP tool %s/%s failed: %w", c.name, name, err)
+	}
+	return &result, nil
+}
+
+// Close shuts down the connection
+func (c *Client) Close() error {
+	return c.transport.Close()
+}
+
+func (c *Client) call(ctx context.Context, method string, params, result interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	id := c.nextID.Add(1)
+	resp, err := c.transport.Call(ctx, &Request{
+		JSONRPC: jsonRPCVersion,
+		ID:      &id,
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("invalid %s result: %w", method, err)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/killallgit/ryan/pkg/logger"
+)
+
+const sessionHeader = "Mcp-Session-Id"
+
+// HTTPTransport talks to a server over the streamable HTTP transport. Each
+// message is POSTed; responses arrive as JSON or as a server-sent event stream.
+type HTTPTransport struct {
+	name    string
+	url     string
+	headers map[string]string
+	client  *http.Client
+
+	mu        sync.Mutex
+	sessionID string
+}
+
+// NewHTTPTransport creates a transport for the server at url
+func NewHTTPTransport(name, url string, headers map[string]string) *HTTPTransport {
+	return &HTTPTransport{
+		name:    name,
+		url:     url,
+		headers: headers,
+		client:  &http.Client{},
+	}
+}
+
+// Call posts a request and waits for its response
+func (t *HTTPTransport) Call(ctx context.Context, req *Request) (*Response, error) {
+	if req.ID == nil {
+		return nil, fmt.Errorf("request %s has no id", req.Method)
+	}
+
+	resp, err := t.post(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if sessionID := resp.Header.Get(sessionHeader); sessionID != "" {
+		t.mu.Lock()
+		t.sessionID = sessionID
+		t.mu.Unlock()
+	}
+
+	id := fmt.Sprintf("%d", *req.ID)
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+
+	switch mediaType {
+	case "application/json":
+		var msg message
+		if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+			return nil, fmt.Errorf("invalid response from MCP server %s: %w", t.name, err)
+		}
+		if string(msg.ID) != id {
+			return nil, fmt.Errorf("MCP server %s answered id %s, expected %s", t.name, string(msg.ID), id)
+		}
+		return msg.response(), nil
+
+	case "text/event-stream":
+		return t.readEventStream(resp.Body, id)
+
+	default:
+		return nil, fmt.Errorf("MCP server %s returned unexpected content type %q", t.name, mediaType)
+	}
+}
+
+// Notify posts a notification; the server acknowledges it without a body
+func (t *HTTPTransport) Notify(ctx context.Context, req *Request) error {
+	resp, err := t.post(ctx, req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return nil
+}
+
+// Close ends the server session
+func (t *HTTPTransport) Close() error {
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	if sessionID == "" {
+		return nil
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, t.url, nil)
+	if err != nil {
+		return err
+	}
+	t.setHeaders(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		logger.Debug("Failed to end MCP session with %s: %v", t.name, err)
+		return nil
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (t *HTTPTransport) post(ctx context.Context, v interface{}) (*http.Response, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	t.setHeaders(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach MCP server %s: %w", t.name, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("MCP server %s returned %s: %s", t.name, resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	return resp, nil
+}
+
+func (t *HTTPTransport) setHeaders(req *http.Request) {
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sessionID != "" {
+		req.Header.Set(sessionHeader, t.sessionID)
+	}
+}
+
+// readEventStream reads server-sent events until the response with the given id arrives
+func (t *HTTPTransport) readEventStream(body io.Reader, id string) (*Response, error) {
+	reader := bufio.NewReader(body)
+	var data strings.Builder
+
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+
+		if strings.HasPrefix(line, "data:") {
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+
+		// A blank line (or the end of the stream) dispatches the event
+		if (line == "" || err != nil) && data.Len() > 0 {
+			var msg message
+			if jsonErr := json.Unmarshal([]byte(data.String()), &msg); jsonErr != nil {
+				logger.Debug("MCP server %s sent invalid event: %s", t.name, data.String())
+			} else if msg.isResponse() && string(msg.ID) == id {
+				return msg.response(), nil
+			} else {
+				logger.Debug("MCP server %s event ignored: %s", t.name, msg.Method)
+			}
+			data.Reset()
+		}
+
+		if err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("MCP server %s closed the stream without a response", t.name)
+			}
+			return nil, fmt.Errorf("failed to read from MCP server %s: %w", t.name, err)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Command fakeserver is a minimal MCP server used by the mcp package tests.
+// It speaks newline-delimited JSON-RPC on stdin/stdout and offers two tools,
+// "echo" and "fail", split across two tools/list pages.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   interface{}     `json:"error,omitempty"`
+}
+
+var tools = []map[string]interface{}{
+	{
+		"name":        "echo",
+		"description": "Echo a message back.",
+		"inputSchema": map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"message": map[string]string{"type": "string"}},
+			"required":   []string{"message"},
+		},
+	},
+	{
+		"name":        "fail",
+		"description": "Always fails.",
+		"inputSchema": map[string]interface{}{"type": "object"},
+	},
+}
+
+func main() {
+	fmt.Fprintln(os.Stderr, "fake MCP server started")
+
+	encoder := json.NewEncoder(os.Stdout)
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+
+	for scanner.Scan() {
+		var msg message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid message: %v\n", err)
+			continue
+		}
+		if len(msg.ID) == 0 || msg.Method == "" {
+			// Notifications and responses to our own requests need no answer
+			continue
+		}
+
+		reply := message{JSONRPC: "2.0", ID: msg.ID}
+		switch msg.Method {
+		case "initialize":
+			// Exercise server-to-client requests before answering
+			encoder.Encode(message{JSONRPC: "2.0", ID: json.RawMessage(`"server-ping"`), Method: "ping"})
+			reply.Result = map[string]interface{}{
+				"protocolVersion": "2025-03-26",
+				"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+				"serverInfo":      map[string]string{"name": "fakeserver", "version": "1.0.0"},
+			}
+		case "tools/list":
+			var params struct {
+				Cursor string `json:"cursor"`
+			}
+			json.Unmarshal(msg.Params, &params)
+			if params.Cursor == "" {
+				reply.Result = map[string]interface{}{"tools": tools[:1], "nextCursor": "page-2"}
+			} else {
+				reply.Result = map[string]interface{}{"tools": tools[1:]}
+			}
+		case "tools/call":
+			reply.Result = callTool(msg.Params)
+		default:
+			reply.Error = map[string]interface{}{"code": -32601, "message": "method not found"}
+		}
+		encoder.Encode(reply)
+	}
+}
+
+func callTool(raw json.RawMessage) map[string]interface{} {
+	var params struct {
+		Name      string `json:"name"`
+		Arguments struct {
+			Message string `json:"message"`
+		} `json:"arguments"`
+	}
+	json.Unmarshal(raw, &params)
+
+	switch params.Name {
+	case "echo":
+		return textResult("echo: "+params.Arguments.Message, false)
+	case "fail":
+		return textResult("something went wrong", true)
+	default:
+		return textResult("unknown tool "+params.Name, true)
+	}
+}
+
+func textResult(text string, isError bool) map[string]interface{} {
+	return map[string]interface{}{
+		"content": []map[string]string{{"type": "text", "text": text}},
+		"isError": isError,
+	}
+}
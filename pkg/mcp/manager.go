@@ -0,0 +1,108 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/killallgit/ryan/pkg/config"
+	"github.com/killallgit/ryan/pkg/logger"
+	"github.com/killallgit/ryan/pkg/tools/registry"
+	"github.com/tmc/langchaingo/tools"
+)
+
+// Manager connects to the configured MCP servers and registers their tools
+type Manager struct {
+	mu       sync.Mutex
+	registry registry.Registry
+	clients  []*Client
+	tools    []string
+}
+
+// NewManager creates a manager that registers tools into reg
+func NewManager(reg registry.Registry) *Manager {
+	return &Manager{registry: reg}
+}
+
+// Start connects to every server and registers its tools. A server that fails
+// to start is skipped; its error is included in the returned error.
+func (m *Manager) Start(ctx context.Context, servers map[string]config.MCPServer) error {
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		if err := m.startServer(ctx, name, servers[name]); err != nil {
+			logger.Warn("MCP server %s unavailable: %v", name, err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *Manager) startServer(ctx context.Context, name string, server config.MCPServer) error {
+	client, err := Connect(ctx, name, server)
+	if err != nil {
+		return err
+	}
+
+	definitions, err := client.ListTools(ctx)
+	if err != nil {
+		client.Close()
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clients = append(m.clients, client)
+
+	registered := 0
+	for _, definition := range definitions {
+		definition := definition
+		toolName := ToolName(name, definition.Name)
+		err := m.registry.Register(toolName, func(skipPermissions bool) tools.Tool {
+			return NewToolWithBypass(client, definition, skipPermissions)
+		})
+		if err != nil {
+			logger.Warn("Skipping MCP tool %s: %v", toolName, err)
+			continue
+		}
+		m.tools = append(m.tools, toolName)
+		registered++
+	}
+
+	logger.Info("Registered %d of %d tool(s) from MCP server %s", registered, len(definitions), name)
+	return nil
+}
+
+// Tools returns the registered tool names
+func (m *Manager) Tools() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string{}, m.tools...)
+}
+
+// Close unregisters the tools and disconnects from every server
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, name := range m.tools {
+		m.registry.Unregister(name)
+	}
+	m.tools = nil
+
+	var errs []error
+	for _, client := range m.clients {
+		if err := client.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close MCP server %s: %w", client.Name(), err))
+		}
+	}
+	m.clients = nil
+	return errors.Join(errs...)
+}
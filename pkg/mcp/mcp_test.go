@@ -0,0 +1,193 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/killallgit/ryan/pkg/config"
+	"github.com/killallgit/ryan/pkg/tools/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var fakeServerPath string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "ryan-mcp-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fakeServerPath = filepath.Join(dir, "fakeserver")
+	build := exec.Command("go", "build", "-o", fakeServerPath, "./internal/fakeserver")
+	build.Stderr = os.Stderr
+	if err := build.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to build fake MCP server: %v\n", err)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestStdioClient(t *testing.T) {
+	ctx := context.Background()
+	client, err := Connect(ctx, "fake", config.MCPServer{Command: fakeServerPath})
+	require.NoError(t, err)
+	defer client.Close()
+
+	assert.Equal(t, "fakeserver", client.ServerInfo().Name)
+
+	definitions, err := client.ListTools(ctx)
+	require.NoError(t, err)
+	require.Len(t, definitions, 2, "both pages should be listed")
+	assert.Equal(t, "echo", definitions[0].Name)
+	assert.Equal(t, "fail", definitions[1].Name)
+
+	result, err := client.CallTool(ctx, "echo", json.RawMessage(`{"message":"hi"}`))
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+	assert.Equal(t, "echo: hi", formatContent(result.Content))
+
+	result, err = client.CallTool(ctx, "fail", nil)
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+}
+
+func TestConnectErrors(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := Connect(ctx, "empty", config.MCPServer{})
+	assert.ErrorContains(t, err, "needs a command or a url")
+
+	_, err = Connect(ctx, "missing", config.MCPServer{Command: filepath.Join(t.TempDir(), "missing")})
+	assert.Error(t, err)
+
+	// A process that exits immediately fails the handshake instead of hanging
+	_, err = Connect(ctx, "exits", config.MCPServer{Command: "true"})
+	assert.ErrorContains(t, err, "exited")
+}
+
+func TestManager(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".ryan"), 0755))
+	settings := `{"permissions": {"allow": ["Mcp(fake:echo)"]}}`
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".ryan", "settings.json"), []byte(settings), 0644))
+
+	reg := registry.New()
+	manager := NewManager(reg)
+	ctx := context.Background()
+
+	err := manager.Start(ctx, map[string]config.MCPServer{
+		"fake":   {Command: fakeServerPath},
+		"broken": {Command: filepath.Join(t.TempDir(), "missing")},
+	})
+	assert.ErrorContains(t, err, "missing", "broken servers are reported")
+	assert.ElementsMatch(t, []string{"mcp__fake__echo", "mcp__fake__fail"}, manager.Tools())
+
+	echo, err := reg.Get("mcp__fake__echo", false)
+	require.NoError(t, err)
+	assert.Contains(t, echo.Description(), `"required":["message"]`)
+
+	provider, ok := echo.(registry.SchemaProvider)
+	require.True(t, ok)
+	assert.JSONEq(t, `{"type":"object","properties":{"message":{"type":"string"}},"required":["message"]}`,
+		string(provider.InputSchema()))
+
+	t.Run("allowed call", func(t *testing.T) {
+		output, err := echo.Call(ctx, `{"message": "hello"}`)
+		require.NoError(t, err)
+		assert.Equal(t, "echo: hello", output)
+	})
+
+	t.Run("input must be an object", func(t *testing.T) {
+		_, err := echo.Call(ctx, `hello`)
+		assert.ErrorContains(t, err, "JSON object")
+	})
+
+	t.Run("denied by ACL", func(t *testing.T) {
+		fail, err := reg.Get("mcp__fake__fail", false)
+		require.NoError(t, err)
+		_, err = fail.Call(ctx, `{}`)
+		assert.ErrorContains(t, err, "Mcp(fake:fail)")
+	})
+
+	t.Run("tool errors are returned", func(t *testing.T) {
+		fail, err := reg.Get("mcp__fake__fail", true)
+		require.NoError(t, err)
+		_, err = fail.Call(ctx, `{}`)
+		assert.ErrorContains(t, err, "something went wrong")
+	})
+
+	require.NoError(t, manager.Close())
+	assert.False(t, reg.IsRegistered("mcp__fake__echo"))
+}
+
+func TestHTTPTransport(t *testing.T) {
+	var sessionHeaders []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		sessionHeaders = append(sessionHeaders, r.Header.Get(sessionHeader))
+
+		var msg message
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&msg))
+
+		switch msg.Method {
+		case "initialize":
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set(sessionHeader, "session-1")
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":{"protocolVersion":"2025-03-26","serverInfo":{"name":"httpserver","version":"2"}}}`, msg.ID)
+		case "notifications/initialized":
+			w.WriteHeader(http.StatusAccepted)
+		case "tools/list":
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n\n")
+			fmt.Fprintf(w, "data: {\"jsonrpc\":\"2.0\",\"id\":%s,\n", msg.ID)
+			fmt.Fprint(w, "data: \"result\":{\"tools\":[{\"name\":\"search\",\"inputSchema\":{\"type\":\"object\"}}]}}\n\n")
+		case "tools/call":
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, "data: {\"jsonrpc\":\"2.0\",\"id\":%s,\"result\":{\"content\":[{\"type\":\"text\",\"text\":\"found\"}]}}\n\n", msg.ID)
+		default:
+			http.Error(w, "unexpected method", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := Connect(ctx, "web", config.MCPServer{URL: server.URL, Headers: map[string]string{"Authorization": "Bearer x"}})
+	require.NoError(t, err)
+	assert.Equal(t, "httpserver", client.ServerInfo().Name)
+
+	definitions, err := client.ListTools(ctx)
+	require.NoError(t, err)
+	require.Len(t, definitions, 1)
+	assert.Equal(t, "search", definitions[0].Name)
+
+	result, err := client.CallTool(ctx, "search", json.RawMessage(`{}`))
+	require.NoError(t, err)
+	assert.Equal(t, "found", formatContent(result.Content))
+
+	require.NoError(t, client.Close())
+
+	// The session ID from initialize is sent on every later request
+	assert.Equal(t, []string{"", "session-1", "session-1", "session-1"}, sessionHeaders)
+}
+
+func TestToolName(t *testing.T) {
+	assert.Equal(t, "mcp__github__create_issue", ToolName("github", "create_issue"))
+	assert.Equal(t, "mcp__my_server__tool_v2", ToolName("my server", "tool.v2"))
+}
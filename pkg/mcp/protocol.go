@@ -0,0 +1,122 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ProtocolVersion is the MCP revision requested during initialization
+const ProtocolVersion = "2025-03-26"
+
+const jsonRPCVersion = "2.0"
+
+// ryanInfo identifies Ryan as an MCP client or server
+var ryanInfo = Implementation{Name: "ryan", Version: "0.1.0"}
+
+// Request is a JSON-RPC request or, without an ID, a notification
+type Request struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      *int64      `json:"id,omitempty"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// Response is a JSON-RPC response
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// RPCError is a JSON-RPC error object
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("MCP error %d: %s", e.Code, e.Message)
+}
+
+// message is any incoming JSON-RPC message: a response, a request or a notification
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// isResponse reports whether the message answers a request
+func (m *message) isResponse() bool {
+	return m.Method == "" && len(m.ID) > 0
+}
+
+func (m *message) response() *Response {
+	return &Response{JSONRPC: m.JSONRPC, ID: m.ID, Result: m.Result, Error: m.Error}
+}
+
+// Implementation identifies an MCP client or server
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// InitializeParams are sent by the client to start a session
+type InitializeParams struct {
+	ProtocolVersion string                 `json:"protocolVersion"`
+	Capabilities    map[string]interface{} `json:"capabilities"`
+	ClientInfo      Implementation         `json:"clientInfo"`
+}
+
+// InitializeResult is the server's answer to initialize
+type InitializeResult struct {
+	ProtocolVersion string                     `json:"protocolVersion"`
+	Capabilities    map[string]json.RawMessage `json:"capabilities"`
+	ServerInfo      Implementation             `json:"serverInfo"`
+	Instructions    string                     `json:"instructions,omitempty"`
+}
+
+// ToolDefinition describes a tool offered by a server
+type ToolDefinition struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"inputSchema"`
+}
+
+// ListToolsResult is one page of tools/list
+type ListToolsResult struct {
+	Tools      []ToolDefinition `json:"tools"`
+	NextCursor string           `json:"nextCursor,omitempty"`
+}
+
+// CallToolParams are sent with tools/call
+type CallToolParams struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+// Content is a single item of a tool result
+type Content struct {
+	Type     string           `json:"type"`
+	Text     string           `json:"text,omitempty"`
+	MimeType string           `json:"mimeType,omitempty"`
+	Data     string           `json:"data,omitempty"`
+	Resource *ResourceContent `json:"resource,omitempty"`
+}
+
+// ResourceContent is an embedded resource in a tool result
+type ResourceContent struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+}
+
+// CallToolResult is the result of tools/call
+type CallToolResult struct {
+	Content []Content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
@@ -0,0 +1,244 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/killallgit/ryan/pkg/logger"
+	"github.com/killallgit/ryan/pkg/tools/registry"
+	"github.com/tmc/langchaingo/tools"
+)
+
+// stringInputSchema describes tools that take a single free-form string
+var stringInputSchema = json.RawMessage(`{"type":"object","properties":{"input":{"type":"string","description":"Tool input, as described in the tool description"}},"required":["input"]}`)
+
+// Server exposes langchaingo tools to MCP clients over stdio
+type Server struct {
+	tools map[string]tools.Tool
+	order []string
+
+	writeMu sync.Mutex
+	out     io.Writer
+
+	mu       sync.Mutex
+	inflight map[string]context.CancelFunc
+}
+
+// NewServer creates a server offering the given tools
+func NewServer(serverTools []tools.Tool) *Server {
+	s := &Server{
+		tools:    make(map[string]tools.Tool),
+		inflight: make(map[string]context.CancelFunc),
+	}
+	for _, tool := range serverTools {
+		if _, exists := s.tools[tool.Name()]; exists {
+			logger.Warn("Duplicate tool %s not exposed over MCP", tool.Name())
+			continue
+		}
+		s.tools[tool.Name()] = tool
+		s.order = append(s.order, tool.Name())
+	}
+	return s
+}
+
+// Serve reads newline-delimited JSON-RPC requests from in and writes responses
+// to out until in is closed or ctx is cancelled. Tool calls run concurrently.
+func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	s.out = out
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	reader := bufio.NewReader(in)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			s.handleLine(ctx, line, &wg)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read request: %w", err)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *Server) handleLine(ctx context.Context, line []byte, wg *sync.WaitGroup) {
+	var msg message
+	if err := json.Unmarshal(line, &msg); err != nil {
+		s.reply(Response{JSONRPC: jsonRPCVersion, ID: json.RawMessage("null"),
+			Error: &RPCError{Code: -32700, Message: "parse error"}})
+		return
+	}
+
+	// Notifications and responses have nothing to answer
+	if len(msg.ID) == 0 || msg.Method == "" {
+		if msg.Method == "notifications/cancelled" {
+			s.cancelRequest(msg.Params)
+		}
+		return
+	}
+
+	if msg.Method != "tools/call" {
+		s.reply(s.handle(&msg))
+		return
+	}
+
+	callCtx, cancel := context.WithCancel(ctx)
+	s.mu.Lock()
+	s.inflight[string(msg.ID)] = cancel
+	s.mu.Unlock()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.inflight, string(msg.ID))
+			s.mu.Unlock()
+			cancel()
+		}()
+		s.reply(s.callTool(callCtx, &msg))
+	}()
+}
+
+func (s *Server) handle(msg *message) Response {
+	resp := Response{JSONRPC: jsonRPCVersion, ID: msg.ID}
+
+	switch msg.Method {
+	case "initialize":
+		resp.Result = mustMarshal(map[string]interface{}{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]interface{}{"tools": map[string]bool{"listChanged": false}},
+			"serverInfo":      ryanInfo,
+		})
+	case "ping":
+		resp.Result = json.RawMessage(`{}`)
+	case "tools/list":
+		definitions := make([]ToolDefinition, 0, len(s.order))
+		for _, name := range s.order {
+			definitions = append(definitions, describeTool(s.tools[name]))
+		}
+		resp.Result = mustMarshal(ListToolsResult{Tools: definitions})
+	default:
+		resp.Error = &RPCError{Code: -32601, Message: "method not found: " + msg.Method}
+	}
+	return resp
+}
+
+func (s *Server) callTool(ctx context.Context, msg *message) Response {
+	resp := Response{JSONRPC: jsonRPCVersion, ID: msg.ID}
+
+	var params CallToolParams
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		resp.Error = &RPCError{Code: -32602, Message: "invalid params: " + err.Error()}
+		return resp
+	}
+
+	tool, ok := s.tools[params.Name]
+	if !ok {
+		resp.Error = &RPCError{Code: -32602, Message: "unknown tool: " + params.Name}
+		return resp
+	}
+
+	input, err := toolInput(tool, params.Arguments)
+	if err != nil {
+		resp.Result = mustMarshal(errorResult(err))
+		return resp
+	}
+
+	logger.Debug("MCP client called %s", params.Name)
+	output, err := tool.Call(ctx, input)
+	if err != nil {
+		resp.Result = mustMarshal(errorResult(err))
+		return resp
+	}
+
+	resp.Result = mustMarshal(CallToolResult{Content: []Content{{Type: "text", Text: output}}})
+	return resp
+}
+
+func (s *Server) cancelRequest(params json.RawMessage) {
+	var cancelled struct {
+		RequestID json.RawMessage `json:"requestId"`
+	}
+	if err := json.Unmarshal(params, &cancelled); err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	cancel, ok := s.inflight[string(cancelled.RequestID)]
+	s.mu.Unlock()
+	if ok {
+		cancel()
+	}
+}
+
+func (s *Server) reply(resp Response) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		logger.Warn("Failed to encode MCP response: %v", err)
+		return
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if _, err := s.out.Write(append(data, '\n')); err != nil {
+		logger.Warn("Failed to write MCP response: %v", err)
+	}
+}
+
+// describeTool builds the MCP definition of a tool. Tools without a JSON schema
+// take their usual string input as an "input" argument.
+func describeTool(tool tools.Tool) ToolDefinition {
+	schema := stringInputSchema
+	if provider, ok := tool.(registry.SchemaProvider); ok {
+		schema = provider.InputSchema()
+	}
+	return ToolDefinition{Name: tool.Name(), Description: tool.Description(), InputSchema: schema}
+}
+
+// toolInput converts MCP arguments into the string a tool's Call expects
+func toolInput(tool tools.Tool, arguments json.RawMessage) (string, error) {
+	if _, ok := tool.(registry.SchemaProvider); ok {
+		if len(arguments) == 0 || string(arguments) == "null" {
+			return "{}", nil
+		}
+		return string(arguments), nil
+	}
+
+	var args struct {
+		Input *string `json:"input"`
+	}
+	if len(arguments) > 0 {
+		if err := json.Unmarshal(arguments, &args); err != nil {
+			return "", fmt.Errorf("arguments must be a JSON object: %w", err)
+		}
+	}
+	if args.Input == nil {
+		return "", fmt.Errorf(`missing required argument "input"`)
+	}
+	return *args.Input, nil
+}
+
+func errorResult(err error) CallToolResult {
+	return CallToolResult{Content: []Content{{Type: "text", Text: err.Error()}}, IsError: true}
+}
+
+func mustMarshal(v interface{}) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("failed to encode MCP result: %v", err))
+	}
+	return data
+}
@@ -0,0 +1,126 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	ryantools "github.com/killallgit/ryan/pkg/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/tools"
+)
+
+type upperTool struct{}
+
+func (upperTool) Name() string        { return "upper" }
+func (upperTool) Description() string { return "Uppercase the input" }
+func (upperTool) Call(ctx context.Context, input string) (string, error) {
+	if input == "" {
+		return "", fmt.Errorf("nothing to uppercase")
+	}
+	return strings.ToUpper(input), nil
+}
+
+type fakeExecutor struct{ prompts []string }
+
+func (f *fakeExecutor) Execute(ctx context.Context, prompt string) (string, error) {
+	f.prompts = append(f.prompts, prompt)
+	return "done: " + prompt, nil
+}
+
+// serve runs the server over the given request lines and returns responses keyed by id
+func serve(t *testing.T, serverTools []tools.Tool, requests ...string) map[string]message {
+	t.Helper()
+	var out bytes.Buffer
+	err := NewServer(serverTools).Serve(context.Background(), strings.NewReader(strings.Join(requests, "\n")+"\n"), &out)
+	require.NoError(t, err)
+
+	responses := make(map[string]message)
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var msg message
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &msg))
+		responses[string(msg.ID)] = msg
+	}
+	return responses
+}
+
+func callResult(t *testing.T, msg message) CallToolResult {
+	t.Helper()
+	require.Nil(t, msg.Error)
+	var result CallToolResult
+	require.NoError(t, json.Unmarshal(msg.Result, &result))
+	return result
+}
+
+func TestServerHandshakeAndList(t *testing.T) {
+	executor := &fakeExecutor{}
+	responses := serve(t, []tools.Tool{upperTool{}, NewAgentTool(executor)},
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"resources/list"}`,
+		`not json`,
+	)
+	require.Len(t, responses, 4, "notifications are not answered")
+
+	var initResult InitializeResult
+	require.NoError(t, json.Unmarshal(responses["1"].Result, &initResult))
+	assert.Equal(t, ProtocolVersion, initResult.ProtocolVersion)
+	assert.Equal(t, "ryan", initResult.ServerInfo.Name)
+
+	var list ListToolsResult
+	require.NoError(t, json.Unmarshal(responses["2"].Result, &list))
+	require.Len(t, list.Tools, 2)
+	assert.Equal(t, "upper", list.Tools[0].Name)
+	assert.JSONEq(t, string(stringInputSchema), string(list.Tools[0].InputSchema))
+	assert.Equal(t, "ask_ryan", list.Tools[1].Name)
+	assert.Contains(t, string(list.Tools[1].InputSchema), `"prompt"`)
+
+	assert.Equal(t, -32601, responses["3"].Error.Code)
+	assert.Equal(t, -32700, responses["null"].Error.Code)
+}
+
+func TestServerToolCalls(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	executor := &fakeExecutor{}
+
+	// Default permissions do not allow Bash, so the ACL rejects the call
+	serverTools := []tools.Tool{upperTool{}, ryantools.NewBashTool(), NewAgentTool(executor)}
+	responses := serve(t, serverTools,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"upper","arguments":{"input":"hello"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"upper","arguments":{"input":""}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"upper","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"bash","arguments":{"input":"echo hi"}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"ask_ryan","arguments":{"prompt":"fix the build"}}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"tools/call","params":{"name":"missing"}}`,
+	)
+
+	result := callResult(t, responses["1"])
+	assert.False(t, result.IsError)
+	assert.Equal(t, "HELLO", result.Content[0].Text)
+
+	result = callResult(t, responses["2"])
+	assert.True(t, result.IsError)
+	assert.Equal(t, "nothing to uppercase", result.Content[0].Text)
+
+	result = callResult(t, responses["3"])
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.Content[0].Text, `"input"`)
+
+	result = callResult(t, responses["4"])
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.Content[0].Text, "permission denied")
+
+	result = callResult(t, responses["5"])
+	assert.Equal(t, "done: fix the build", result.Content[0].Text)
+	assert.Equal(t, []string{"fix the build"}, executor.prompts)
+
+	assert.Equal(t, -32602, responses["6"].Error.Code)
+}
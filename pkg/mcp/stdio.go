@@ -0,0 +1,255 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/killallgit/ryan/pkg/logger"
+)
+
+// Transport sends JSON-RPC messages to an MCP server
+type Transport interface {
+	// Call sends a request and waits for its response
+	Call(ctx context.Context, req *Request) (*Response, error)
+
+	// Notify sends a notification, which has no response
+	Notify(ctx context.Context, req *Request) error
+
+	// Close shuts the connection down
+	Close() error
+}
+
+// closeTimeout is how long a server process gets to exit after stdin is closed
+const closeTimeout = 5 * time.Second
+
+// exitWait is how long a failed write waits to see whether the server exited
+const exitWait = time.Second
+
+// StdioTransport talks to a server process over newline-delimited JSON on stdin/stdout
+type StdioTransport struct {
+	name  string
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	pending map[int64]chan *Response
+	done    chan struct{}
+	err     error
+}
+
+// NewStdioTransport starts the server process
+func NewStdioTransport(name, command string, args, env []string) (*StdioTransport, error) {
+	cmd := exec.Command(command, args...)
+	cmd.Env = append(os.Environ(), env...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stdout: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stderr: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", command, err)
+	}
+
+	t := &StdioTransport{
+		name:    name,
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[int64]chan *Response),
+		done:    make(chan struct{}),
+	}
+
+	stderrDone := make(chan struct{})
+	go func() {
+		t.logStderr(stderr)
+		close(stderrDone)
+	}()
+	go t.readLoop(stdout, stderrDone)
+
+	return t, nil
+}
+
+// Call sends a request and waits for its response
+func (t *StdioTransport) Call(ctx context.Context, req *Request) (*Response, error) {
+	if req.ID == nil {
+		return nil, fmt.Errorf("request %s has no id", req.Method)
+	}
+
+	ch := make(chan *Response, 1)
+	t.mu.Lock()
+	if t.err != nil {
+		t.mu.Unlock()
+		return nil, t.err
+	}
+	t.pending[*req.ID] = ch
+	t.mu.Unlock()
+
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, *req.ID)
+		t.mu.Unlock()
+	}()
+
+	if err := t.write(req); err != nil {
+		return nil, t.writeErr(err)
+	}
+
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-t.done:
+		return nil, t.closedErr()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Notify sends a notification
+func (t *StdioTransport) Notify(ctx context.Context, req *Request) error {
+	if err := t.write(req); err != nil {
+		return t.writeErr(err)
+	}
+	return nil
+}
+
+// Close closes stdin and waits for the server to exit, killing it if it does not
+func (t *StdioTransport) Close() error {
+	t.stdin.Close()
+
+	select {
+	case <-t.done:
+	case <-time.After(closeTimeout):
+		logger.Warn("MCP server %s did not exit, killing it", t.name)
+		t.cmd.Process.Kill()
+		<-t.done
+	}
+
+	return nil
+}
+
+func (t *StdioTransport) write(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	if _, err := t.stdin.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write to MCP server %s: %w", t.name, err)
+	}
+	return nil
+}
+
+// readLoop dispatches responses to waiting calls until stdout closes
+func (t *StdioTransport) readLoop(stdout io.Reader, stderrDone <-chan struct{}) {
+	reader := bufio.NewReader(stdout)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			t.handle(line)
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	// Wait closes the pipes, so stderr must be drained first
+	<-stderrDone
+	waitErr := t.cmd.Wait()
+
+	t.mu.Lock()
+	if waitErr != nil {
+		t.err = fmt.Errorf("MCP server %s exited: %w", t.name, waitErr)
+	} else {
+		t.err = fmt.Errorf("MCP server %s exited", t.name)
+	}
+	t.mu.Unlock()
+	close(t.done)
+}
+
+func (t *StdioTransport) handle(line []byte) {
+	var msg message
+	if err := json.Unmarshal(line, &msg); err != nil {
+		logger.Debug("MCP server %s sent invalid JSON: %s", t.name, string(line))
+		return
+	}
+
+	if !msg.isResponse() {
+		t.handleServerMessage(&msg)
+		return
+	}
+
+	id, err := strconv.ParseInt(string(msg.ID), 10, 64)
+	if err != nil {
+		logger.Debug("MCP server %s sent response with unknown id %s", t.name, string(msg.ID))
+		return
+	}
+
+	t.mu.Lock()
+	ch, ok := t.pending[id]
+	t.mu.Unlock()
+	if ok {
+		ch <- msg.response()
+	}
+}
+
+// handleServerMessage answers server-initiated requests; notifications are only logged
+func (t *StdioTransport) handleServerMessage(msg *message) {
+	if len(msg.ID) == 0 {
+		logger.Debug("MCP server %s notification: %s", t.name, msg.Method)
+		return
+	}
+
+	reply := Response{JSONRPC: jsonRPCVersion, ID: msg.ID}
+	if msg.Method == "ping" {
+		reply.Result = json.RawMessage(`{}`)
+	} else {
+		reply.Error = &RPCError{Code: -32601, Message: "method not found: " + msg.Method}
+	}
+	if err := t.write(reply); err != nil {
+		logger.Warn("Failed to answer MCP server %s: %v", t.name, err)
+	}
+}
+
+func (t *StdioTransport) logStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		logger.Debug("MCP server %s: %s", t.name, scanner.Text())
+	}
+}
+
+// writeErr reports a failed write as the server's exit when it has gone away,
+// so a broken pipe reads the same as a closed stdout
+func (t *StdioTransport) writeErr(err error) error {
+	select {
+	case <-t.done:
+		return t.closedErr()
+	case <-time.After(exitWait):
+		return err
+	}
+}
+
+func (t *StdioTransport) closedErr() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
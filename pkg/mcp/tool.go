@@ -0,0 +1,119 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	ryantools "github.com/killallgit/ryan/pkg/tools"
+)
+
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// ToolName returns the namespaced registry name for a server's tool
+func ToolName(server, tool string) string {
+	return "mcp__" + unsafeNameChars.ReplaceAllString(server, "_") + "__" + unsafeNameChars.ReplaceAllString(tool, "_")
+}
+
+// Tool exposes a remote MCP tool as a langchaingo tool
+type Tool struct {
+	*ryantools.SecuredTool
+	client     *Client
+	server     string
+	definition ToolDefinition
+}
+
+// NewTool creates a tool that calls definition on the client's server
+func NewTool(client *Client, definition ToolDefinition) *Tool {
+	return NewToolWithBypass(client, definition, false)
+}
+
+// NewToolWithBypass creates an MCP tool with optional permission bypass
+func NewToolWithBypass(client *Client, definition ToolDefinition, bypass bool) *Tool {
+	return &Tool{
+		SecuredTool: ryantools.NewSecuredToolWithBypass(bypass),
+		client:      client,
+		server:      client.Name(),
+		definition:  definition,
+	}
+}
+
+// Name returns the namespaced tool name
+func (t *Tool) Name() string {
+	return ToolName(t.server, t.definition.Name)
+}
+
+// Description returns the server's description followed by the input schema
+func (t *Tool) Description() string {
+	description := strings.TrimSpace(t.definition.Description)
+	if description == "" {
+		description = fmt.Sprintf("%s tool from MCP server %s.", t.definition.Name, t.server)
+	}
+	return fmt.Sprintf("%s Input: JSON object matching schema %s", description, compactJSON(t.InputSchema()))
+}
+
+// InputSchema returns the JSON schema of the tool arguments
+func (t *Tool) InputSchema() json.RawMessage {
+	if len(t.definition.InputSchema) == 0 {
+		return json.RawMessage(`{"type":"object"}`)
+	}
+	return t.definition.InputSchema
+}
+
+// Call validates access and forwards the JSON arguments to the server
+func (t *Tool) Call(ctx context.Context, input string) (string, error) {
+	if err := t.ValidateAccess("Mcp", t.server+":"+t.definition.Name); err != nil {
+		return "", err
+	}
+
+	arguments := strings.TrimSpace(input)
+	if arguments == "" {
+		arguments = "{}"
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(arguments), &object); err != nil {
+		return "", fmt.Errorf("input must be a JSON object: %w", err)
+	}
+
+	result, err := t.client.CallTool(ctx, t.definition.Name, json.RawMessage(arguments))
+	if err != nil {
+		return "", err
+	}
+
+	output := formatContent(result.Content)
+	if result.IsError {
+		return "", fmt.Errorf("%s failed: %s", t.Name(), output)
+	}
+	return output, nil
+}
+
+// formatContent flattens tool result content into text
+func formatContent(content []Content) string {
+	parts := make([]string, 0, len(content))
+	for _, item := range content {
+		switch item.Type {
+		case "text":
+			parts = append(parts, item.Text)
+		case "resource":
+			if item.Resource != nil && item.Resource.Text != "" {
+				parts = append(parts, item.Resource.Text)
+			} else if item.Resource != nil {
+				parts = append(parts, fmt.Sprintf("[resource: %s]", item.Resource.URI))
+			}
+		default:
+			parts = append(parts, fmt.Sprintf("[%s: %s]", item.Type, item.MimeType))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func compactJSON(data json.RawMessage) string {
+	var out bytes.Buffer
+	if err := json.Compact(&out, data); err != nil {
+		return string(data)
+	}
+	return out.String()
+}
@@ -0,0 +1,237 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/killallgit/ryan/pkg/embeddings"
+	"github.com/killallgit/ryan/pkg/vectorstore"
+)
+
+// longTermCollection is the vector collection in the memory database holding long-term memories
+const longTermCollection = "memories"
+
+// Limits on how much of an exchange is kept and shown again
+const (
+	maxRememberedLength = 2000
+	maxRecalledLength   = 500
+)
+
+// MemoryKind says where a long-term memory came from
+type MemoryKind string
+
+const (
+	KindExchange MemoryKind = "exchange" // A completed user and assistant turn
+	KindFact     MemoryKind = "fact"     // Something the agent chose to remember
+)
+
+// LongTermEntry is one remembered exchange or fact
+type LongTermEntry struct {
+	ID      string
+	Kind    MemoryKind
+	Content string
+	Session string
+	Created time.Time
+	Score   float32 // Similarity to the query when recalled
+}
+
+// LongTermMemory embeds exchanges and facts into a vector collection so they
+// can be recalled in later sessions. Entries are scoped to the project they
+// were made in.
+type LongTermMemory struct {
+	store    *vectorstore.SQLiteStore
+	embedder embeddings.Embedder
+	project  string
+}
+
+// CurrentProject returns the project long-term memories are scoped to, the working directory
+func CurrentProject() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "."
+	}
+	return dir
+}
+
+// NewLongTermMemory opens the long-term memories of project in the database at
+// dbPath. Without an embedder memories can be listed and forgotten but not
+// added or recalled. The memory takes ownership of the embedder and closes it
+// in Close.
+func NewLongTermMemory(dbPath, project string, embedder embeddings.Embedder) (*LongTermMemory, error) {
+	store, err := vectorstore.NewSQLiteStore(vectorstore.SQLiteConfig{
+		Path:           dbPath,
+		CollectionName: longTermCollection,
+		Embedder:       embedder,
+	})
+	if err != nil {
+		if embedder != nil {
+			embedder.Close()
+		}
+		return nil, fmt.Errorf("failed to open long-term memory: %w", err)
+	}
+	return &LongTermMemory{store: store, embedder: embedder, project: project}, nil
+}
+
+// RememberExchange stores a completed turn of a session
+func (m *LongTermMemory) RememberExchange(ctx context.Context, session, prompt, response string) (LongTermEntry, error) {
+	content := fmt.Sprintf("User: %s\nAssistant: %s", strings.TrimSpace(prompt), strings.TrimSpace(response))
+	return m.remember(ctx, KindExchange, session, content)
+}
+
+// RememberFact stores something worth knowing in later sessions
+func (m *LongTermMemory) RememberFact(ctx context.Context, session, fact string) (LongTermEntry, error) {
+	fact = strings.TrimSpace(fact)
+	if fact == "" {
+		return LongTermEntry{}, fmt.Errorf("nothing to remember")
+	}
+	return m.remember(ctx, KindFact, session, fact)
+}
+
+func (m *LongTermMemory) remember(ctx context.Context, kind MemoryKind, session, content string) (LongTermEntry, error) {
+	entry := LongTermEntry{
+		ID:      "mem-" + strconv.FormatInt(time.Now().UnixNano(), 36),
+		Kind:    kind,
+		Content: truncateRunes(content, maxRememberedLength),
+		Session: session,
+		Created: time.Now(),
+	}
+	err := m.store.AddDocuments(ctx, []vectorstore.Document{{
+		ID:      entry.ID,
+		Content: entry.Content,
+		Metadata: map[string]interface{}{
+			"project": m.project,
+			"kind":    string(entry.Kind),
+			"session": entry.Session,
+			"created": entry.Created.Format(time.RFC3339Nano),
+		},
+	}})
+	if err != nil {
+		return LongTermEntry{}, fmt.Errorf("failed to remember %s: %w", kind, err)
+	}
+	return entry, nil
+}
+
+// Recall returns up to k memories of the project relevant to query, best
+// first, leaving out those of excludeSession, whose turns are already in context
+func (m *LongTermMemory) Recall(ctx context.Context, query string, k int, minScore float32, excludeSession string) ([]LongTermEntry, error) {
+	if k <= 0 {
+		return nil, nil
+	}
+	filter := m.filter()
+	if excludeSession != "" {
+		filter = filter.WithNotEquals("session", excludeSession)
+	}
+	results, err := m.store.SimilaritySearchWithFilter(ctx, query, k, minScore, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recall memories: %w", err)
+	}
+
+	entries := make([]LongTermEntry, len(results))
+	for i, result := range results {
+		entries[i] = entryFromDocument(result.Document)
+		entries[i].Score = result.Score
+	}
+	return entries, nil
+}
+
+// List returns every memory of the project, newest first
+func (m *LongTermMemory) List(ctx context.Context) ([]LongTermEntry, error) {
+	documents, err := m.store.List(ctx, m.filter())
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]LongTermEntry, len(documents))
+	for i, doc := range documents {
+		entries[i] = entryFromDocument(doc)
+	}
+	sort.SliceStable(entries, func(a, b int) bool {
+		if !entries[a].Created.Equal(entries[b].Created) {
+			return entries[a].Created.After(entries[b].Created)
+		}
+		return entries[a].ID > entries[b].ID
+	})
+	return entries, nil
+}
+
+// Forget removes memories of the project by ID, failing if any is unknown
+func (m *LongTermMemory) Forget(ctx context.Context, ids ...string) error {
+	entries, err := m.List(ctx)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		known[entry.ID] = true
+	}
+	for _, id := range ids {
+		if !known[id] {
+			return fmt.Errorf("no memory with id %s", id)
+		}
+	}
+	return m.store.DeleteDocuments(ctx, ids)
+}
+
+// ForgetAll removes every memory of the project and returns how many there were
+func (m *LongTermMemory) ForgetAll(ctx context.Context) (int, error) {
+	entries, err := m.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	ids := make([]string, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.ID
+	}
+	return len(ids), m.store.DeleteDocuments(ctx, ids)
+}
+
+// Close closes the memory database and the embedder
+func (m *LongTermMemory) Close() error {
+	err := m.store.Close()
+	if m.embedder != nil {
+		if embedErr := m.embedder.Close(); err == nil {
+			err = embedErr
+		}
+	}
+	return err
+}
+
+func (m *LongTermMemory) filter() vectorstore.Filter {
+	return vectorstore.Filter{}.WithEquals("project", m.project)
+}
+
+func entryFromDocument(doc vectorstore.Document) LongTermEntry {
+	entry := LongTermEntry{ID: doc.ID, Content: doc.Content}
+	entry.Kind = MemoryKind(fmt.Sprint(doc.Metadata["kind"]))
+	entry.Session = fmt.Sprint(doc.Metadata["session"])
+	if created, ok := doc.Metadata["created"].(string); ok {
+		entry.Created, _ = time.Parse(time.RFC3339Nano, created)
+	}
+	return entry
+}
+
+// FormatRecollections renders recalled memories as a context block for a prompt
+func FormatRecollections(entries []LongTermEntry) string {
+	if len(entries) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Relevant memories from earlier sessions in this project (they may be out of date):\n")
+	for _, entry := range entries {
+		content := strings.ReplaceAll(truncateRunes(entry.Content, maxRecalledLength), "\n", "\n  ")
+		fmt.Fprintf(&b, "- [%s, %s] %s\n", entry.Created.Format("2006-01-02"), entry.Kind, content)
+	}
+	return b.String()
+}
+
+// truncateRunes shortens s to at most n runes
+func truncateRunes(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n]) + "..."
+	}
+	return s
+}
@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/killallgit/ryan/pkg/embeddings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLongTermMemory(t *testing.T, dbPath, project string, embedder embeddings.Embedder) *LongTermMemory {
+	t.Helper()
+	longTerm, err := NewLongTermMemory(dbPath, project, embedder)
+	require.NoError(t, err)
+	t.Cleanup(func() { longTerm.Close() })
+	return longTerm
+}
+
+func TestLongTermMemory(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "memory.db")
+	embedder := embeddings.NewLocalEmbedder(256)
+	longTerm := newTestLongTermMemory(t, dbPath, "/src/ryan", embedder)
+	ctx := context.Background()
+
+	exchange, err := longTerm.RememberExchange(ctx, "session_a", "Which database should sessions use?", "We decided to keep sessions in SQLite.")
+	require.NoError(t, err)
+	fact, err := longTerm.RememberFact(ctx, "session_a", "Release builds are cut from the main branch every Friday.")
+	require.NoError(t, err)
+	_, err = longTerm.RememberFact(ctx, "session_a", "  ")
+	assert.Error(t, err)
+
+	// Another project in the same database is kept apart
+	other := newTestLongTermMemory(t, dbPath, "/src/other", embedder)
+	_, err = other.RememberFact(ctx, "session_x", "Sessions are kept in Postgres.")
+	require.NoError(t, err)
+
+	entries, err := longTerm.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, fact.ID, entries[0].ID, "newest first")
+	assert.Equal(t, KindFact, entries[0].Kind)
+	assert.Equal(t, "session_a", entries[1].Session)
+	assert.Contains(t, entries[1].Content, "User: Which database")
+
+	// A new session recalls the decision; the session that made it does not need to
+	recalled, err := longTerm.Recall(ctx, "where are sessions stored", 1, 0, "session_b")
+	require.NoError(t, err)
+	require.Len(t, recalled, 1)
+	assert.Equal(t, exchange.ID, recalled[0].ID)
+	assert.Positive(t, recalled[0].Score)
+	assert.Contains(t, FormatRecollections(recalled), "exchange] User: Which database")
+
+	recalled, err = longTerm.Recall(ctx, "where are sessions stored", 3, 0, "session_a")
+	require.NoError(t, err)
+	assert.Empty(t, recalled)
+
+	recalled, err = longTerm.Recall(ctx, "where are sessions stored", 3, 0.99, "")
+	require.NoError(t, err)
+	assert.Empty(t, recalled, "memories below the minimum score are left out")
+
+	// Listing and forgetting work without an embedder
+	offline := newTestLongTermMemory(t, dbPath, "/src/ryan", nil)
+	assert.ErrorContains(t, offline.Forget(ctx, "mem-unknown"), "no memory with id")
+	require.NoError(t, offline.Forget(ctx, exchange.ID))
+	entries, err = offline.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+	_, err = offline.RememberFact(ctx, "session_b", "needs an embedder")
+	assert.Error(t, err)
+
+	n, err := longTerm.ForgetAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	entries, err = other.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "other projects keep their memories")
+}
+
+// closeCountingEmbedder records how often it is closed
+type closeCountingEmbedder struct {
+	embeddings.Embedder
+	closed int
+}
+
+func (e *closeCountingEmbedder) Close() error {
+	e.closed++
+	return nil
+}
+
+func TestLongTermMemoryRecallExcludesSessionInQuery(t *testing.T) {
+	embedder := &closeCountingEmbedder{Embedder: embeddings.NewLocalEmbedder(256)}
+	longTerm, err := NewLongTermMemory(filepath.Join(t.TempDir(), "memory.db"), "/src/ryan", embedder)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// The current session's entries outrank the older one several times over
+	for i := 0; i < 5; i++ {
+		_, err := longTerm.RememberFact(ctx, "session_now", "Sessions are stored in SQLite files.")
+		require.NoError(t, err)
+	}
+	older, err := longTerm.RememberFact(ctx, "session_old", "Sessions used to be stored in JSON.")
+	require.NoError(t, err)
+
+	recalled, err := longTerm.Recall(ctx, "where are sessions stored", 1, 0, "session_now")
+	require.NoError(t, err)
+	require.Len(t, recalled, 1)
+	assert.Equal(t, older.ID, recalled[0].ID)
+
+	require.NoError(t, longTerm.Close())
+	assert.Equal(t, 1, embedder.closed, "the memory owns its embedder")
+}
@@ -14,17 +14,26 @@ import (
 
 type Memory struct {
 	store     *sqlite3.SqliteChatMessageHistory
+	todos     *TodoList
 	dbPath    string
 	sessionID string
 }
 
-func New(sessionID string) (*Memory, error) {
+// DatabasePath returns the project's memory database, creating its directory
+func DatabasePath() (string, error) {
 	// Create context directory for memory database using config helper
 	contextDir := config.BuildSettingsPath("context")
 	if err := os.MkdirAll(contextDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create context directory: %w", err)
+		return "", fmt.Errorf("failed to create context directory: %w", err)
+	}
+	return filepath.Join(contextDir, "memory.db"), nil
+}
+
+func New(sessionID string) (*Memory, error) {
+	dbPath, err := DatabasePath()
+	if err != nil {
+		return nil, err
 	}
-	dbPath := filepath.Join(contextDir, "memory.db")
 
 	connectionString := fmt.Sprintf("file:%s?mode=rwc", dbPath)
 	chatHistory := sqlite3.NewSqliteChatMessageHistory(
@@ -32,8 +41,15 @@ func New(sessionID string) (*Memory, error) {
 		sqlite3.WithSession(sessionID),
 	)
 
+	todos, err := NewTodoList(dbPath, sessionID)
+	if err != nil {
+		chatHistory.DB.Close()
+		return nil, err
+	}
+
 	return &Memory{
 		store:     chatHistory,
+		todos:     todos,
 		dbPath:    dbPath,
 		sessionID: sessionID,
 	}, nil
@@ -99,11 +115,19 @@ func (m *Memory) ConvertToLLMMessages() ([]llm.Message, error) {
 }
 
 func (m *Memory) Clear() error {
-	return m.store.Clear(context.Background())
+	if err := m.store.Clear(context.Background()); err != nil {
+		return err
+	}
+	return m.todos.Clear()
 }
 
 func (m *Memory) Close() error {
-	return nil
+	return m.todos.Close()
+}
+
+// Todos returns the session's task list, stored in the same database as the chat history
+func (m *Memory) Todos() *TodoList {
+	return m.todos
 }
 
 // ChatMessageHistory returns the underlying chat message history for use with agents
@@ -0,0 +1,198 @@
+package memory
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	_ "github.com/mattn/go-sqlite3" // sqlite3 driver
+)
+
+// TodoStatus is the progress of a todo item
+type TodoStatus string
+
+const (
+	TodoPending    TodoStatus = "pending"
+	TodoInProgress TodoStatus = "in_progress"
+	TodoDone       TodoStatus = "done"
+)
+
+// Todo is a single task in the agent's plan
+type Todo struct {
+	ID      string     `json:"id"`
+	Content string     `json:"content"`
+	Status  TodoStatus `json:"status"`
+}
+
+// TodoList is a per-session task list persisted next to the chat history
+type TodoList struct {
+	mu        sync.Mutex
+	db        *sql.DB
+	sessionID string
+	onChange  []func([]Todo)
+}
+
+// NewTodoList opens the todo list for a session in the given SQLite database
+func NewTodoList(dbPath, sessionID string) (*TodoList, error) {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?mode=rwc", dbPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open todo database: %w", err)
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS todos (
+		session TEXT NOT NULL,
+		position INTEGER NOT NULL,
+		id TEXT NOT NULL,
+		content TEXT NOT NULL,
+		status TEXT NOT NULL,
+		PRIMARY KEY (session, id)
+	)`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create todo table: %w", err)
+	}
+
+	return &TodoList{db: db, sessionID: sessionID}, nil
+}
+
+// Get returns the todos for the session in order
+func (l *TodoList) Get() ([]Todo, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.get()
+}
+
+func (l *TodoList) get() ([]Todo, error) {
+	rows, err := l.db.Query(`SELECT id, content, status FROM todos WHERE session = ? ORDER BY position`, l.sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read todos: %w", err)
+	}
+	defer rows.Close()
+
+	todos := []Todo{}
+	for rows.Next() {
+		var todo Todo
+		if err := rows.Scan(&todo.ID, &todo.Content, &todo.Status); err != nil {
+			return nil, fmt.Errorf("failed to read todos: %w", err)
+		}
+		todos = append(todos, todo)
+	}
+	return todos, rows.Err()
+}
+
+// Set replaces the session's todos. Missing IDs are assigned from the item position.
+func (l *TodoList) Set(todos []Todo) ([]Todo, error) {
+	normalized, err := NormalizeTodos(todos)
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	tx, err := l.db.Begin()
+	if err != nil {
+		l.mu.Unlock()
+		return nil, fmt.Errorf("failed to update todos: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM todos WHERE session = ?`, l.sessionID); err != nil {
+		tx.Rollback()
+		l.mu.Unlock()
+		return nil, fmt.Errorf("failed to update todos: %w", err)
+	}
+	for i, todo := range normalized {
+		_, err := tx.Exec(`INSERT INTO todos (session, position, id, content, status) VALUES (?, ?, ?, ?, ?)`,
+			l.sessionID, i, todo.ID, todo.Content, string(todo.Status))
+		if err != nil {
+			tx.Rollback()
+			l.mu.Unlock()
+			return nil, fmt.Errorf("failed to update todos: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		l.mu.Unlock()
+		return nil, fmt.Errorf("failed to update todos: %w", err)
+	}
+
+	listeners := append([]func([]Todo){}, l.onChange...)
+	l.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(append([]Todo{}, normalized...))
+	}
+	return normalized, nil
+}
+
+// Clear removes all todos for the session
+func (l *TodoList) Clear() error {
+	_, err := l.Set(nil)
+	return err
+}
+
+// OnChange registers a callback invoked with the full list after every update
+func (l *TodoList) OnChange(fn func([]Todo)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onChange = append(l.onChange, fn)
+}
+
+// Close closes the underlying database
+func (l *TodoList) Close() error {
+	return l.db.Close()
+}
+
+// NormalizeTodos validates todo items, trimming content, defaulting the status
+// to pending and assigning IDs to items without one
+func NormalizeTodos(todos []Todo) ([]Todo, error) {
+	normalized := make([]Todo, 0, len(todos))
+	seen := make(map[string]bool)
+
+	for i, todo := range todos {
+		todo.ID = strings.TrimSpace(todo.ID)
+		todo.Content = strings.TrimSpace(todo.Content)
+
+		if todo.Content == "" {
+			return nil, fmt.Errorf("todo %d has no content", i+1)
+		}
+		if todo.ID == "" {
+			todo.ID = strconv.Itoa(i + 1)
+		}
+		if seen[todo.ID] {
+			return nil, fmt.Errorf("duplicate todo id %q", todo.ID)
+		}
+		seen[todo.ID] = true
+
+		switch todo.Status {
+		case "":
+			todo.Status = TodoPending
+		case TodoPending, TodoInProgress, TodoDone:
+		default:
+			return nil, fmt.Errorf("todo %q has invalid status %q (use pending, in_progress or done)", todo.ID, todo.Status)
+		}
+
+		normalized = append(normalized, todo)
+	}
+
+	return normalized, nil
+}
+
+// FormatTodos renders todos as a Markdown checklist
+func FormatTodos(todos []Todo) string {
+	if len(todos) == 0 {
+		return "No todos"
+	}
+
+	lines := make([]string, 0, len(todos))
+	for _, todo := range todos {
+		mark := " "
+		switch todo.Status {
+		case TodoInProgress:
+			mark = "~"
+		case TodoDone:
+			mark = "x"
+		}
+		lines = append(lines, fmt.Sprintf("- [%s] %s. %s", mark, todo.ID, todo.Content))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTodoList(t *testing.T, dbPath, sessionID string) *TodoList {
+	t.Helper()
+	list, err := NewTodoList(dbPath, sessionID)
+	require.NoError(t, err)
+	t.Cleanup(func() { list.Close() })
+	return list
+}
+
+func TestTodoList(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "memory.db")
+	list := newTestTodoList(t, dbPath, "session_a")
+
+	todos, err := list.Get()
+	require.NoError(t, err)
+	assert.Empty(t, todos)
+
+	var notified []Todo
+	list.OnChange(func(todos []Todo) { notified = todos })
+
+	saved, err := list.Set([]Todo{
+		{Content: "  read the code  ", Status: TodoDone},
+		{Content: "write the fix", Status: TodoInProgress},
+		{ID: "tests", Content: "add tests"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []Todo{
+		{ID: "1", Content: "read the code", Status: TodoDone},
+		{ID: "2", Content: "write the fix", Status: TodoInProgress},
+		{ID: "tests", Content: "add tests", Status: TodoPending},
+	}, saved)
+	assert.Equal(t, saved, notified)
+
+	t.Run("persisted per session", func(t *testing.T) {
+		reopened := newTestTodoList(t, dbPath, "session_a")
+		todos, err := reopened.Get()
+		require.NoError(t, err)
+		assert.Equal(t, saved, todos)
+
+		other := newTestTodoList(t, dbPath, "session_b")
+		todos, err = other.Get()
+		require.NoError(t, err)
+		assert.Empty(t, todos)
+	})
+
+	t.Run("set replaces the list", func(t *testing.T) {
+		_, err := list.Set([]Todo{{ID: "1", Content: "only item", Status: TodoDone}})
+		require.NoError(t, err)
+		todos, err := list.Get()
+		require.NoError(t, err)
+		assert.Len(t, todos, 1)
+	})
+
+	t.Run("clear", func(t *testing.T) {
+		require.NoError(t, list.Clear())
+		todos, err := list.Get()
+		require.NoError(t, err)
+		assert.Empty(t, todos)
+		assert.Empty(t, notified)
+	})
+}
+
+func TestNormalizeTodos(t *testing.T) {
+	_, err := NormalizeTodos([]Todo{{Content: ""}})
+	assert.Error(t, err)
+
+	_, err = NormalizeTodos([]Todo{{ID: "1", Content: "a"}, {ID: "1", Content: "b"}})
+	assert.Error(t, err)
+
+	_, err = NormalizeTodos([]Todo{{Content: "a", Status: "blocked"}})
+	assert.Error(t, err)
+}
+
+func TestFormatTodos(t *testing.T) {
+	assert.Equal(t, "No todos", FormatTodos(nil))
+	assert.Equal(t, "- [x] 1. a\n- [~] 2. b\n- [ ] 3. c", FormatTodos([]Todo{
+		{ID: "1", Content: "a", Status: TodoDone},
+		{ID: "2", Content: "b", Status: TodoInProgress},
+		{ID: "3", Content: "c", Status: TodoPending},
+	}))
+}
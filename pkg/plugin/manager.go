@@ -0,0 +1,103 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/killallgit/ryan/pkg/config"
+	"github.com/killallgit/ryan/pkg/logger"
+	"github.com/killallgit/ryan/pkg/tools/registry"
+	"github.com/tmc/langchaingo/tools"
+)
+
+// describeTimeout bounds the describe handshake at startup
+const describeTimeout = 5 * time.Second
+
+// DefaultDir returns the plugin directory next to the settings file
+func DefaultDir() string {
+	return config.BuildSettingsPath("plugins")
+}
+
+// Discover describes every executable in dir. Plugins that fail the handshake
+// are skipped and reported in the returned error; a missing dir is not an error.
+func Discover(ctx context.Context, dir string, timeout time.Duration) ([]*Plugin, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin directory: %w", err)
+	}
+
+	var plugins []*Plugin
+	var errs []error
+	seen := make(map[string]string)
+
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") || entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+			logger.Debug("Skipping non-executable plugin file %s", path)
+			continue
+		}
+
+		describeCtx, cancel := context.WithTimeout(ctx, describeTimeout)
+		plugin, err := Describe(describeCtx, path, timeout)
+		cancel()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		name := plugin.Descriptor.Name
+		if other, exists := seen[name]; exists {
+			errs = append(errs, fmt.Errorf("plugin %s: name %s is already used by %s", path, name, other))
+			continue
+		}
+		seen[name] = path
+		plugins = append(plugins, plugin)
+	}
+
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Descriptor.Name < plugins[j].Descriptor.Name })
+	return plugins, errors.Join(errs...)
+}
+
+// Register discovers the plugins in dir and registers them as tools, returning their names
+func Register(ctx context.Context, reg registry.Registry, dir string, timeout time.Duration) ([]string, error) {
+	plugins, err := Discover(ctx, dir, timeout)
+	errs := []error{err}
+
+	taken := registry.ToolNames(reg)
+	var names []string
+	for _, plugin := range plugins {
+		plugin := plugin
+		name := plugin.Descriptor.Name
+		if taken[name] {
+			errs = append(errs, fmt.Errorf("plugin %s: name %s is already used by another tool", plugin.Path, name))
+			continue
+		}
+
+		err := reg.Register(name, func(skipPermissions bool) tools.Tool {
+			return NewToolWithBypass(plugin, skipPermissions)
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("plugin %s: %w", plugin.Path, err))
+			continue
+		}
+		taken[name] = true
+		names = append(names, name)
+		logger.Info("Registered plugin %s %s from %s", name, plugin.Descriptor.Version, plugin.Path)
+	}
+
+	return names, errors.Join(errs...)
+}
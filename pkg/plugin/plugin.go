@@ -0,0 +1,169 @@
+// Package plugin runs third-party tools shipped as executables in .ryan/plugins.
+//
+// A plugin is any executable that reads one JSON request from stdin and writes
+// one JSON response to stdout. Every request starts a new process, so a plugin
+// that crashes or hangs only fails the request it was handling.
+//
+// The describe handshake:
+//
+//	-> {"protocol_version": 1, "type": "describe"}
+//	<- {"protocol_version": 1, "name": "jira", "version": "0.3.0",
+//	    "description": "...", "input_schema": {"type": "object", ...}}
+//
+// A call:
+//
+//	-> {"protocol_version": 1, "type": "call", "input": {...}}
+//	<- {"output": "..."} or {"error": "..."}
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ProtocolVersion is the plugin protocol version this build speaks
+const ProtocolVersion = 1
+
+// defaultTimeout applies when no call timeout is configured
+const defaultTimeout = 60 * time.Second
+
+// maxOutputBytes caps how much a plugin may write to stdout for one request
+const maxOutputBytes = 4 * 1024 * 1024
+
+var validName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+// Request is sent to a plugin on stdin
+type Request struct {
+	ProtocolVersion int             `json:"protocol_version"`
+	Type            string          `json:"type"`
+	Input           json.RawMessage `json:"input,omitempty"`
+}
+
+// Descriptor is a plugin's answer to the describe request
+type Descriptor struct {
+	ProtocolVersion int             `json:"protocol_version"`
+	Name            string          `json:"name"`
+	Version         string          `json:"version"`
+	Description     string          `json:"description"`
+	InputSchema     json.RawMessage `json:"input_schema"`
+}
+
+// CallResponse is a plugin's answer to a call request
+type CallResponse struct {
+	Output string `json:"output"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Plugin is a discovered, described plugin executable
+type Plugin struct {
+	Path       string
+	Descriptor Descriptor
+	timeout    time.Duration
+}
+
+// Describe runs the describe handshake and checks the protocol version
+func Describe(ctx context.Context, path string, timeout time.Duration) (*Plugin, error) {
+	var descriptor Descriptor
+	if err := run(ctx, path, timeout, Request{ProtocolVersion: ProtocolVersion, Type: "describe"}, &descriptor); err != nil {
+		return nil, fmt.Errorf("plugin %s: describe failed: %w", path, err)
+	}
+
+	switch {
+	case descriptor.ProtocolVersion == 0:
+		return nil, fmt.Errorf("plugin %s does not declare protocol_version", path)
+	case descriptor.ProtocolVersion != ProtocolVersion:
+		return nil, fmt.Errorf("plugin %s uses protocol version %d, expected %d", path, descriptor.ProtocolVersion, ProtocolVersion)
+	case !validName.MatchString(descriptor.Name):
+		return nil, fmt.Errorf("plugin %s has invalid name %q (use letters, digits, _ and -)", path, descriptor.Name)
+	}
+
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	if len(descriptor.InputSchema) == 0 {
+		descriptor.InputSchema = json.RawMessage(`{"type":"object"}`)
+	}
+
+	return &Plugin{Path: path, Descriptor: descriptor, timeout: timeout}, nil
+}
+
+// Call runs the plugin with JSON object input and returns its output
+func (p *Plugin) Call(ctx context.Context, input json.RawMessage) (string, error) {
+	var resp CallResponse
+	if err := run(ctx, p.Path, p.timeout, Request{ProtocolVersion: ProtocolVersion, Type: "call", Input: input}, &resp); err != nil {
+		return "", fmt.Errorf("plugin %s failed: %w", p.Descriptor.Name, err)
+	}
+	if resp.Error != "" {
+		return resp.Output, fmt.Errorf("plugin %s: %s", p.Descriptor.Name, resp.Error)
+	}
+	return resp.Output, nil
+}
+
+// run starts the plugin for a single request and decodes its response
+func run(ctx context.Context, path string, timeout time.Duration, req Request, resp interface{}) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Stdin = bytes.NewReader(append(data, '\n'))
+	cmd.Env = append(cmd.Environ(), fmt.Sprintf("RYAN_PLUGIN_PROTOCOL=%d", ProtocolVersion))
+	cmd.WaitDelay = time.Second
+
+	stdout := &limitedBuffer{limit: maxOutputBytes}
+	stderr := &limitedBuffer{limit: 4096}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	runErr := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %v", timeout)
+	}
+	if stdout.overflow {
+		return fmt.Errorf("response exceeds %d bytes", maxOutputBytes)
+	}
+	if runErr != nil {
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			return fmt.Errorf("%w: %s", runErr, detail)
+		}
+		return runErr
+	}
+
+	if err := json.Unmarshal(bytes.TrimSpace(stdout.Bytes()), resp); err != nil {
+		return fmt.Errorf("invalid JSON response: %w", err)
+	}
+	return nil
+}
+
+// limitedBuffer keeps at most limit bytes and records whether more were written
+type limitedBuffer struct {
+	bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	remaining := b.limit - b.Len()
+	if len(p) > remaining {
+		b.overflow = true
+		if remaining > 0 {
+			b.Buffer.Write(p[:remaining])
+		}
+		// Report a full write so the plugin is not killed by a broken pipe
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
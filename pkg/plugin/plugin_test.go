@@ -0,0 +1,151 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/killallgit/ryan/pkg/tools/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const describeGreet = `{"protocol_version":1,"name":"%s","version":"1.0.0","description":"Greets someone.","input_schema":{"type":"object","properties":{"name":{"type":"string"}}}}`
+
+// writePlugin writes a shell plugin that answers describe with descriptor and runs call for calls
+func writePlugin(t *testing.T, dir, file, descriptor, call string) string {
+	t.Helper()
+	script := fmt.Sprintf(`#!/bin/sh
+read -r req
+case "$req" in
+  *'"type":"describe"'*) echo '%s' ;;
+  *) %s ;;
+esac
+`, descriptor, call)
+	path := filepath.Join(dir, file)
+	require.NoError(t, os.WriteFile(path, []byte(script), 0755))
+	return path
+}
+
+func TestDescribeAndCall(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	greet := writePlugin(t, dir, "greet", fmt.Sprintf(describeGreet, "greet"),
+		`name=$(printf '%s' "$req" | sed -n 's/.*"name":"\([^"]*\)".*/\1/p'); echo "{\"output\":\"hello $name\"}"`)
+
+	plugin, err := Describe(ctx, greet, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "greet", plugin.Descriptor.Name)
+	assert.Equal(t, "1.0.0", plugin.Descriptor.Version)
+
+	output, err := plugin.Call(ctx, []byte(`{"name":"bob"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "hello bob", output)
+}
+
+func TestPluginFailuresAreIsolated(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	descriptor := fmt.Sprintf(describeGreet, "flaky")
+
+	tests := []struct {
+		name    string
+		call    string
+		wantErr string
+	}{
+		{"crash", `echo boom >&2; exit 3`, "exit status 3: boom"},
+		{"hang", `sleep 10`, "timed out"},
+		{"garbage", `echo not json`, "invalid JSON response"},
+		{"reported error", `echo '{"error":"bad input"}'`, "plugin flaky: bad input"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writePlugin(t, dir, tt.name, descriptor, tt.call)
+			plugin, err := Describe(ctx, path, 500*time.Millisecond)
+			require.NoError(t, err)
+
+			start := time.Now()
+			_, err = plugin.Call(ctx, []byte(`{}`))
+			assert.ErrorContains(t, err, tt.wantErr)
+			assert.Less(t, time.Since(start), 5*time.Second)
+		})
+	}
+}
+
+func TestDescribeRejectsIncompatiblePlugins(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		descriptor string
+		wantErr    string
+	}{
+		{"newer protocol", `{"protocol_version":2,"name":"future"}`, "protocol version 2, expected 1"},
+		{"no protocol", `{"name":"legacy"}`, "does not declare protocol_version"},
+		{"bad name", `{"protocol_version":1,"name":"has space"}`, "invalid name"},
+		{"not json", `hello`, "invalid JSON response"},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writePlugin(t, dir, fmt.Sprintf("p%d", i), tt.descriptor, "exit 1")
+			_, err := Describe(ctx, path, time.Second)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".ryan"), 0755))
+	settings := `{"permissions": {"allow": ["Plugin(greet:*)"]}}`
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".ryan", "settings.json"), []byte(settings), 0644))
+
+	dir := t.TempDir()
+	echoOutput := `echo '{"output":"ok"}'`
+	writePlugin(t, dir, "a-greet", fmt.Sprintf(describeGreet, "greet"), echoOutput)
+	writePlugin(t, dir, "b-greet-copy", fmt.Sprintf(describeGreet, "greet"), echoOutput)
+	writePlugin(t, dir, "other", fmt.Sprintf(describeGreet, "other"), echoOutput)
+	writePlugin(t, dir, "old", `{"protocol_version":2,"name":"old"}`, echoOutput)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a plugin"), 0644))
+
+	reg := registry.New()
+	names, err := Register(context.Background(), reg, dir, time.Second)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "name greet is already used by")
+	assert.Contains(t, err.Error(), "protocol version 2")
+	assert.NotContains(t, err.Error(), "README.md")
+	assert.Equal(t, []string{"greet", "other"}, names)
+
+	greet, err := reg.Get("greet", false)
+	require.NoError(t, err)
+	provider, ok := greet.(registry.SchemaProvider)
+	require.True(t, ok)
+	assert.Contains(t, string(provider.InputSchema()), `"name"`)
+
+	output, err := greet.Call(context.Background(), `{"name":"bob"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", output)
+
+	// Each plugin has its own ACL name
+	other, err := reg.Get("other", false)
+	require.NoError(t, err)
+	_, err = other.Call(context.Background(), `{}`)
+	assert.ErrorContains(t, err, "Plugin(other:{})")
+
+	_, err = greet.Call(context.Background(), `not json`)
+	assert.ErrorContains(t, err, "JSON object")
+}
+
+func TestDiscoverMissingDir(t *testing.T) {
+	plugins, err := Discover(context.Background(), filepath.Join(t.TempDir(), "missing"), time.Second)
+	assert.NoError(t, err)
+	assert.Empty(t, plugins)
+}
@@ -0,0 +1,66 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	ryantools "github.com/killallgit/ryan/pkg/tools"
+)
+
+// Tool adapts a plugin to a langchaingo tool
+type Tool struct {
+	*ryantools.SecuredTool
+	plugin *Plugin
+}
+
+// NewTool creates a tool for a described plugin
+func NewTool(plugin *Plugin) *Tool {
+	return NewToolWithBypass(plugin, false)
+}
+
+// NewToolWithBypass creates a plugin tool with optional permission bypass
+func NewToolWithBypass(plugin *Plugin, bypass bool) *Tool {
+	return &Tool{
+		SecuredTool: ryantools.NewSecuredToolWithBypass(bypass),
+		plugin:      plugin,
+	}
+}
+
+// Name returns the plugin name
+func (t *Tool) Name() string {
+	return t.plugin.Descriptor.Name
+}
+
+// Description returns the plugin description followed by the input schema
+func (t *Tool) Description() string {
+	description := strings.TrimSpace(t.plugin.Descriptor.Description)
+	if description == "" {
+		description = fmt.Sprintf("Plugin %s.", t.plugin.Descriptor.Name)
+	}
+	return fmt.Sprintf("%s Input: JSON object matching schema %s", description, string(t.InputSchema()))
+}
+
+// InputSchema returns the plugin's JSON schema
+func (t *Tool) InputSchema() json.RawMessage {
+	return t.plugin.Descriptor.InputSchema
+}
+
+// Call validates access and runs the plugin with the JSON input
+func (t *Tool) Call(ctx context.Context, input string) (string, error) {
+	arguments := strings.TrimSpace(input)
+	if arguments == "" {
+		arguments = "{}"
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(arguments), &object); err != nil {
+		return "", fmt.Errorf("input must be a JSON object: %w", err)
+	}
+
+	if err := t.ValidateAccess("Plugin", t.plugin.Descriptor.Name+":"+arguments); err != nil {
+		return "", err
+	}
+
+	return t.plugin.Call(ctx, json.RawMessage(arguments))
+}
@@ -0,0 +1,193 @@
+package prompt
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// systemTemplates holds the default template of each system prompt section
+//
+//go:embed system/*.tmpl
+var systemTemplates embed.FS
+
+// SystemSections are the sections of the system prompt in the order they are assembled.
+// Each is rendered from <name>.tmpl; a section that renders empty is left out.
+var SystemSections = []string{"identity", "environment", "git", "tools", "permissions", "instructions"}
+
+// Defaults for environment detection
+const (
+	defaultGitStatusLines = 20
+	gitTimeout            = 2 * time.Second
+	maxToolSummaryLength  = 120
+)
+
+// SystemEnvironment is what the system prompt tells the model about where it runs
+type SystemEnvironment struct {
+	WorkingDir  string
+	OS          string
+	Arch        string
+	Shell       string
+	Date        time.Time
+	Git         GitInfo
+	Tools       []ToolInfo
+	Permissions PermissionInfo
+}
+
+// GitInfo describes the repository containing the working directory
+type GitInfo struct {
+	Repository bool
+	Branch     string   // Empty when HEAD is detached
+	Status     []string // Short status lines of uncommitted changes
+	MoreStatus int      // Status lines left out
+}
+
+// ToolInfo names a tool the model can call
+type ToolInfo struct {
+	Name    string
+	Summary string // First sentence of the description
+}
+
+// PermissionInfo describes the tool ACL in force
+type PermissionInfo struct {
+	Allowed  []string // Allowed Tool(pattern) entries
+	Bypassed bool     // Checks are skipped for the session
+}
+
+// NewToolInfo summarizes a tool by its name and the first sentence of its description
+func NewToolInfo(name, description string) ToolInfo {
+	summary := strings.Join(strings.Fields(description), " ")
+	if i := strings.Index(summary, ". "); i >= 0 {
+		summary = summary[:i+1]
+	}
+	if runes := []rune(summary); len(runes) > maxToolSummaryLength {
+		summary = string(runes[:maxToolSummaryLength-3]) + "..."
+	}
+	return ToolInfo{Name: name, Summary: summary}
+}
+
+// DetectEnvironment describes the working directory, platform, date and git
+// state. At most gitStatusLines status lines are kept (default 20). Tools and
+// permissions are left for the caller to fill in.
+func DetectEnvironment(ctx context.Context, gitStatusLines int) SystemEnvironment {
+	if gitStatusLines <= 0 {
+		gitStatusLines = defaultGitStatusLines
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		wd = "."
+	}
+	return SystemEnvironment{
+		WorkingDir: wd,
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		Shell:      os.Getenv("SHELL"),
+		Date:       time.Now(),
+		Git:        detectGit(ctx, wd, gitStatusLines),
+	}
+}
+
+func detectGit(ctx context.Context, dir string, statusLines int) GitInfo {
+	ctx, cancel := context.WithTimeout(ctx, gitTimeout)
+	defer cancel()
+
+	git := func(args ...string) (string, error) {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Dir = dir
+		out, err := cmd.Output()
+		return strings.TrimRight(string(out), "\n"), err
+	}
+
+	if inside, err := git("rev-parse", "--is-inside-work-tree"); err != nil || inside != "true" {
+		return GitInfo{}
+	}
+	info := GitInfo{Repository: true}
+	if branch, err := git("branch", "--show-current"); err == nil {
+		info.Branch = branch
+	}
+	if status, err := git("status", "--short"); err == nil && status != "" {
+		lines := strings.Split(status, "\n")
+		if len(lines) > statusLines {
+			info.MoreStatus = len(lines) - statusLines
+			lines = lines[:statusLines]
+		}
+		info.Status = lines
+	}
+	return info
+}
+
+// SystemPromptConfig says where section overrides are looked up
+type SystemPromptConfig struct {
+	// ProjectDir holds the project's <section>.tmpl overrides, which take precedence
+	ProjectDir string
+
+	// UserDir holds the user's <section>.tmpl overrides
+	UserDir string
+}
+
+// SystemPromptBuilder assembles the system prompt from section templates.
+// Each section comes from the project directory, else the user directory,
+// else the built-in default, so an empty override file removes a section.
+type SystemPromptBuilder struct {
+	config SystemPromptConfig
+}
+
+// NewSystemPromptBuilder creates a system prompt builder
+func NewSystemPromptBuilder(config SystemPromptConfig) *SystemPromptBuilder {
+	return &SystemPromptBuilder{config: config}
+}
+
+// Build renders every section for env and joins those that are not empty
+func (b *SystemPromptBuilder) Build(env SystemEnvironment) (string, error) {
+	var sections []string
+	for _, name := range SystemSections {
+		text, source, err := b.section(name)
+		if err != nil {
+			return "", err
+		}
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse %s: %w", source, err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, env); err != nil {
+			return "", fmt.Errorf("failed to render %s: %w", source, err)
+		}
+		if rendered := strings.TrimSpace(buf.String()); rendered != "" {
+			sections = append(sections, rendered)
+		}
+	}
+	return strings.Join(sections, "\n\n"), nil
+}
+
+// section returns the template text of a section and where it came from
+func (b *SystemPromptBuilder) section(name string) (string, string, error) {
+	file := name + ".tmpl"
+	for _, dir := range []string{b.config.ProjectDir, b.config.UserDir} {
+		if dir == "" {
+			continue
+		}
+		path := filepath.Join(dir, file)
+		data, err := os.ReadFile(path)
+		if err == nil {
+			return string(data), path, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", "", fmt.Errorf("failed to read prompt override: %w", err)
+		}
+	}
+
+	data, err := systemTemplates.ReadFile("system/" + file)
+	if err != nil {
+		return "", "", fmt.Errorf("no template for system prompt section %s: %w", name, err)
+	}
+	return string(data), "built-in " + file, nil
+}
@@ -0,0 +1,119 @@
+package prompt
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEnvironment() SystemEnvironment {
+	return SystemEnvironment{
+		WorkingDir: "/src/project",
+		OS:         "linux",
+		Arch:       "amd64",
+		Date:       time.Date(2026, 3, 9, 12, 0, 0, 0, time.UTC),
+		Git: GitInfo{
+			Repository: true,
+			Branch:     "main",
+			Status:     []string{" M main.go"},
+			MoreStatus: 2,
+		},
+		Tools:       []ToolInfo{NewToolInfo("file_read", "Read file contents. Paths are relative.")},
+		Permissions: PermissionInfo{Allowed: []string{"FileRead(*.go)"}},
+	}
+}
+
+func TestSystemPromptBuilderDefaults(t *testing.T) {
+	built, err := NewSystemPromptBuilder(SystemPromptConfig{}).Build(testEnvironment())
+	require.NoError(t, err)
+
+	for _, want := range []string{
+		"Working directory: /src/project",
+		"Platform: linux/amd64",
+		"Date: Monday, 9 March 2026",
+		"Branch: main",
+		" M main.go",
+		"... and 2 more",
+		"- file_read: Read file contents.",
+		"- FileRead(*.go)",
+	} {
+		assert.Contains(t, built, want)
+	}
+	assert.NotContains(t, built, "Shell:", "an unknown shell is left out")
+	assert.NotContains(t, built, "\n\n\n", "empty sections leave no gaps")
+	assert.Less(t, strings.Index(built, "Environment:"), strings.Index(built, "Git:"))
+	assert.Less(t, strings.Index(built, "Git:"), strings.Index(built, "Tools available"))
+
+	env := testEnvironment()
+	env.Git = GitInfo{}
+	env.Permissions = PermissionInfo{Bypassed: true}
+	built, err = NewSystemPromptBuilder(SystemPromptConfig{}).Build(env)
+	require.NoError(t, err)
+	assert.NotContains(t, built, "Git:")
+	assert.Contains(t, built, "Permission checks are disabled")
+}
+
+func TestSystemPromptBuilderOverrides(t *testing.T) {
+	projectDir := t.TempDir()
+	userDir := t.TempDir()
+	write := func(dir, name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name+".tmpl"), []byte(content), 0644))
+	}
+	write(userDir, "identity", "You are the user's assistant.")
+	write(userDir, "instructions", "User instructions.")
+	write(projectDir, "instructions", "Project instructions for {{.WorkingDir}}.")
+	write(projectDir, "git", "")
+
+	built, err := NewSystemPromptBuilder(SystemPromptConfig{ProjectDir: projectDir, UserDir: userDir}).Build(testEnvironment())
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(built, "You are the user's assistant."), "user overrides replace defaults")
+	assert.True(t, strings.HasSuffix(built, "Project instructions for /src/project."), "project overrides win over user ones")
+	assert.NotContains(t, built, "User instructions.")
+	assert.NotContains(t, built, "Branch:", "an empty override removes the section")
+	assert.Contains(t, built, "Working directory:", "sections without overrides use the defaults")
+
+	write(projectDir, "tools", "{{.Missing}}")
+	_, err = NewSystemPromptBuilder(SystemPromptConfig{ProjectDir: projectDir}).Build(testEnvironment())
+	assert.ErrorContains(t, err, filepath.Join(projectDir, "tools.tmpl"))
+}
+
+func TestNewToolInfo(t *testing.T) {
+	info := NewToolInfo("bash", "Execute bash\n  commands. Output is captured.")
+	assert.Equal(t, "Execute bash commands.", info.Summary)
+
+	info = NewToolInfo("long", strings.Repeat("word ", 50))
+	assert.Len(t, []rune(info.Summary), maxToolSummaryLength)
+}
+
+func TestDetectGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	ctx := context.Background()
+	dir := t.TempDir()
+	assert.False(t, detectGit(ctx, dir, 10).Repository)
+
+	git := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	git("init", "-q", "-b", "trunk")
+	for _, name := range []string{"a.go", "b.go", "c.go"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("package a"), 0644))
+	}
+
+	info := detectGit(ctx, dir, 2)
+	assert.True(t, info.Repository)
+	assert.Equal(t, "trunk", info.Branch)
+	assert.Equal(t, []string{"?? a.go", "?? b.go"}, info.Status)
+	assert.Equal(t, 1, info.MoreStatus)
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"unicode/utf8"
 
+	"github.com/killallgit/ryan/pkg/logger"
 	"github.com/killallgit/ryan/pkg/vectorstore"
+	"github.com/tmc/langchaingo/llms"
 )
 
 // Augmenter handles prompt augmentation with retrieved context
@@ -27,10 +30,22 @@ type AugmenterConfig struct {
 
 	// MinRelevanceScore filters out low-relevance documents
 	MinRelevanceScore float32
+
+	// Citations numbers the passages; share it with knowledge_search so numbers
+	// stay unique within a turn. When nil each prompt is numbered from 1.
+	Citations *CitationSet
+
+	// Rewriter turns the prompt into standalone search queries using History.
+	// When nil the prompt is searched for as it is.
+	Rewriter *QueryRewriter
+
+	// History returns the conversation so far for the Rewriter
+	History func() ([]llms.ChatMessage, error)
 }
 
 // DefaultTemplate is the default prompt template
 const DefaultTemplate = `Answer the following question based on the provided context. If the context doesn't contain relevant information, say so.
+Cite the passages you use by their number in square brackets, e.g. [1].
 
 Context:
 %s
@@ -53,12 +68,45 @@ func NewAugmenter(retriever *Retriever, config AugmenterConfig) *Augmenter {
 	}
 }
 
-// AugmentPrompt augments a prompt with retrieved context
+// AugmentPrompt augments a prompt with numbered passages the model can cite
 func (a *Augmenter) AugmentPrompt(ctx context.Context, prompt string) (string, error) {
-	// Retrieve relevant documents
-	results, err := a.retriever.RetrieveWithScores(ctx, prompt)
+	result, err := a.AugmentWithDetails(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return result.AugmentedPrompt, nil
+}
+
+// queries returns what to search for to answer prompt. Rewriting only
+// sharpens the search, so when it fails the prompt is used as it is.
+func (a *Augmenter) queries(ctx context.Context, prompt string) []string {
+	if a.config.Rewriter == nil {
+		return []string{prompt}
+	}
+
+	var history []llms.ChatMessage
+	if a.config.History != nil {
+		var err error
+		if history, err = a.config.History(); err != nil {
+			logger.Warn("Rewriting the query without history: %v", err)
+		}
+	}
+
+	rewritten, err := a.config.Rewriter.Rewrite(ctx, prompt, history)
+	if err != nil {
+		logger.Warn("Searching with the prompt as written: %v", err)
+		return []string{prompt}
+	}
+	logger.Debug("Rewrote query to %q with %d more queries", rewritten.Standalone, len(rewritten.Queries())-1)
+	return rewritten.Queries()
+}
+
+// retrieve returns the results relevant enough to include and the queries that found them
+func (a *Augmenter) retrieve(ctx context.Context, prompt string) ([]vectorstore.SearchResult, []string, error) {
+	queries := a.queries(ctx, prompt)
+	results, err := a.retriever.SearchQueries(ctx, queries, 0, vectorstore.Filter{})
 	if err != nil {
-		return "", fmt.Errorf("failed to retrieve context: %w", err)
+		return nil, nil, fmt.Errorf("failed to retrieve context: %w", err)
 	}
 
 	// Filter by relevance score if configured
@@ -71,54 +119,58 @@ func (a *Augmenter) AugmentPrompt(ctx context.Context, prompt string) (string, e
 		}
 		results = filtered
 	}
-
-	// Format context
-	context := a.formatContext(results)
-
-	// Truncate if necessary
-	if len(context) > a.config.MaxContextLength {
-		context = context[:a.config.MaxContextLength] + "..."
-	}
-
-	// Apply template
-	augmented := fmt.Sprintf(a.config.Template, context, prompt)
-	return augmented, nil
+	return results, queries, nil
 }
 
-// formatContext formats retrieved documents as context
-func (a *Augmenter) formatContext(results []vectorstore.SearchResult) string {
+// formatContext numbers the passages that fit in MaxContextLength and returns
+// the context with the results and citations it includes. Passages are kept
+// whole except for a first passage that is longer than the limit on its own.
+func (a *Augmenter) formatContext(results []vectorstore.SearchResult) (string, []vectorstore.SearchResult, []Citation) {
 	if len(results) == 0 {
-		return "No relevant context found."
+		return "No relevant context found.", nil, nil
 	}
 
-	var parts []string
-	for i, result := range results {
-		content := result.Document.Content
+	citations := a.config.Citations
+	if citations == nil {
+		citations = NewCitationSet()
+	}
 
-		// Add score if configured
+	var parts []string
+	var included []vectorstore.SearchResult
+	var cited []Citation
+	used := 0
+	for _, result := range results {
+		body := fmt.Sprintf("%s (chunk %s)\n", NewCitation(0, result).Location(), result.Document.ID)
 		if a.config.IncludeScores {
-			content = fmt.Sprintf("[Relevance: %.2f]\n%s", result.Score, content)
+			body += fmt.Sprintf("[Relevance: %.2f]\n", result.Score)
 		}
+		body += result.Document.Content
 
-		// Add source metadata if available
-		if source, ok := result.Document.Metadata["source"].(string); ok {
-			content = fmt.Sprintf("[Source: %s]\n%s", source, content)
+		// The limit is in characters, matching truncate
+		if len(included) > 0 && used+utf8.RuneCountInString(body) > a.config.MaxContextLength {
+			break
 		}
+		body = truncate(body, a.config.MaxContextLength)
+		used += utf8.RuneCountInString(body)
 
-		parts = append(parts, fmt.Sprintf("%d. %s", i+1, content))
+		citation := citations.Add(result)
+		parts = append(parts, fmt.Sprintf("[%d] %s", citation.Number, body))
+		included = append(included, result)
+		cited = append(cited, citation)
 	}
 
-	return strings.Join(parts, "\n\n")
+	return strings.Join(parts, "\n\n"), included, cited
 }
 
 // GetContext retrieves and formats context without augmenting the prompt
 func (a *Augmenter) GetContext(ctx context.Context, query string) (string, error) {
-	results, err := a.retriever.RetrieveWithScores(ctx, query)
+	results, _, err := a.retrieve(ctx, query)
 	if err != nil {
-		return "", fmt.Errorf("failed to retrieve context: %w", err)
+		return "", err
 	}
 
-	return a.formatContext(results), nil
+	context, _, _ := a.formatContext(results)
+	return context, nil
 }
 
 // AugmentResult contains the result of prompt augmentation
@@ -126,6 +178,9 @@ type AugmentResult struct {
 	// OriginalPrompt is the original user prompt
 	OriginalPrompt string
 
+	// Queries are what was searched for, the standalone query first
+	Queries []string
+
 	// AugmentedPrompt is the prompt with added context
 	AugmentedPrompt string
 
@@ -137,35 +192,39 @@ type AugmentResult struct {
 
 	// Scores are the relevance scores
 	Scores []float32
+
+	// Citations describe the numbered passages in Context
+	Citations []Citation
 }
 
 // AugmentWithDetails provides detailed augmentation results
 func (a *Augmenter) AugmentWithDetails(ctx context.Context, prompt string) (*AugmentResult, error) {
-	// Retrieve relevant documents
-	results, err := a.retriever.RetrieveWithScores(ctx, prompt)
+	results, queries, err := a.retrieve(ctx, prompt)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve context: %w", err)
+		return nil, err
 	}
 
+	// Format context
+	context, included, citations := a.formatContext(results)
+
 	// Extract documents and scores
-	documents := make([]vectorstore.Document, len(results))
-	scores := make([]float32, len(results))
-	for i, result := range results {
+	documents := make([]vectorstore.Document, len(included))
+	scores := make([]float32, len(included))
+	for i, result := range included {
 		documents[i] = result.Document
 		scores[i] = result.Score
 	}
 
-	// Format context
-	context := a.formatContext(results)
-
 	// Create augmented prompt
 	augmented := fmt.Sprintf(a.config.Template, context, prompt)
 
 	return &AugmentResult{
 		OriginalPrompt:  prompt,
+		Queries:         queries,
 		AugmentedPrompt: augmented,
 		Context:         context,
 		Documents:       documents,
 		Scores:          scores,
+		Citations:       citations,
 	}, nil
 }
@@ -0,0 +1,152 @@
+package retrieval
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Chunk is a piece of a file with the lines it spans (1-based, inclusive)
+type Chunk struct {
+	Content   string
+	StartLine int
+	EndLine   int
+	Metadata  map[string]interface{} // Chunker-specific metadata such as a symbol or heading
+}
+
+// Chunker splits file content into chunks
+type Chunker interface {
+	Chunk(content string) []Chunk
+}
+
+// ChunkerFactory creates a chunker for the configured chunk size and overlap
+type ChunkerFactory func(config DocumentConfig) Chunker
+
+var (
+	chunkersMu sync.RWMutex
+	chunkers   = map[string]ChunkerFactory{
+		".go":       func(config DocumentConfig) Chunker { return NewGoChunker(config) },
+		".md":       func(config DocumentConfig) Chunker { return NewMarkdownChunker(config) },
+		".markdown": func(config DocumentConfig) Chunker { return NewMarkdownChunker(config) },
+	}
+)
+
+// RegisterChunker sets the chunker used for files with the given extension, e.g. ".py"
+func RegisterChunker(ext string, factory ChunkerFactory) {
+	chunkersMu.Lock()
+	defer chunkersMu.Unlock()
+	chunkers[strings.ToLower(ext)] = factory
+}
+
+// ChunkerFor returns the chunker for a file path, falling back to line-based chunking
+func ChunkerFor(path string, config DocumentConfig) Chunker {
+	chunkersMu.RLock()
+	factory, ok := chunkers[strings.ToLower(filepath.Ext(path))]
+	chunkersMu.RUnlock()
+
+	if ok {
+		return factory(config)
+	}
+	return NewLineChunker(config)
+}
+
+// LineChunker splits content at line boundaries into chunks of about ChunkSize
+// characters, repeating roughly ChunkOverlap characters of lines between chunks
+type LineChunker struct {
+	size    int
+	overlap int
+}
+
+// NewLineChunker creates a line-based chunker
+func NewLineChunker(config DocumentConfig) *LineChunker {
+	config = config.withDefaults()
+	return &LineChunker{size: config.ChunkSize, overlap: config.ChunkOverlap}
+}
+
+// Chunk splits content into line-aligned chunks
+func (c *LineChunker) Chunk(content string) []Chunk {
+	return splitLines(fileLines(content), 1, c.size, c.overlap, nil)
+}
+
+// fileLines splits content into lines; the newline ending the last line does not start another
+func fileLines(content string) []string {
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// splitLines chunks lines whose first line has number firstLine. Every chunk gets a copy of metadata.
+// A single line longer than size is split into several chunks with the same line number.
+func splitLines(lines []string, firstLine, size, overlap int, metadata map[string]interface{}) []Chunk {
+	var chunks []Chunk
+	add := func(content string, start, end int) {
+		if strings.TrimSpace(content) == "" {
+			return
+		}
+		chunks = append(chunks, Chunk{Content: content, StartLine: start, EndLine: end, Metadata: copyMetadata(metadata)})
+	}
+
+	for i := 0; i < len(lines); {
+		j, used := i, 0
+		for j < len(lines) {
+			length := utf8.RuneCountInString(lines[j]) + 1
+			if used+length > size && j > i {
+				break
+			}
+			used += length
+			j++
+		}
+
+		if j == i+1 && used > size+1 {
+			// One overlong line, e.g. minified code
+			runes := []rune(lines[i])
+			for start := 0; start < len(runes); start += size {
+				add(string(runes[start:min(start+size, len(runes))]), firstLine+i, firstLine+i)
+			}
+		} else {
+			add(strings.Join(lines[i:j], "\n"), firstLine+i, firstLine+j-1)
+		}
+
+		if j >= len(lines) {
+			break
+		}
+
+		// Step back over up to overlap characters of lines, always moving forward
+		next, repeated := j, 0
+		for next > i+1 {
+			length := utf8.RuneCountInString(lines[next-1]) + 1
+			if repeated+length > overlap {
+				break
+			}
+			repeated += length
+			next--
+		}
+		i = next
+	}
+	return chunks
+}
+
+// section chunks lines start..end (1-based, inclusive) as one chunk, or by lines if it is too large
+func section(lines []string, start, end, size, overlap int, metadata map[string]interface{}) []Chunk {
+	if start > end {
+		return nil
+	}
+	content := strings.Join(lines[start-1:end], "\n")
+	if strings.TrimSpace(content) == "" {
+		return nil
+	}
+	if utf8.RuneCountInString(content) <= size {
+		return []Chunk{{Content: content, StartLine: start, EndLine: end, Metadata: metadata}}
+	}
+	return splitLines(lines[start-1:end], start, size, overlap, metadata)
+}
+
+func copyMetadata(metadata map[string]interface{}) map[string]interface{} {
+	if metadata == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(metadata))
+	for k, v := range metadata {
+		copied[k] = v
+	}
+	return copied
+}
@@ -0,0 +1,204 @@
+package retrieval
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const goSource = `// Package store keeps things.
+package store
+
+import (
+	"fmt"
+)
+
+// MaxItems caps the store.
+const MaxItems = 10
+
+type (
+	// Store holds items.
+	Store[T any] struct {
+		items []T
+	}
+	Key string
+)
+
+// Get returns an item.
+// It panics on a bad index.
+func (s *Store[T]) Get(i int) T {
+	return s.items[i]
+}
+
+func New() *Store[int] {
+	fmt.Println("new")
+	return &Store[int]{}
+}
+`
+
+func TestGoChunker(t *testing.T) {
+	chunks := NewGoChunker(DocumentConfig{}).Chunk(goSource)
+	require.Len(t, chunks, 5)
+
+	header := chunks[0]
+	assert.Equal(t, 1, header.StartLine)
+	assert.Equal(t, 6, header.EndLine)
+	assert.Equal(t, "package", header.Metadata["kind"])
+	assert.Contains(t, header.Content, `"fmt"`)
+
+	constant := chunks[1]
+	assert.Equal(t, "const", constant.Metadata["kind"])
+	assert.Equal(t, "MaxItems", constant.Metadata["symbol"])
+	assert.Equal(t, "MaxItems caps the store.", constant.Metadata["doc"])
+	assert.Equal(t, 8, constant.StartLine, "chunks start at their doc comment")
+
+	types := chunks[2]
+	assert.Equal(t, "type", types.Metadata["kind"])
+	assert.Equal(t, "Store, Key", types.Metadata["symbol"])
+
+	method := chunks[3]
+	assert.Equal(t, "method", method.Metadata["kind"])
+	assert.Equal(t, "Get", method.Metadata["symbol"])
+	assert.Equal(t, "Store", method.Metadata["receiver"])
+	assert.Equal(t, "store", method.Metadata["package"])
+	assert.Equal(t, "Get returns an item.\nIt panics on a bad index.", method.Metadata["doc"])
+	assert.Equal(t, 19, method.StartLine)
+	assert.Equal(t, 23, method.EndLine)
+	assert.True(t, strings.HasPrefix(method.Content, "// Get returns an item."))
+	assert.True(t, strings.HasSuffix(method.Content, "}"))
+
+	function := chunks[4]
+	assert.Equal(t, "function", function.Metadata["kind"])
+	assert.Equal(t, "New", function.Metadata["symbol"])
+	assert.NotContains(t, function.Metadata, "doc")
+	assert.NotContains(t, function.Metadata, "receiver")
+}
+
+func TestGoChunkerSplitsLargeDeclarations(t *testing.T) {
+	var body strings.Builder
+	body.WriteString("package big\n\nfunc Big() {\n")
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&body, "\tprintln(%d)\n", i)
+	}
+	body.WriteString("}\n")
+
+	chunks := NewGoChunker(DocumentConfig{ChunkSize: 200, ChunkOverlap: 20}).Chunk(body.String())
+	require.Greater(t, len(chunks), 3)
+	for _, chunk := range chunks[1:] {
+		assert.Equal(t, "Big", chunk.Metadata["symbol"])
+		assert.LessOrEqual(t, len(chunk.Content), 200)
+	}
+	assert.Equal(t, 3, chunks[1].StartLine)
+	assert.Equal(t, 54, chunks[len(chunks)-1].EndLine)
+}
+
+func TestGoChunkerFallsBackOnSyntaxErrors(t *testing.T) {
+	chunks := NewGoChunker(DocumentConfig{}).Chunk("package broken\n\nfunc {")
+	require.Len(t, chunks, 1)
+	assert.Equal(t, "go", chunks[0].Metadata["language"])
+	assert.NotContains(t, chunks[0].Metadata, "kind")
+}
+
+func TestMarkdownChunker(t *testing.T) {
+	doc := strings.Join([]string{
+		"Intro text.",
+		"",
+		"# Guide",
+		"## Install",
+		"Run the installer.",
+		"```sh",
+		"# not a heading",
+		"```",
+		"### Linux ###",
+		"Use the package.",
+		"## Usage",
+		"Start it.",
+	}, "\n")
+
+	chunks := NewMarkdownChunker(DocumentConfig{}).Chunk(doc)
+	require.Len(t, chunks, 4)
+
+	assert.Equal(t, "Intro text.\n", chunks[0].Content)
+	assert.NotContains(t, chunks[0].Metadata, "heading")
+
+	// "# Guide" has no body of its own, so it stays with "## Install"
+	assert.Equal(t, 3, chunks[1].StartLine)
+	assert.Equal(t, 8, chunks[1].EndLine)
+	assert.Equal(t, "Install", chunks[1].Metadata["heading"])
+	assert.Equal(t, "Guide > Install", chunks[1].Metadata["section"])
+	assert.Contains(t, chunks[1].Content, "# not a heading")
+
+	assert.Equal(t, "Linux", chunks[2].Metadata["heading"])
+	assert.Equal(t, "Guide > Install > Linux", chunks[2].Metadata["section"])
+
+	assert.Equal(t, "Guide > Usage", chunks[3].Metadata["section"])
+	assert.Equal(t, 12, chunks[3].EndLine)
+}
+
+func TestLineChunker(t *testing.T) {
+	lines := make([]string, 30)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %02d", i+1) // 8 characters with the newline
+	}
+
+	chunks := NewLineChunker(DocumentConfig{ChunkSize: 90, ChunkOverlap: 18}).Chunk(strings.Join(lines, "\n"))
+	require.Len(t, chunks, 4)
+	assert.Equal(t, 1, chunks[0].StartLine)
+	assert.Equal(t, 11, chunks[0].EndLine)
+	assert.Equal(t, 10, chunks[1].StartLine, "two lines of overlap")
+	assert.Equal(t, 30, chunks[len(chunks)-1].EndLine)
+
+	// An overlong line is split rather than kept whole
+	chunks = NewLineChunker(DocumentConfig{ChunkSize: 10, ChunkOverlap: 2}).Chunk(strings.Repeat("x", 25))
+	require.Len(t, chunks, 3)
+	assert.Equal(t, 1, chunks[2].StartLine)
+}
+
+func TestChunkerFor(t *testing.T) {
+	assert.IsType(t, &GoChunker{}, ChunkerFor("pkg/a.go", DocumentConfig{}))
+	assert.IsType(t, &MarkdownChunker{}, ChunkerFor("README.MD", DocumentConfig{}))
+	assert.IsType(t, &LineChunker{}, ChunkerFor("main.py", DocumentConfig{}))
+
+	RegisterChunker(".py", func(config DocumentConfig) Chunker { return NewMarkdownChunker(config) })
+	defer func() {
+		chunkersMu.Lock()
+		delete(chunkers, ".py")
+		chunkersMu.Unlock()
+	}()
+	assert.IsType(t, &MarkdownChunker{}, ChunkerFor("main.py", DocumentConfig{}))
+}
+
+func TestChunkFileMetadata(t *testing.T) {
+	manager := NewDocumentManager(DocumentConfig{IDPrefix: "p"})
+	docs := manager.ChunkFile("store.go", goSource, map[string]interface{}{"source": "store.go"})
+	require.Len(t, docs, 5)
+
+	method := docs[3]
+	assert.Equal(t, "store.go", method.Metadata["source"])
+	assert.Equal(t, 19, method.Metadata["start_line"])
+	assert.Equal(t, 23, method.Metadata["end_line"])
+	assert.Equal(t, "Get", method.Metadata["symbol"])
+	assert.Equal(t, 3, method.Metadata["chunk_index"])
+	assert.Equal(t, 5, method.Metadata["total_chunks"])
+	assert.True(t, strings.HasPrefix(method.ID, "p_"))
+	assert.True(t, strings.HasSuffix(method.ID, "_chunk_3"))
+}
+
+func TestSplitTextMultibyte(t *testing.T) {
+	manager := NewDocumentManager(DocumentConfig{ChunkSize: 20, ChunkOverlap: 5})
+
+	// The boundary sits in the first half when counted in runes but not in bytes,
+	// so it must not cut the chunk short
+	text := "éééééé. Ünïcödé téxt gœs hérè and keeps going."
+	chunks := manager.ChunkText(text)
+	require.NotEmpty(t, chunks)
+	assert.Equal(t, "éééééé. Ünïcödé téxt", chunks[0])
+	assert.Len(t, chunks, 3)
+	for _, chunk := range chunks {
+		assert.LessOrEqual(t, len([]rune(chunk)), 20)
+		assert.True(t, strings.ToValidUTF8(chunk, "?") == chunk, "chunks are valid UTF-8")
+	}
+}
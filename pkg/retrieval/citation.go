@@ -0,0 +1,139 @@
+package retrieval
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/killallgit/ryan/pkg/vectorstore"
+)
+
+// maxExcerptLength caps the passage text kept with a citation
+const maxExcerptLength = 500
+
+// citationPattern matches citation markers such as [2] in a response
+var citationPattern = regexp.MustCompile(`\[(\d+)\]`)
+
+// Citation identifies a retrieved passage the model was given and may cite as [Number]
+type Citation struct {
+	Number    int     `json:"number"`
+	Source    string  `json:"source,omitempty"`
+	StartLine int     `json:"start_line,omitempty"`
+	EndLine   int     `json:"end_line,omitempty"`
+	ChunkID   string  `json:"chunk_id"`
+	Score     float32 `json:"score"`
+	Excerpt   string  `json:"excerpt,omitempty"`
+	Cited     bool    `json:"cited"` // The response refers to this passage
+}
+
+// NewCitation describes a search result as citation number n
+func NewCitation(n int, result vectorstore.SearchResult) Citation {
+	metadata := result.Document.Metadata
+	citation := Citation{
+		Number:  n,
+		ChunkID: result.Document.ID,
+		Score:   result.Score,
+		Excerpt: truncate(result.Document.Content, maxExcerptLength),
+	}
+	if source, ok := metadata["source"]; ok {
+		citation.Source = fmt.Sprint(source)
+	}
+	citation.StartLine = metadataInt(metadata, "start_line")
+	citation.EndLine = metadataInt(metadata, "end_line")
+	return citation
+}
+
+// Location returns where the passage comes from, e.g. "pkg/agent/state.go:10-42"
+func (c Citation) Location() string {
+	switch {
+	case c.Source == "":
+		return c.ChunkID
+	case c.StartLine > 0 && c.EndLine > c.StartLine:
+		return fmt.Sprintf("%s:%d-%d", c.Source, c.StartLine, c.EndLine)
+	case c.StartLine > 0:
+		return fmt.Sprintf("%s:%d", c.Source, c.StartLine)
+	default:
+		return c.Source
+	}
+}
+
+// CitationSet numbers the passages shown to the model during one turn, so the
+// augmenter and knowledge_search never hand out the same number twice. The
+// same chunk keeps its number when it is retrieved again. A nil set is empty.
+type CitationSet struct {
+	citations []Citation
+	byChunk   map[string]int
+	mu        sync.Mutex
+}
+
+// NewCitationSet creates an empty citation set
+func NewCitationSet() *CitationSet {
+	return &CitationSet{byChunk: make(map[string]int)}
+}
+
+// Add numbers a search result, returning the existing citation if its chunk was already added
+func (s *CitationSet) Add(result vectorstore.SearchResult) Citation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i, ok := s.byChunk[result.Document.ID]; ok {
+		return s.citations[i]
+	}
+	citation := NewCitation(len(s.citations)+1, result)
+	s.byChunk[result.Document.ID] = len(s.citations)
+	s.citations = append(s.citations, citation)
+	return citation
+}
+
+// List returns a copy of the citations in number order
+func (s *CitationSet) List() []Citation {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Citation(nil), s.citations...)
+}
+
+// Reset forgets every citation, starting the numbering again at 1
+func (s *CitationSet) Reset() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.citations = nil
+	s.byChunk = make(map[string]int)
+}
+
+// MarkCited returns a copy of citations with Cited set for each [n] marker in response
+func MarkCited(citations []Citation, response string) []Citation {
+	cited := make(map[int]bool)
+	for _, match := range citationPattern.FindAllStringSubmatch(response, -1) {
+		n, _ := strconv.Atoi(match[1])
+		cited[n] = true
+	}
+
+	marked := make([]Citation, len(citations))
+	for i, citation := range citations {
+		citation.Cited = cited[citation.Number]
+		marked[i] = citation
+	}
+	return marked
+}
+
+// metadataInt reads a numeric metadata value, which stores may keep as a string
+func metadataInt(metadata map[string]interface{}, key string) int {
+	switch v := metadata[key].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	case string:
+		n, _ := strconv.Atoi(v)
+		return n
+	default:
+		return 0
+	}
+}
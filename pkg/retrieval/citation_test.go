@@ -0,0 +1,94 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/killallgit/ryan/pkg/vectorstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCitationLocation(t *testing.T) {
+	result := func(metadata map[string]interface{}) vectorstore.SearchResult {
+		return vectorstore.SearchResult{Document: vectorstore.Document{ID: "chunk-1", Metadata: metadata}}
+	}
+
+	assert.Equal(t, "pkg/a.go:10-42", NewCitation(1, result(map[string]interface{}{"source": "pkg/a.go", "start_line": "10", "end_line": 42})).Location())
+	assert.Equal(t, "pkg/a.go:7", NewCitation(1, result(map[string]interface{}{"source": "pkg/a.go", "start_line": 7, "end_line": 7})).Location())
+	assert.Equal(t, "notes.md", NewCitation(1, result(map[string]interface{}{"source": "notes.md"})).Location())
+	assert.Equal(t, "chunk-1", NewCitation(1, result(nil)).Location())
+}
+
+func TestCitationSet(t *testing.T) {
+	set := NewCitationSet()
+	a := set.Add(vectorstore.SearchResult{Document: vectorstore.Document{ID: "a", Content: strings.Repeat("x", 600)}})
+	b := set.Add(vectorstore.SearchResult{Document: vectorstore.Document{ID: "b"}})
+	again := set.Add(vectorstore.SearchResult{Document: vectorstore.Document{ID: "a"}})
+
+	assert.Equal(t, 1, a.Number)
+	assert.Equal(t, 2, b.Number)
+	assert.Equal(t, 1, again.Number, "a chunk keeps its number")
+	assert.Len(t, set.List(), 2)
+	assert.Len(t, []rune(a.Excerpt), maxExcerptLength+3)
+
+	marked := MarkCited(set.List(), "See [2] and [7].")
+	assert.False(t, marked[0].Cited)
+	assert.True(t, marked[1].Cited)
+	assert.False(t, set.List()[1].Cited, "marking does not change the set")
+
+	set.Reset()
+	assert.Empty(t, set.List())
+	assert.Equal(t, 1, set.Add(vectorstore.SearchResult{Document: vectorstore.Document{ID: "b"}}).Number)
+
+	var none *CitationSet
+	assert.Nil(t, none.List())
+	none.Reset()
+}
+
+func TestAugmenterCitations(t *testing.T) {
+	retriever := newTestRetriever(t)
+	citations := NewCitationSet()
+	augmenter := NewAugmenter(retriever, AugmenterConfig{Citations: citations})
+	ctx := context.Background()
+
+	result, err := augmenter.AugmentWithDetails(ctx, "where are sessions kept")
+	require.NoError(t, err)
+	require.Len(t, result.Citations, 2)
+	assert.Contains(t, result.AugmentedPrompt, "Cite the passages you use")
+	for _, citation := range result.Citations {
+		assert.Contains(t, result.Context, fmt.Sprintf("[%d] %s", citation.Number, citation.Source))
+		assert.Contains(t, result.Context, "(chunk "+citation.ChunkID+")")
+	}
+
+	// knowledge_search continues the numbering of the turn
+	tool := NewKnowledgeSearchToolWithCitations(retriever, citations)
+	output, err := tool.Call(ctx, `{"query": "install", "k": 3}`)
+	require.NoError(t, err)
+	assert.Contains(t, output, "[3] score")
+	assert.Len(t, citations.List(), 3)
+}
+
+func TestAugmenterContextLimit(t *testing.T) {
+	augmenter := NewAugmenter(newTestRetriever(t), AugmenterConfig{MaxContextLength: 60})
+
+	result, err := augmenter.AugmentWithDetails(context.Background(), "sessions")
+	require.NoError(t, err)
+	require.Len(t, result.Citations, 1, "passages that do not fit are left out whole")
+	assert.Len(t, result.Documents, 1)
+	assert.True(t, strings.HasPrefix(result.Context, "[1] "))
+}
+
+func TestAugmenterContextLimitCountsCharacters(t *testing.T) {
+	augmenter := NewAugmenter(newTestRetriever(t), AugmenterConfig{MaxContextLength: 200})
+	results := []vectorstore.SearchResult{
+		{Document: vectorstore.Document{ID: "a", Content: strings.Repeat("é", 60)}},
+		{Document: vectorstore.Document{ID: "b", Content: strings.Repeat("ü", 60)}},
+	}
+
+	// Each passage is about 80 characters but 140 bytes
+	_, included, _ := augmenter.formatContext(results)
+	assert.Len(t, included, 2)
+}
@@ -27,15 +27,20 @@ type DocumentConfig struct {
 
 // NewDocumentManager creates a new document manager
 func NewDocumentManager(config DocumentConfig) *DocumentManager {
-	if config.ChunkSize == 0 {
-		config.ChunkSize = 1000
+	return &DocumentManager{
+		config: config.withDefaults(),
 	}
-	if config.ChunkOverlap == 0 {
-		config.ChunkOverlap = 200
+}
+
+// withDefaults fills in the default chunk size and overlap
+func (c DocumentConfig) withDefaults() DocumentConfig {
+	if c.ChunkSize == 0 {
+		c.ChunkSize = 1000
 	}
-	return &DocumentManager{
-		config: config,
+	if c.ChunkOverlap == 0 {
+		c.ChunkOverlap = 200
 	}
+	return c
 }
 
 // CreateDocument creates a document from content
@@ -77,57 +82,90 @@ func (m *DocumentManager) ChunkDocument(doc vectorstore.Document) []vectorstore.
 	return result
 }
 
+// ChunkFile splits a file with the chunker for its type (see ChunkerFor). Each
+// chunk gets the given metadata plus start_line, end_line and the chunker's own keys.
+func (m *DocumentManager) ChunkFile(path, content string, metadata map[string]interface{}) []vectorstore.Document {
+	parent := m.CreateDocument(content, metadata)
+	chunks := ChunkerFor(path, m.config).Chunk(content)
+
+	result := make([]vectorstore.Document, len(chunks))
+	for i, chunk := range chunks {
+		chunkMetadata := make(map[string]interface{})
+		for k, v := range metadata {
+			chunkMetadata[k] = v
+		}
+		for k, v := range chunk.Metadata {
+			chunkMetadata[k] = v
+		}
+		chunkMetadata["start_line"] = chunk.StartLine
+		chunkMetadata["end_line"] = chunk.EndLine
+		chunkMetadata["chunk_index"] = i
+		chunkMetadata["total_chunks"] = len(chunks)
+		chunkMetadata["parent_id"] = parent.ID
+
+		result[i] = vectorstore.Document{
+			ID:       fmt.Sprintf("%s_chunk_%d", parent.ID, i),
+			Content:  chunk.Content,
+			Metadata: chunkMetadata,
+		}
+	}
+
+	return result
+}
+
 // ChunkText splits text into chunks
 func (m *DocumentManager) ChunkText(text string) []string {
 	return m.splitText(text, m.config.ChunkSize, m.config.ChunkOverlap)
 }
 
-// splitText splits text into overlapping chunks
+// splitText splits text into overlapping chunks of at most chunkSize runes,
+// preferring to end a chunk at a sentence boundary
 func (m *DocumentManager) splitText(text string, chunkSize, overlap int) []string {
-	if len(text) <= chunkSize {
+	runes := []rune(text)
+	if len(runes) <= chunkSize {
 		return []string{text}
 	}
 
 	var chunks []string
-	runes := []rune(text)
-
 	for start := 0; start < len(runes); {
 		end := start + chunkSize
 		if end > len(runes) {
 			end = len(runes)
 		}
 
-		// Try to break at sentence boundary
-		chunk := string(runes[start:end])
+		// Try to break at a sentence boundary in the second half of the chunk
 		if end < len(runes) {
-			// Look for sentence ending
-			lastPeriod := strings.LastIndex(chunk, ". ")
-			lastQuestion := strings.LastIndex(chunk, "? ")
-			lastExclaim := strings.LastIndex(chunk, "! ")
-
-			// Find the last sentence boundary
-			lastBoundary := max(lastPeriod, lastQuestion, lastExclaim)
-			if lastBoundary > 0 && lastBoundary > chunkSize/2 {
-				chunk = chunk[:lastBoundary+2]
-				end = start + len([]rune(chunk))
+			if boundary := lastSentenceEnd(runes[start:end]); boundary > chunkSize/2 {
+				end = start + boundary
 			}
 		}
 
-		chunks = append(chunks, strings.TrimSpace(chunk))
+		chunks = append(chunks, strings.TrimSpace(string(runes[start:end])))
 
 		// Move forward with overlap
 		if end >= len(runes) {
 			break
 		}
-		start = end - overlap
-		if start < 0 {
-			start = 0
+		next := end - overlap
+		if next <= start {
+			next = end
 		}
+		start = next
 	}
 
 	return chunks
 }
 
+// lastSentenceEnd returns the rune index just after the last ". ", "? " or "! " in text, or -1
+func lastSentenceEnd(text []rune) int {
+	for i := len(text) - 2; i >= 0; i-- {
+		if text[i+1] == ' ' && (text[i] == '.' || text[i] == '?' || text[i] == '!') {
+			return i + 2
+		}
+	}
+	return -1
+}
+
 // generateID generates a unique ID for content
 func (m *DocumentManager) generateID(content string) string {
 	h := sha256.New()
@@ -135,17 +173,6 @@ func (m *DocumentManager) generateID(content string) string {
 	return fmt.Sprintf("%x", h.Sum(nil))[:16]
 }
 
-// max returns the maximum of three integers
-func max(a, b, c int) int {
-	if a >= b && a >= c {
-		return a
-	}
-	if b >= a && b >= c {
-		return b
-	}
-	return c
-}
-
 // DocumentLoader provides utilities for loading documents
 type DocumentLoader struct {
 	manager *DocumentManager
@@ -0,0 +1,119 @@
+package retrieval
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// GoChunker splits Go source at top-level declarations. Each function, method,
+// type, const and var block becomes a chunk with its doc comment, and the
+// package clause and imports form a header chunk. Files that do not parse are
+// chunked by lines.
+type GoChunker struct {
+	size    int
+	overlap int
+}
+
+// NewGoChunker creates a declaration-aware chunker for Go source
+func NewGoChunker(config DocumentConfig) *GoChunker {
+	config = config.withDefaults()
+	return &GoChunker{size: config.ChunkSize, overlap: config.ChunkOverlap}
+}
+
+// Chunk splits Go source into declaration chunks
+func (c *GoChunker) Chunk(content string) []Chunk {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.ParseComments)
+	if err != nil {
+		return splitLines(fileLines(content), 1, c.size, c.overlap, map[string]interface{}{"language": "go"})
+	}
+
+	lines := fileLines(content)
+	pkg := file.Name.Name
+	line := func(pos token.Pos) int { return fset.Position(pos).Line }
+	meta := func(kind string) map[string]interface{} {
+		return map[string]interface{}{"language": "go", "package": pkg, "kind": kind}
+	}
+
+	// The header runs from the top of the file through the package clause and imports
+	headerEnd := line(file.Name.End())
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			headerEnd = line(gen.End())
+		}
+	}
+	chunks := section(lines, 1, headerEnd, c.size, c.overlap, meta("package"))
+
+	for _, decl := range file.Decls {
+		var metadata map[string]interface{}
+		var doc *ast.CommentGroup
+
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			doc = d.Doc
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				metadata = meta("method")
+				metadata["receiver"] = receiverType(d.Recv.List[0].Type)
+			} else {
+				metadata = meta("function")
+			}
+			metadata["symbol"] = d.Name.Name
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+			doc = d.Doc
+			metadata = meta(d.Tok.String())
+			metadata["symbol"] = strings.Join(specNames(d), ", ")
+		default:
+			continue
+		}
+
+		start := line(decl.Pos())
+		if doc != nil {
+			start = line(doc.Pos())
+			metadata["doc"] = strings.TrimSpace(doc.Text())
+		}
+		chunks = append(chunks, section(lines, start, line(decl.End()), c.size, c.overlap, metadata)...)
+	}
+
+	return chunks
+}
+
+// receiverType returns the type name of a method receiver without pointers or type parameters
+func receiverType(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// specNames lists the names declared by a type, const or var declaration
+func specNames(decl *ast.GenDecl) []string {
+	var names []string
+	for _, spec := range decl.Specs {
+		switch s := spec.(type) {
+		case *ast.TypeSpec:
+			names = append(names, s.Name.Name)
+		case *ast.ValueSpec:
+			for _, name := range s.Names {
+				names = append(names, name.Name)
+			}
+		}
+	}
+	return names
+}
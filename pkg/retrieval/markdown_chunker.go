@@ -0,0 +1,87 @@
+package retrieval
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	markdownHeading = regexp.MustCompile(`^ {0,3}(#{1,6})\s+(.*?)(\s+#+)?\s*$`)
+	markdownFence   = regexp.MustCompile("^ {0,3}(```|~~~)")
+)
+
+// MarkdownChunker splits markdown into sections at headings. Each chunk records
+// its heading and the path of enclosing headings. Headings inside fenced code
+// blocks are ignored, and a heading directly followed by a subheading is kept
+// with it rather than becoming a chunk of its own.
+type MarkdownChunker struct {
+	size    int
+	overlap int
+}
+
+// NewMarkdownChunker creates a heading-aware chunker for markdown
+func NewMarkdownChunker(config DocumentConfig) *MarkdownChunker {
+	config = config.withDefaults()
+	return &MarkdownChunker{size: config.ChunkSize, overlap: config.ChunkOverlap}
+}
+
+// Chunk splits markdown into heading sections
+func (c *MarkdownChunker) Chunk(content string) []Chunk {
+	lines := fileLines(content)
+
+	var chunks []Chunk
+	var path []string // Enclosing headings, indexed by level-1
+	start, hasBody := 1, false
+	metadata := map[string]interface{}{"language": "markdown"}
+
+	flush := func(end int) {
+		chunks = append(chunks, section(lines, start, end, c.size, c.overlap, metadata)...)
+	}
+
+	inFence := false
+	for i, line := range lines {
+		if markdownFence.MatchString(line) {
+			inFence = !inFence
+		}
+		match := markdownHeading.FindStringSubmatch(line)
+		if inFence || match == nil {
+			if strings.TrimSpace(line) != "" {
+				hasBody = true
+			}
+			continue
+		}
+
+		// Keep a heading without body together with the heading that follows it
+		if hasBody {
+			flush(i)
+			start = i + 1
+		}
+		hasBody = false
+
+		level := len(match[1])
+		for len(path) < level {
+			path = append(path, "")
+		}
+		path = append(path[:level-1], match[2])
+
+		metadata = map[string]interface{}{
+			"language": "markdown",
+			"heading":  match[2],
+			"section":  joinHeadings(path),
+		}
+	}
+	flush(len(lines))
+
+	return chunks
+}
+
+// joinHeadings joins the non-empty headings of a path with " > "
+func joinHeadings(path []string) string {
+	parts := make([]string, 0, len(path))
+	for _, heading := range path {
+		if heading != "" {
+			parts = append(parts, heading)
+		}
+	}
+	return strings.Join(parts, " > ")
+}
@@ -0,0 +1,100 @@
+package retrieval
+
+import (
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/killallgit/ryan/pkg/vectorstore"
+)
+
+// defaultMMRLambda favours relevance while still pushing out near-duplicates
+const defaultMMRLambda = 0.7
+
+// selectMMR picks up to k results by maximal marginal relevance. Each pick
+// maximises lambda*relevance - (1-lambda)*similarity to the closest earlier
+// pick, so overlapping chunks of the same passage do not crowd out the rest.
+// Relevance is the result score; similarity uses the stored vectors and falls
+// back to word overlap for results without one, such as keyword-only matches.
+func selectMMR(results []vectorstore.SearchResult, k int, lambda float64) []vectorstore.SearchResult {
+	if k <= 0 || len(results) == 0 {
+		return nil
+	}
+
+	words := make([]map[string]bool, len(results))
+	similarity := func(i, j int) float64 {
+		a, b := results[i].Document, results[j].Document
+		if len(a.Vector) > 0 && len(a.Vector) == len(b.Vector) {
+			return cosine(a.Vector, b.Vector)
+		}
+		if words[i] == nil {
+			words[i] = wordSet(a.Content)
+		}
+		if words[j] == nil {
+			words[j] = wordSet(b.Content)
+		}
+		return jaccard(words[i], words[j])
+	}
+
+	// closest[i] is the highest similarity of candidate i to anything selected so far
+	closest := make([]float64, len(results))
+	used := make([]bool, len(results))
+	selected := make([]vectorstore.SearchResult, 0, k)
+	for len(selected) < k && len(selected) < len(results) {
+		best, bestValue := -1, math.Inf(-1)
+		for i, result := range results {
+			if used[i] {
+				continue
+			}
+			value := lambda*float64(result.Score) - (1-lambda)*closest[i]
+			if value > bestValue {
+				best, bestValue = i, value
+			}
+		}
+
+		used[best] = true
+		selected = append(selected, results[best])
+		for i := range results {
+			if !used[i] {
+				closest[i] = math.Max(closest[i], similarity(i, best))
+			}
+		}
+	}
+	return selected
+}
+
+func cosine(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+func wordSet(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}) {
+		set[word] = true
+	}
+	return set
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for word := range a {
+		if b[word] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
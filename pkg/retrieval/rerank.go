@@ -0,0 +1,105 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/killallgit/ryan/pkg/vectorstore"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// Reranker reorders retrieved passages by how well they answer a query
+type Reranker interface {
+	Rerank(ctx context.Context, query string, results []vectorstore.SearchResult) ([]vectorstore.SearchResult, error)
+}
+
+// maxRerankPassageLength caps how much of each passage the reranking model reads
+const maxRerankPassageLength = 1500
+
+// rerankScorePattern matches reply lines such as "3: 7" or "[3] = 7.5"
+var rerankScorePattern = regexp.MustCompile(`(?m)^\s*\[?(\d+)\]?\s*[:=-]\s*(\d+(?:\.\d+)?)`)
+
+// LLMReranker asks a language model to score every passage against the query in one call
+type LLMReranker struct {
+	llm llms.Model
+}
+
+// NewLLMReranker creates a reranker backed by llm
+func NewLLMReranker(llm llms.Model) *LLMReranker {
+	return &LLMReranker{llm: llm}
+}
+
+// Rerank orders results by the model's relevance scores, scaled to 0-1.
+// Passages the model did not score keep their order after the scored ones.
+func (r *LLMReranker) Rerank(ctx context.Context, query string, results []vectorstore.SearchResult) ([]vectorstore.SearchResult, error) {
+	if len(results) < 2 {
+		return results, nil
+	}
+
+	reply, err := llms.GenerateFromSinglePrompt(ctx, r.llm, rerankPrompt(query, results), llms.WithTemperature(0))
+	if err != nil {
+		return nil, fmt.Errorf("reranking failed: %w", err)
+	}
+
+	scores := make(map[int]float32)
+	for _, match := range rerankScorePattern.FindAllStringSubmatch(reply, -1) {
+		n, _ := strconv.Atoi(match[1])
+		score, _ := strconv.ParseFloat(match[2], 32)
+		if n >= 1 && n <= len(results) {
+			scores[n-1] = float32(min(score, 10) / 10)
+		}
+	}
+	if len(scores) == 0 {
+		return nil, fmt.Errorf("reranking reply had no scores: %q", truncate(reply, 200))
+	}
+
+	reranked := make([]vectorstore.SearchResult, len(results))
+	copy(reranked, results)
+	scored := make([]bool, len(results))
+	for i := range reranked {
+		score, ok := scores[i]
+		scored[i] = ok
+		reranked[i].Score = score
+		reranked[i].Distance = 1 - score
+	}
+
+	order := make([]int, len(results))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		if scored[order[a]] != scored[order[b]] {
+			return scored[order[a]]
+		}
+		return reranked[order[a]].Score > reranked[order[b]].Score
+	})
+
+	sorted := make([]vectorstore.SearchResult, len(order))
+	for i, idx := range order {
+		sorted[i] = reranked[idx]
+	}
+	return sorted, nil
+}
+
+func rerankPrompt(query string, results []vectorstore.SearchResult) string {
+	var b strings.Builder
+	b.WriteString("Rate how well each passage helps answer the query, from 0 (irrelevant) to 10 (answers it directly).\n")
+	b.WriteString(`Reply with one line per passage in the form "<passage number>: <score>" and nothing else.` + "\n\n")
+	fmt.Fprintf(&b, "Query: %s\n", query)
+	for i, result := range results {
+		fmt.Fprintf(&b, "\n[%d]\n%s\n", i+1, truncate(result.Document.Content, maxRerankPassageLength))
+	}
+	return b.String()
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n]) + "..."
+	}
+	return s
+}
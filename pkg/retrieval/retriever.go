@@ -3,8 +3,10 @@ package retrieval
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 
+	"github.com/killallgit/ryan/pkg/logger"
 	"github.com/killallgit/ryan/pkg/vectorstore"
 	"github.com/tmc/langchaingo/schema"
 )
@@ -20,7 +22,9 @@ type Config struct {
 	// MaxDocuments is the maximum number of documents to retrieve
 	MaxDocuments int
 
-	// ScoreThreshold is the minimum similarity score required
+	// ScoreThreshold is the minimum similarity score required. In hybrid search
+	// it applies to the fused score, which is 1 for a document ranked first by
+	// both the embedding and keyword searches.
 	ScoreThreshold float32
 
 	// MaxContextLength is the maximum context length for augmentation
@@ -28,13 +32,41 @@ type Config struct {
 
 	// IncludeMetadata determines if metadata should be included
 	IncludeMetadata bool
+
+	// VectorWeight and KeywordWeight weigh the embedding and keyword rankings
+	// when the store also supports keyword search; both default to 1
+	VectorWeight  float64
+	KeywordWeight float64
+
+	// MMR picks the final results by maximal marginal relevance so near-duplicate
+	// chunks do not crowd out other passages; MMRLambda (default 0.7) trades
+	// relevance (1) against diversity (0)
+	MMR       bool
+	MMRLambda float64
+
+	// Reranker, when set, reorders the candidates before MMR selects the results
+	Reranker Reranker
 }
 
+// rrfK damps the lead of top-ranked documents in reciprocal rank fusion
+const rrfK = 60
+
+// candidateFactor is how many more candidates than k are gathered when fusion,
+// reranking or MMR choose among them
+const candidateFactor = 3
+
 // NewRetriever creates a new retriever
 func NewRetriever(store vectorstore.VectorStore, config Config) *Retriever {
 	if config.MaxDocuments == 0 {
 		config.MaxDocuments = 4
 	}
+	if config.VectorWeight == 0 && config.KeywordWeight == 0 {
+		config.VectorWeight = 1
+		config.KeywordWeight = 1
+	}
+	if config.MMRLambda == 0 {
+		config.MMRLambda = defaultMMRLambda
+	}
 	return &Retriever{
 		vectorStore: store,
 		config:      config,
@@ -43,18 +75,9 @@ func NewRetriever(store vectorstore.VectorStore, config Config) *Retriever {
 
 // Retrieve retrieves relevant documents for a query
 func (r *Retriever) Retrieve(ctx context.Context, query string) ([]vectorstore.Document, error) {
-	if r.vectorStore == nil {
-		return nil, fmt.Errorf("vector store not initialized")
-	}
-
-	results, err := r.vectorStore.SimilaritySearchWithScore(
-		ctx,
-		query,
-		r.config.MaxDocuments,
-		r.config.ScoreThreshold,
-	)
+	results, err := r.Search(ctx, query, 0, vectorstore.Filter{})
 	if err != nil {
-		return nil, fmt.Errorf("similarity search failed: %w", err)
+		return nil, err
 	}
 
 	documents := make([]vectorstore.Document, len(results))
@@ -67,16 +90,146 @@ func (r *Retriever) Retrieve(ctx context.Context, query string) ([]vectorstore.D
 
 // RetrieveWithScores retrieves documents with their similarity scores
 func (r *Retriever) RetrieveWithScores(ctx context.Context, query string) ([]vectorstore.SearchResult, error) {
+	return r.Search(ctx, query, 0, vectorstore.Filter{})
+}
+
+// Search returns up to k documents for a query whose metadata matches filter.
+// A k of zero uses the configured MaxDocuments. When the store also supports
+// keyword search, the embedding and keyword rankings are fused so exact
+// identifiers and conceptual queries both find their documents. The candidates
+// then pass through the optional reranking and MMR stages.
+func (r *Retriever) Search(ctx context.Context, query string, k int, filter vectorstore.Filter) ([]vectorstore.SearchResult, error) {
+	return r.SearchQueries(ctx, []string{query}, k, filter)
+}
+
+// SearchQueries is Search over several phrasings of one question, such as those
+// from a QueryRewriter. The candidates of every query are fused by rank before
+// reranking, which scores them against the first query.
+func (r *Retriever) SearchQueries(ctx context.Context, queries []string, k int, filter vectorstore.Filter) ([]vectorstore.SearchResult, error) {
 	if r.vectorStore == nil {
 		return nil, fmt.Errorf("vector store not initialized")
 	}
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("no query to search for")
+	}
+	if k <= 0 {
+		k = r.config.MaxDocuments
+	}
+
+	keyword, hybrid := r.vectorStore.(vectorstore.KeywordSearcher)
+	hybrid = hybrid && r.config.KeywordWeight > 0
+
+	pool := k
+	if hybrid || r.config.MMR || r.config.Reranker != nil || len(queries) > 1 {
+		pool = k * candidateFactor
+	}
+
+	rankings := make([]ranking, 0, len(queries))
+	for _, query := range queries {
+		var results []vectorstore.SearchResult
+		var err error
+		if hybrid {
+			results, err = r.hybridSearch(ctx, keyword, query, pool, filter)
+		} else {
+			results, err = r.vectorStore.SimilaritySearchWithFilter(ctx, query, pool, r.config.ScoreThreshold, filter)
+			if err != nil {
+				err = fmt.Errorf("similarity search failed: %w", err)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		rankings = append(rankings, ranking{results: results, weight: 1})
+	}
 
-	return r.vectorStore.SimilaritySearchWithScore(
-		ctx,
-		query,
-		r.config.MaxDocuments,
-		r.config.ScoreThreshold,
-	)
+	results := rankings[0].results
+	if len(rankings) > 1 {
+		results = fuseRankings(pool, rankings)
+	}
+
+	if r.config.Reranker != nil && len(results) > 1 {
+		reranked, err := r.config.Reranker.Rerank(ctx, queries[0], results)
+		if err != nil {
+			// Reranking only refines the order, so fall back to the retrieval ranking
+			logger.Warn("Keeping retrieval order: %v", err)
+		} else {
+			results = reranked
+		}
+	}
+
+	if r.config.MMR {
+		return selectMMR(results, k, r.config.MMRLambda), nil
+	}
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results, nil
+}
+
+// hybridSearch fuses the embedding and keyword rankings into up to k results
+// scoring at least the threshold
+func (r *Retriever) hybridSearch(ctx context.Context, keyword vectorstore.KeywordSearcher, query string, k int, filter vectorstore.Filter) ([]vectorstore.SearchResult, error) {
+	var vectorResults []vectorstore.SearchResult
+	if r.config.VectorWeight > 0 {
+		var err error
+		vectorResults, err = r.vectorStore.SimilaritySearchWithFilter(ctx, query, k, 0, filter)
+		if err != nil {
+			return nil, fmt.Errorf("similarity search failed: %w", err)
+		}
+	}
+	keywordResults, err := keyword.KeywordSearch(ctx, query, k, filter)
+	if err != nil {
+		return nil, fmt.Errorf("keyword search failed: %w", err)
+	}
+
+	fused := fuseRankings(k, []ranking{
+		{results: vectorResults, weight: r.config.VectorWeight},
+		{results: keywordResults, weight: r.config.KeywordWeight},
+	})
+	// Fused results are sorted by score, so cut at the first one below the threshold
+	for i, result := range fused {
+		if result.Score < r.config.ScoreThreshold {
+			return fused[:i], nil
+		}
+	}
+	return fused, nil
+}
+
+// ranking is one ordered result list taking part in a fusion
+type ranking struct {
+	results []vectorstore.SearchResult
+	weight  float64
+}
+
+// fuseRankings merges rankings with weighted reciprocal rank fusion and returns
+// the top k. Scores are scaled so a document ranked first everywhere scores 1.
+func fuseRankings(k int, rankings []ranking) []vectorstore.SearchResult {
+	scores := make(map[string]float64)
+	var fused []vectorstore.SearchResult
+	var maxScore float64
+	for _, rk := range rankings {
+		maxScore += rk.weight / (rrfK + 1)
+		for rank, result := range rk.results {
+			id := result.Document.ID
+			if _, ok := scores[id]; !ok {
+				fused = append(fused, result)
+			}
+			scores[id] += rk.weight / float64(rrfK+rank+1)
+		}
+	}
+
+	sort.SliceStable(fused, func(i, j int) bool {
+		return scores[fused[i].Document.ID] > scores[fused[j].Document.ID]
+	})
+	if len(fused) > k {
+		fused = fused[:k]
+	}
+	for i := range fused {
+		score := float32(scores[fused[i].Document.ID] / maxScore)
+		fused[i].Score = score
+		fused[i].Distance = 1 - score
+	}
+	return fused
 }
 
 // FormatDocuments formats retrieved documents into a context string
@@ -0,0 +1,203 @@
+package retrieval
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/killallgit/ryan/pkg/embeddings"
+	"github.com/killallgit/ryan/pkg/vectorstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func ranked(ids ...string) []vectorstore.SearchResult {
+	out := make([]vectorstore.SearchResult, len(ids))
+	for i, id := range ids {
+		out[i] = vectorstore.SearchResult{Document: vectorstore.Document{ID: id}}
+	}
+	return out
+}
+
+func resultIDs(results []vectorstore.SearchResult) []string {
+	out := make([]string, len(results))
+	for i, result := range results {
+		out[i] = result.Document.ID
+	}
+	return out
+}
+
+func TestFuseRankings(t *testing.T) {
+	fused := fuseRankings(3, []ranking{
+		{results: ranked("a", "b", "c"), weight: 1},
+		{results: ranked("c", "d"), weight: 1},
+	})
+	assert.Equal(t, []string{"c", "a", "b"}, resultIDs(fused), "found by both rankings beats first in one")
+	assert.Less(t, fused[0].Score, float32(1))
+
+	fused = fuseRankings(2, []ranking{
+		{results: ranked("a", "b"), weight: 1},
+		{results: ranked("b", "a"), weight: 3},
+	})
+	assert.Equal(t, []string{"b", "a"}, resultIDs(fused), "the heavier ranking wins ties of rank")
+
+	fused = fuseRankings(5, []ranking{
+		{results: ranked("a"), weight: 1},
+		{results: ranked("a"), weight: 1},
+	})
+	require.Len(t, fused, 1)
+	assert.InDelta(t, 1, fused[0].Score, 1e-6)
+	assert.InDelta(t, 0, fused[0].Distance, 1e-6)
+}
+
+func TestRetrieverHybridSearch(t *testing.T) {
+	chromemStore, err := vectorstore.NewChromemStore(vectorstore.ChromemConfig{Embedder: embeddings.NewMockEmbedder(64)})
+	require.NoError(t, err)
+	store, err := vectorstore.NewKeywordStore(chromemStore, "")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, store.AddDocuments(ctx, []vectorstore.Document{
+		{ID: "bypass", Content: "func NewPermissionManagerWithBypass(bypass bool) *PermissionManager", Metadata: map[string]interface{}{"source": "pkg/tools/permissions.go"}},
+		{ID: "session", Content: "Sessions are stored in SQLite", Metadata: map[string]interface{}{"source": "docs/memory.md"}},
+		{ID: "install", Content: "Install with go install", Metadata: map[string]interface{}{"source": "README.md"}},
+	}))
+
+	// Keyword ranking alone finds the exact identifier
+	retriever := NewRetriever(store, Config{MaxDocuments: 1, KeywordWeight: 1})
+	found, err := retriever.Search(ctx, "NewPermissionManagerWithBypass", 0, vectorstore.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bypass"}, resultIDs(found))
+
+	// Fused results include the keyword match and are limited to k
+	retriever = NewRetriever(store, Config{MaxDocuments: 2})
+	found, err = retriever.Search(ctx, "NewPermissionManagerWithBypass", 0, vectorstore.Filter{})
+	require.NoError(t, err)
+	assert.Len(t, found, 2)
+	assert.Contains(t, resultIDs(found), "bypass")
+
+	found, err = retriever.Search(ctx, "stored sessions", 3, vectorstore.Filter{}.WithPathPrefix("source", "docs"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"session"}, resultIDs(found), "both rankings honour the filter")
+
+	docs, err := retriever.Retrieve(ctx, "install")
+	require.NoError(t, err)
+	assert.Len(t, docs, 2)
+	// The threshold applies to the fused score, so keyword matches do not bypass it
+	retriever = NewRetriever(store, Config{MaxDocuments: 3, ScoreThreshold: 0.9})
+	found, err = retriever.Search(ctx, "NewPermissionManagerWithBypass", 0, vectorstore.Filter{})
+	require.NoError(t, err)
+	for _, result := range found {
+		assert.GreaterOrEqual(t, result.Score, float32(0.9))
+	}
+
+	retriever = NewRetriever(store, Config{MaxDocuments: 3, ScoreThreshold: 1.01})
+	found, err = retriever.Search(ctx, "NewPermissionManagerWithBypass", 0, vectorstore.Filter{})
+	require.NoError(t, err)
+	assert.Empty(t, found)
+}
+
+func TestSelectMMR(t *testing.T) {
+	candidates := []vectorstore.SearchResult{
+		{Document: vectorstore.Document{ID: "a", Vector: []float32{1, 0}}, Score: 0.9},
+		{Document: vectorstore.Document{ID: "a-overlap", Vector: []float32{0.99, 0.1}}, Score: 0.88},
+		{Document: vectorstore.Document{ID: "b", Vector: []float32{0, 1}}, Score: 0.7},
+	}
+
+	assert.Equal(t, []string{"a", "b"}, resultIDs(selectMMR(candidates, 2, defaultMMRLambda)),
+		"the overlapping chunk gives way to a different passage")
+	assert.Equal(t, []string{"a", "a-overlap"}, resultIDs(selectMMR(candidates, 2, 1)),
+		"lambda 1 ranks by relevance alone")
+	assert.Len(t, selectMMR(candidates, 10, defaultMMRLambda), 3)
+
+	// Results without vectors are compared by their words
+	keywordOnly := []vectorstore.SearchResult{
+		{Document: vectorstore.Document{ID: "x", Content: "sessions are stored in sqlite"}, Score: 0.9},
+		{Document: vectorstore.Document{ID: "x-overlap", Content: "sessions are stored in sqlite tables"}, Score: 0.85},
+		{Document: vectorstore.Document{ID: "y", Content: "install with go install"}, Score: 0.6},
+	}
+	assert.Equal(t, []string{"x", "y"}, resultIDs(selectMMR(keywordOnly, 2, defaultMMRLambda)))
+}
+
+// fakeLLM replies with a fixed text and records the prompt
+type fakeLLM struct {
+	reply  string
+	err    error
+	prompt string
+}
+
+func (f *fakeLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	for _, part := range messages[len(messages)-1].Parts {
+		if text, ok := part.(llms.TextContent); ok {
+			f.prompt = text.Text
+		}
+	}
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: f.reply}}}, nil
+}
+
+func (f *fakeLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, f, prompt, options...)
+}
+
+func TestLLMReranker(t *testing.T) {
+	candidates := []vectorstore.SearchResult{
+		{Document: vectorstore.Document{ID: "a", Content: "install with go install"}, Score: 0.9},
+		{Document: vectorstore.Document{ID: "b", Content: "sessions are stored in sqlite"}, Score: 0.8},
+		{Document: vectorstore.Document{ID: "c", Content: "unrelated"}, Score: 0.7},
+	}
+	ctx := context.Background()
+
+	llm := &fakeLLM{reply: "1: 2\n[2] = 9.5\n"}
+	reranked, err := NewLLMReranker(llm).Rerank(ctx, "where are sessions kept", candidates)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "a", "c"}, resultIDs(reranked), "unscored passages go last")
+	assert.InDelta(t, 0.95, reranked[0].Score, 1e-6)
+	assert.Contains(t, llm.prompt, "Query: where are sessions kept")
+	assert.Contains(t, llm.prompt, "[3]\nunrelated")
+	assert.Equal(t, "a", candidates[0].Document.ID, "the input is not reordered")
+
+	_, err = NewLLMReranker(&fakeLLM{reply: "I cannot rate these"}).Rerank(ctx, "q", candidates)
+	assert.ErrorContains(t, err, "no scores")
+}
+
+func TestRetrieverRerankAndMMR(t *testing.T) {
+	store, err := vectorstore.NewChromemStore(vectorstore.ChromemConfig{Embedder: embeddings.NewMockEmbedder(64)})
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, store.AddDocuments(ctx, []vectorstore.Document{
+		{ID: "a", Content: "alpha"},
+		{ID: "b", Content: "beta"},
+		{ID: "c", Content: "gamma"},
+	}))
+
+	// The reranker sees every candidate and decides the order
+	retriever := NewRetriever(store, Config{MaxDocuments: 1, Reranker: rerankerFunc(func(results []vectorstore.SearchResult) []vectorstore.SearchResult {
+		assert.Len(t, results, 3)
+		for _, result := range results {
+			if result.Document.ID == "c" {
+				return []vectorstore.SearchResult{result}
+			}
+		}
+		return nil
+	})})
+	found, err := retriever.Search(ctx, "query", 0, vectorstore.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c"}, resultIDs(found))
+
+	// A failing reranker falls back to the retrieval order
+	retriever = NewRetriever(store, Config{MaxDocuments: 2, MMR: true, Reranker: NewLLMReranker(&fakeLLM{err: errors.New("offline")})})
+	found, err = retriever.Search(ctx, "query", 0, vectorstore.Filter{})
+	require.NoError(t, err)
+	assert.Len(t, found, 2)
+}
+
+// rerankerFunc adapts a function to the Reranker interface
+type rerankerFunc func([]vectorstore.SearchResult) []vectorstore.SearchResult
+
+func (f rerankerFunc) Rerank(ctx context.Context, query string, results []vectorstore.SearchResult) ([]vectorstore.SearchResult, error) {
+	return f(results), nil
+}
@@ -0,0 +1,141 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// Defaults for query rewriting
+const (
+	defaultRewriteHistory   = 6
+	maxRewriteMessageLength = 500
+	maxAlternativeQueries   = 5
+	maxHypotheticalLength   = 1000
+)
+
+// rewriteLinePattern matches the QUERY and ALTERNATIVE lines of a rewrite reply
+var rewriteLinePattern = regexp.MustCompile(`(?m)^\s*(QUERY|ALTERNATIVE)\s*:\s*(.+?)\s*$`)
+
+// RewriteConfig selects the query transformations
+type RewriteConfig struct {
+	// HyDE also searches with a hypothetical passage answering the query,
+	// which tends to sit closer to real answers than the question does
+	HyDE bool
+
+	// MultiQuery is how many alternative phrasings to search with as well (0 for none)
+	MultiQuery int
+
+	// History is how many recent messages are given to the model (default 6)
+	History int
+}
+
+// RewrittenQuery holds the search queries derived from one turn
+type RewrittenQuery struct {
+	Standalone   string   // The turn as a query that makes sense without the conversation
+	Alternatives []string // Other phrasings of the standalone query
+	Hypothetical string   // A passage that would answer the query
+}
+
+// Queries returns every query to search with, the standalone one first
+func (q RewrittenQuery) Queries() []string {
+	queries := append([]string{q.Standalone}, q.Alternatives...)
+	if q.Hypothetical != "" {
+		queries = append(queries, q.Hypothetical)
+	}
+	return queries
+}
+
+// QueryRewriter turns a conversational turn into search queries with a language model
+type QueryRewriter struct {
+	llm    llms.Model
+	config RewriteConfig
+}
+
+// NewQueryRewriter creates a query rewriter backed by llm
+func NewQueryRewriter(llm llms.Model, config RewriteConfig) *QueryRewriter {
+	if config.History <= 0 {
+		config.History = defaultRewriteHistory
+	}
+	config.MultiQuery = min(max(config.MultiQuery, 0), maxAlternativeQueries)
+	return &QueryRewriter{llm: llm, config: config}
+}
+
+// Rewrite resolves references in prompt to the recent history, such as "it"
+// in "and how is it tested?", and adds the configured extra queries. A first
+// turn with no extra queries configured is returned as it is without a model call.
+func (r *QueryRewriter) Rewrite(ctx context.Context, prompt string, history []llms.ChatMessage) (RewrittenQuery, error) {
+	prompt = strings.TrimSpace(prompt)
+	if len(history) == 0 && !r.config.HyDE && r.config.MultiQuery == 0 {
+		return RewrittenQuery{Standalone: prompt}, nil
+	}
+
+	reply, err := llms.GenerateFromSinglePrompt(ctx, r.llm, r.prompt(prompt, history), llms.WithTemperature(0))
+	if err != nil {
+		return RewrittenQuery{}, fmt.Errorf("query rewriting failed: %w", err)
+	}
+
+	// The hypothetical passage may run over several lines, so it is cut off first
+	var rewritten RewrittenQuery
+	if i := strings.Index(reply, "HYPOTHETICAL:"); i >= 0 {
+		if r.config.HyDE {
+			rewritten.Hypothetical = truncate(strings.TrimSpace(reply[i+len("HYPOTHETICAL:"):]), maxHypotheticalLength)
+		}
+		reply = reply[:i]
+	}
+	for _, match := range rewriteLinePattern.FindAllStringSubmatch(reply, -1) {
+		switch {
+		case match[1] == "QUERY" && rewritten.Standalone == "":
+			rewritten.Standalone = match[2]
+		case match[1] == "ALTERNATIVE" && len(rewritten.Alternatives) < r.config.MultiQuery:
+			rewritten.Alternatives = append(rewritten.Alternatives, match[2])
+		}
+	}
+	if rewritten.Standalone == "" {
+		return RewrittenQuery{}, fmt.Errorf("query rewriting reply had no query: %q", truncate(reply, 200))
+	}
+	return rewritten, nil
+}
+
+func (r *QueryRewriter) prompt(prompt string, history []llms.ChatMessage) string {
+	var b strings.Builder
+	b.WriteString("Turn the latest message of a conversation into search queries for an index of code and documentation.\n\n")
+
+	if len(history) > r.config.History {
+		history = history[len(history)-r.config.History:]
+	}
+	if len(history) > 0 {
+		b.WriteString("Conversation:\n")
+		for _, message := range history {
+			role := "Assistant"
+			if message.GetType() == llms.ChatMessageTypeHuman {
+				role = "User"
+			}
+			fmt.Fprintf(&b, "%s: %s\n", role, tail(strings.TrimSpace(message.GetContent()), maxRewriteMessageLength))
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "Latest message: %s\n\n", prompt)
+
+	b.WriteString("Reply with these lines and nothing else:\n")
+	b.WriteString(`QUERY: <the latest message as a standalone search query, replacing words such as "it" or "that" with what they refer to>` + "\n")
+	for i := 0; i < r.config.MultiQuery; i++ {
+		b.WriteString("ALTERNATIVE: <the query worded differently>\n")
+	}
+	if r.config.HyDE {
+		b.WriteString("HYPOTHETICAL: <a short passage answering the query, as it might appear in the code or documentation>\n")
+	}
+	return b.String()
+}
+
+// tail keeps the last n runes of s. Prompts stored in history may carry
+// retrieved context in front, so the question is at their end.
+func tail(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return "..." + string(runes[len(runes)-n:])
+	}
+	return s
+}
@@ -0,0 +1,86 @@
+package retrieval
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/killallgit/ryan/pkg/vectorstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+)
+
+var testHistory = []llms.ChatMessage{
+	llms.HumanChatMessage{Content: strings.Repeat("retrieved context ", 100) + "Question: where are sessions kept?"},
+	llms.AIChatMessage{Content: "Sessions are kept in SQLite."},
+}
+
+func TestQueryRewriter(t *testing.T) {
+	ctx := context.Background()
+
+	// A first turn needs no rewriting
+	llm := &fakeLLM{err: errors.New("not called")}
+	rewritten, err := NewQueryRewriter(llm, RewriteConfig{}).Rewrite(ctx, " how do I install it? ", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"how do I install it?"}, rewritten.Queries())
+
+	llm = &fakeLLM{reply: "QUERY: how are SQLite sessions tested\nALTERNATIVE: session store tests\nALTERNATIVE: memory_test.go\nHYPOTHETICAL: func TestMemory(t *testing.T) {\n\tmem := New(\"s\")\n}"}
+	rewritten, err = NewQueryRewriter(llm, RewriteConfig{HyDE: true, MultiQuery: 1}).Rewrite(ctx, "and how is it tested?", testHistory)
+	require.NoError(t, err)
+	assert.Equal(t, "how are SQLite sessions tested", rewritten.Standalone)
+	assert.Equal(t, []string{"session store tests"}, rewritten.Alternatives, "only the configured number of alternatives")
+	assert.Equal(t, "func TestMemory(t *testing.T) {\n\tmem := New(\"s\")\n}", rewritten.Hypothetical)
+	assert.Len(t, rewritten.Queries(), 3)
+
+	assert.Contains(t, llm.prompt, "User: ...")
+	assert.Contains(t, llm.prompt, "Question: where are sessions kept?", "long messages keep their end")
+	assert.Contains(t, llm.prompt, "Assistant: Sessions are kept in SQLite.")
+	assert.Contains(t, llm.prompt, "Latest message: and how is it tested?")
+	assert.Equal(t, 1, strings.Count(llm.prompt, "ALTERNATIVE:"))
+	assert.Contains(t, llm.prompt, "HYPOTHETICAL:")
+
+	// Without HyDE a hypothetical passage in the reply is ignored
+	rewritten, err = NewQueryRewriter(llm, RewriteConfig{}).Rewrite(ctx, "and how is it tested?", testHistory)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"how are SQLite sessions tested"}, rewritten.Queries())
+
+	_, err = NewQueryRewriter(&fakeLLM{reply: "Sure! Here you go."}, RewriteConfig{}).Rewrite(ctx, "and it?", testHistory)
+	assert.ErrorContains(t, err, "no query")
+}
+
+func TestRetrieverSearchQueries(t *testing.T) {
+	retriever := newTestRetriever(t)
+	ctx := context.Background()
+
+	found, err := retriever.SearchQueries(ctx, []string{"Sessions are stored in SQLite", "Install with go install"}, 2, vectorstore.Filter{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "c"}, resultIDs(found), "each query contributes its best match")
+
+	_, err = retriever.SearchQueries(ctx, nil, 2, vectorstore.Filter{})
+	assert.Error(t, err)
+}
+
+func TestAugmenterRewritesFollowUps(t *testing.T) {
+	llm := &fakeLLM{reply: "QUERY: Install with go install"}
+	augmenter := NewAugmenter(newTestRetriever(t), AugmenterConfig{
+		Rewriter: NewQueryRewriter(llm, RewriteConfig{}),
+		History:  func() ([]llms.ChatMessage, error) { return testHistory, nil },
+	})
+	ctx := context.Background()
+
+	result, err := augmenter.AugmentWithDetails(ctx, "and how do I install it?")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Install with go install"}, result.Queries)
+	require.NotEmpty(t, result.Documents)
+	assert.Equal(t, "c", result.Documents[0].ID)
+	assert.Contains(t, result.AugmentedPrompt, "Question: and how do I install it?", "the prompt itself is not rewritten")
+	assert.Contains(t, llm.prompt, "Assistant: Sessions are kept in SQLite.")
+
+	// A failed rewrite searches with the prompt as written
+	llm.err = errors.New("offline")
+	result, err = augmenter.AugmentWithDetails(ctx, "and how do I install it?")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"and how do I install it?"}, result.Queries)
+}
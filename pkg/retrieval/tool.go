@@ -0,0 +1,146 @@
+package retrieval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/killallgit/ryan/pkg/vectorstore"
+)
+
+// maxSearchResults caps the k a model may ask knowledge_search for
+const maxSearchResults = 20
+
+// KnowledgeSearchTool lets the agent query the vector store when it needs indexed knowledge
+type KnowledgeSearchTool struct {
+	retriever *Retriever
+	citations *CitationSet
+}
+
+// NewKnowledgeSearchTool creates a knowledge_search tool backed by the retriever
+func NewKnowledgeSearchTool(retriever *Retriever) *KnowledgeSearchTool {
+	return &KnowledgeSearchTool{retriever: retriever}
+}
+
+// NewKnowledgeSearchToolWithCitations creates a knowledge_search tool that numbers
+// its results from citations, so the model can cite them as [n]
+func NewKnowledgeSearchToolWithCitations(retriever *Retriever, citations *CitationSet) *KnowledgeSearchTool {
+	return &KnowledgeSearchTool{retriever: retriever, citations: citations}
+}
+
+// Name returns the tool name
+func (t *KnowledgeSearchTool) Name() string {
+	return "knowledge_search"
+}
+
+// Description returns the tool description
+func (t *KnowledgeSearchTool) Description() string {
+	return "Search indexed documents and code in the knowledge base by meaning. Use it when the answer may be in " +
+		"project files or notes you have not seen. " +
+		`Input: JSON {"query": "how are sessions stored", "k": 4, "path": "pkg/memory", "filter": {"language": ["go", "markdown"]}} ` +
+		"where everything but query is optional, path limits results to files under a directory, " +
+		"and filter matches metadata such as language, kind or symbol exactly (a list matches any of its values). " +
+		"Cite results you use by their number, e.g. [2]"
+}
+
+// InputSchema returns the JSON schema of the tool arguments
+func (t *KnowledgeSearchTool) InputSchema() json.RawMessage {
+	return json.RawMessage(fmt.Sprintf(`{"type":"object","properties":{`+
+		`"query":{"type":"string","description":"What to search for"},`+
+		`"k":{"type":"integer","minimum":1,"maximum":%d,"description":"Number of results"},`+
+		`"path":{"type":"string","description":"Only search files under this path"},`+
+		`"filter":{"type":"object","description":"Metadata values the results must have; a list matches any of its values",`+
+		`"additionalProperties":{"type":["string","number","boolean","array"]}}},"required":["query"]}`,
+		maxSearchResults))
+}
+
+// Call searches the knowledge base and formats the matching passages
+func (t *KnowledgeSearchTool) Call(ctx context.Context, input string) (string, error) {
+	var args struct {
+		Query  string                 `json:"query"`
+		K      int                    `json:"k"`
+		Path   string                 `json:"path"`
+		Filter map[string]interface{} `json:"filter"`
+	}
+
+	trimmed := strings.TrimSpace(input)
+	if strings.HasPrefix(trimmed, "{") {
+		if err := json.Unmarshal([]byte(trimmed), &args); err != nil {
+			return "", fmt.Errorf("invalid JSON input: %w", err)
+		}
+	} else {
+		// Accept a bare query
+		args.Query = trimmed
+	}
+
+	if args.Query == "" {
+		return "", fmt.Errorf("query is required")
+	}
+	if args.K > maxSearchResults {
+		args.K = maxSearchResults
+	}
+
+	filter := vectorstore.FilterFromMap(args.Filter)
+	if args.Path != "" {
+		filter = filter.WithPathPrefix("source", SourcePath(args.Path))
+	}
+
+	results, err := t.retriever.Search(ctx, args.Query, args.K, filter)
+	if err != nil {
+		return "", err
+	}
+	if len(results) == 0 {
+		return "No matching documents found.", nil
+	}
+
+	parts := make([]string, len(results))
+	for i, result := range results {
+		n := i + 1
+		if t.citations != nil {
+			n = t.citations.Add(result).Number
+		}
+		header := fmt.Sprintf("[%d] score %.2f", n, result.Score)
+		if meta := formatMetadata(result.Document.Metadata); meta != "" {
+			header += " " + meta
+		}
+		parts[i] = header + "\n" + result.Document.Content
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
+
+// SourcePath returns path in the form indexed chunks record as their source:
+// relative to the working directory when it is inside it
+func SourcePath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// formatMetadata renders metadata as sorted key=value pairs
+func formatMetadata(metadata map[string]interface{}) string {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = fmt.Sprintf("%s=%v", key, metadata[key])
+	}
+	return strings.Join(pairs, " ")
+}
@@ -0,0 +1,99 @@
+package retrieval
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/killallgit/ryan/pkg/embeddings"
+	"github.com/killallgit/ryan/pkg/vectorstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRetriever(t *testing.T) *Retriever {
+	t.Helper()
+	store, err := vectorstore.NewChromemStore(vectorstore.ChromemConfig{
+		CollectionName: "knowledge",
+		Embedder:       embeddings.NewMockEmbedder(64),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+
+	require.NoError(t, store.AddDocuments(context.Background(), []vectorstore.Document{
+		{ID: "a", Content: "Sessions are stored in SQLite", Metadata: map[string]interface{}{"source": "docs/memory.md", "kind": "doc"}},
+		{ID: "b", Content: "func NewMemory() creates a session store", Metadata: map[string]interface{}{"source": "pkg/memory/memory.go", "kind": "code"}},
+		{ID: "c", Content: "Install with go install", Metadata: map[string]interface{}{"source": "README.md", "kind": "doc"}},
+	}))
+
+	return NewRetriever(store, Config{MaxDocuments: 2})
+}
+
+func TestRetrieverSearch(t *testing.T) {
+	retriever := newTestRetriever(t)
+	ctx := context.Background()
+
+	results, err := retriever.Search(ctx, "sessions", 0, vectorstore.Filter{})
+	require.NoError(t, err)
+	assert.Len(t, results, 2, "k defaults to MaxDocuments")
+
+	// k larger than the collection returns everything instead of failing
+	results, err = retriever.Search(ctx, "sessions", 10, vectorstore.Filter{})
+	require.NoError(t, err)
+	assert.Len(t, results, 3)
+
+	results, err = retriever.Search(ctx, "sessions", 5, vectorstore.Filter{}.WithEquals("kind", "code"))
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "b", results[0].Document.ID)
+}
+
+func TestKnowledgeSearchTool(t *testing.T) {
+	tool := NewKnowledgeSearchTool(newTestRetriever(t))
+	ctx := context.Background()
+
+	assert.Equal(t, "knowledge_search", tool.Name())
+	assert.Contains(t, string(tool.InputSchema()), `"required":["query"]`)
+
+	output, err := tool.Call(ctx, `{"query": "where are sessions kept", "k": 3, "filter": {"kind": "doc"}}`)
+	require.NoError(t, err)
+	assert.Contains(t, output, "[1] score")
+	assert.Contains(t, output, "[2] score")
+	assert.NotContains(t, output, "[3]")
+	assert.Contains(t, output, "source=README.md")
+	assert.NotContains(t, output, "pkg/memory/memory.go")
+
+	// A bare string is treated as the query
+	output, err = tool.Call(ctx, "install")
+	require.NoError(t, err)
+	assert.Contains(t, output, "[2] score")
+
+	output, err = tool.Call(ctx, `{"query": "sessions", "k": 3, "path": "pkg/memory/"}`)
+	require.NoError(t, err)
+	assert.Contains(t, output, "source=pkg/memory/memory.go")
+	assert.NotContains(t, output, "[2]")
+
+	// Absolute paths are matched in the relative form sources are indexed with
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	input, err := json.Marshal(map[string]interface{}{"query": "sessions", "k": 3, "path": filepath.Join(wd, "pkg", "memory")})
+	require.NoError(t, err)
+	output, err = tool.Call(ctx, string(input))
+	require.NoError(t, err)
+	assert.Contains(t, output, "source=pkg/memory/memory.go")
+	assert.NotContains(t, output, "[2]")
+
+	output, err = tool.Call(ctx, `{"query": "sessions", "k": 3, "filter": {"source": ["README.md", "docs/memory.md"]}}`)
+	require.NoError(t, err)
+	assert.Contains(t, output, "[2]")
+	assert.NotContains(t, output, "pkg/memory")
+
+	output, err = tool.Call(ctx, `{"query": "x", "filter": {"kind": "image"}}`)
+	require.NoError(t, err)
+	assert.Equal(t, "No matching documents found.", output)
+
+	_, err = tool.Call(ctx, `{"k": 2}`)
+	assert.ErrorContains(t, err, "query is required")
+}
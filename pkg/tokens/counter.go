@@ -32,8 +32,12 @@ func NewTokenCounter(modelName string) (*TokenCounter, error) {
 	}, nil
 }
 
-// CountTokens counts the number of tokens in the given text
+// CountTokens counts the number of tokens in the given text. A nil counter estimates.
 func (tc *TokenCounter) CountTokens(text string) int {
+	if tc == nil {
+		return estimateTokens(text)
+	}
+
 	tc.mu.RLock()
 	defer tc.mu.RUnlock()
 
@@ -73,6 +73,7 @@ func getDefaultPermissions() []string {
 		"Git(branch:*)",
 		"Git(show:*)",
 		"Ripgrep(*)",
+		"RipgrepPath(*)",
 	}
 }
 
@@ -149,3 +150,13 @@ func matchesPattern(toolName, input, pattern string) bool {
 		return input == matcher
 	}
 }
+
+// AllowedPatterns returns the Tool(pattern) entries operations are checked against
+func (pm *PermissionManager) AllowedPatterns() []string {
+	return append([]string(nil), pm.allowedPatterns...)
+}
+
+// Bypassed reports whether permission checks are skipped
+func (pm *PermissionManager) Bypassed() bool {
+	return pm.bypassEnabled
+}
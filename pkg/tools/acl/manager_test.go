@@ -137,3 +137,13 @@ func TestDefaultPermissions(t *testing.T) {
 	// Ensure defaults are reasonable (at least 5 patterns)
 	assert.GreaterOrEqual(t, len(defaults), 5)
 }
+
+func TestAllowedPatternsIsACopy(t *testing.T) {
+	pm := &PermissionManager{allowedPatterns: []string{"Git(status:*)"}}
+
+	patterns := pm.AllowedPatterns()
+	patterns[0] = "Bash(*)"
+	assert.Equal(t, []string{"Git(status:*)"}, pm.AllowedPatterns())
+	assert.False(t, pm.Bypassed())
+	assert.True(t, NewPermissionManagerWithBypass(true).Bypassed())
+}
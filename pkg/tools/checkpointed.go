@@ -0,0 +1,20 @@
+package tools
+
+import "github.com/killallgit/ryan/pkg/checkpoint"
+
+// CheckpointRecorder is implemented by tools that modify files. The agent gives
+// them its session's checkpoint store so the changes of a turn can be rewound.
+type CheckpointRecorder interface {
+	SetCheckpoints(store *checkpoint.Store)
+}
+
+// checkpointed holds the checkpoint store of a file-modifying tool; without
+// one nothing is recorded
+type checkpointed struct {
+	checkpoints *checkpoint.Store
+}
+
+// SetCheckpoints sets the store pre-images are recorded in
+func (c *checkpointed) SetCheckpoints(store *checkpoint.Store) {
+	c.checkpoints = store
+}
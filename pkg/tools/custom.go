@@ -0,0 +1,404 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/killallgit/ryan/pkg/config"
+	"github.com/killallgit/ryan/pkg/logger"
+	"github.com/killallgit/ryan/pkg/tools/registry"
+	"github.com/tmc/langchaingo/tools"
+)
+
+// defaultCustomToolTimeout applies when a custom tool does not set a timeout
+const defaultCustomToolTimeout = 60 * time.Second
+
+var customToolName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+// customToolFuncs are available in command templates. String arguments are
+// already shell-quoted when printed; raw gives the unquoted value for use in
+// other functions (its result is not safe to print on its own) and quote
+// shell-quotes any value.
+var customToolFuncs = template.FuncMap{
+	"quote": func(v interface{}) string {
+		return shellQuote(rawArg(v))
+	},
+	"raw": rawArg,
+}
+
+// CustomTool runs a command template declared in settings.yaml
+type CustomTool struct {
+	*SecuredTool
+	spec     config.CustomTool
+	command  *template.Template
+	timeout  time.Duration
+	required []string
+}
+
+// NewCustomTool creates a custom tool from its declaration
+func NewCustomTool(spec config.CustomTool) (*CustomTool, error) {
+	return NewCustomToolWithBypass(spec, false)
+}
+
+// NewCustomToolWithBypass creates a custom tool with optional permission bypass
+func NewCustomToolWithBypass(spec config.CustomTool, bypass bool) (*CustomTool, error) {
+	if !customToolName.MatchString(spec.Name) {
+		return nil, fmt.Errorf("invalid custom tool name %q (use letters, digits, _ and -)", spec.Name)
+	}
+	if strings.TrimSpace(spec.Command) == "" {
+		return nil, fmt.Errorf("custom tool %s has no command", spec.Name)
+	}
+
+	var required []string
+	seen := make(map[string]bool)
+	for _, arg := range spec.Args {
+		if !customToolName.MatchString(arg.Name) {
+			return nil, fmt.Errorf("custom tool %s has invalid argument name %q", spec.Name, arg.Name)
+		}
+		if seen[arg.Name] {
+			return nil, fmt.Errorf("custom tool %s declares argument %s twice", spec.Name, arg.Name)
+		}
+		seen[arg.Name] = true
+
+		switch arg.Type {
+		case "", "string", "integer", "number", "boolean":
+		default:
+			return nil, fmt.Errorf("custom tool %s argument %s has unsupported type %q", spec.Name, arg.Name, arg.Type)
+		}
+		if arg.Required {
+			required = append(required, arg.Name)
+		}
+	}
+
+	if action := quotedAction(spec.Command); action != "" {
+		return nil, fmt.Errorf("custom tool %s uses %s inside shell quotes; arguments are shell-quoted already, so use it as a bare word", spec.Name, action)
+	}
+
+	command, err := template.New(spec.Name).Option("missingkey=error").Funcs(customToolFuncs).Parse(spec.Command)
+	if err != nil {
+		return nil, fmt.Errorf("custom tool %s has invalid command template: %w", spec.Name, err)
+	}
+
+	timeout := defaultCustomToolTimeout
+	if spec.Timeout > 0 {
+		timeout = time.Duration(spec.Timeout) * time.Second
+	}
+
+	return &CustomTool{
+		SecuredTool: NewSecuredToolWithBypass(bypass),
+		spec:        spec,
+		command:     command,
+		timeout:     timeout,
+		required:    required,
+	}, nil
+}
+
+// Name returns the tool name
+func (t *CustomTool) Name() string {
+	return t.spec.Name
+}
+
+// Description returns the declared description followed by the arguments
+func (t *CustomTool) Description() string {
+	description := strings.TrimSpace(t.spec.Description)
+	if description == "" {
+		description = fmt.Sprintf("Run: %s", t.spec.Command)
+	}
+	if len(t.spec.Args) == 0 {
+		return description + " Input: {}"
+	}
+
+	args := make([]string, 0, len(t.spec.Args))
+	for _, arg := range t.spec.Args {
+		detail := fmt.Sprintf(`"%s": %s`, arg.Name, argType(arg))
+		if arg.Required {
+			detail += " (required)"
+		}
+		if arg.Description != "" {
+			detail += " - " + arg.Description
+		}
+		args = append(args, detail)
+	}
+	return fmt.Sprintf("%s Input: JSON object with %s", description, strings.Join(args, "; "))
+}
+
+// InputSchema returns the JSON schema built from the declared arguments
+func (t *CustomTool) InputSchema() json.RawMessage {
+	properties := make(map[string]interface{}, len(t.spec.Args))
+	for _, arg := range t.spec.Args {
+		property := map[string]string{"type": argType(arg)}
+		if arg.Description != "" {
+			property["description"] = arg.Description
+		}
+		properties[arg.Name] = property
+	}
+
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(t.required) > 0 {
+		schema["required"] = t.required
+	}
+
+	data, _ := json.Marshal(schema)
+	return data
+}
+
+// Call renders the command from the JSON arguments and runs it
+func (t *CustomTool) Call(ctx context.Context, input string) (string, error) {
+	command, err := t.render(input)
+	if err != nil {
+		return "", err
+	}
+
+	if err := t.ValidateAccess("Custom", t.spec.Name+" "+command); err != nil {
+		return "", err
+	}
+
+	cmdCtx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(cmdCtx, "sh", "-c", command)
+	cmd.Dir = t.spec.WorkingDir
+	// Don't wait for children of a killed shell that still hold the output pipes
+	cmd.WaitDelay = time.Second
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	logger.Debug("Running custom tool %s: %s", t.spec.Name, command)
+	err = cmd.Run()
+
+	output := stdout.String()
+	if stderr.Len() > 0 {
+		if output != "" {
+			output += "\n"
+		}
+		output += stderr.String()
+	}
+	output = strings.TrimSpace(output)
+
+	if err != nil {
+		if cmdCtx.Err() == context.DeadlineExceeded {
+			return output, fmt.Errorf("%s timed out after %v", t.spec.Name, t.timeout)
+		}
+		if output != "" {
+			return output, fmt.Errorf("%s failed: %w\nOutput: %s", t.spec.Name, err, output)
+		}
+		return "", fmt.Errorf("%s failed: %w", t.spec.Name, err)
+	}
+
+	if output == "" {
+		output = fmt.Sprintf("%s completed successfully (no output)", t.spec.Name)
+	}
+	return output, nil
+}
+
+// render validates the arguments and fills the command template. Values keep
+// their declared type, so {{if .flag}} tests a boolean; strings print shell-quoted.
+func (t *CustomTool) render(input string) (string, error) {
+	trimmed := strings.TrimSpace(input)
+	if trimmed == "" {
+		trimmed = "{}"
+	}
+
+	var args map[string]interface{}
+	decoder := json.NewDecoder(strings.NewReader(trimmed))
+	decoder.UseNumber()
+	if err := decoder.Decode(&args); err != nil {
+		return "", fmt.Errorf("input must be a JSON object: %w", err)
+	}
+
+	declared := make(map[string]config.CustomToolArg, len(t.spec.Args))
+	for _, arg := range t.spec.Args {
+		declared[arg.Name] = arg
+	}
+
+	var unknown []string
+	for name := range args {
+		if _, ok := declared[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return "", fmt.Errorf("unknown argument(s) for %s: %s", t.spec.Name, strings.Join(unknown, ", "))
+	}
+
+	// Optional arguments that are not given render as nothing
+	data := make(map[string]interface{}, len(t.spec.Args))
+	for _, arg := range t.spec.Args {
+		value, ok := args[arg.Name]
+		if !ok || value == nil {
+			if arg.Required {
+				return "", fmt.Errorf("missing required argument %q", arg.Name)
+			}
+			data[arg.Name] = ""
+			continue
+		}
+
+		typed, err := typedArg(arg, value)
+		if err != nil {
+			return "", err
+		}
+		data[arg.Name] = typed
+	}
+
+	var out strings.Builder
+	if err := t.command.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed to render command for %s: %w", t.spec.Name, err)
+	}
+	return out.String(), nil
+}
+
+// shellString is a string argument; it prints shell-quoted in templates
+type shellString string
+
+func (s shellString) String() string {
+	return shellQuote(string(s))
+}
+
+// typedArg type-checks a value and converts it to its template value
+func typedArg(arg config.CustomToolArg, value interface{}) (interface{}, error) {
+	switch argType(arg) {
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("argument %q must be a string", arg.Name)
+		}
+		return shellString(s), nil
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return nil, fmt.Errorf("argument %q must be an integer", arg.Name)
+		}
+		i, err := n.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("argument %q must be an integer", arg.Name)
+		}
+		return i, nil
+	case "number":
+		n, ok := value.(json.Number)
+		if !ok {
+			return nil, fmt.Errorf("argument %q must be a number", arg.Name)
+		}
+		if _, err := n.Float64(); err != nil {
+			return nil, fmt.Errorf("argument %q must be a number", arg.Name)
+		}
+		return n, nil
+	default:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("argument %q must be a boolean", arg.Name)
+		}
+		return b, nil
+	}
+}
+
+// rawArg returns a template value as unquoted text
+func rawArg(v interface{}) string {
+	if s, ok := v.(shellString); ok {
+		return string(s)
+	}
+	return fmt.Sprint(v)
+}
+
+// quotedAction returns the first template action of command that sits inside
+// single or double shell quotes, or "" if there is none. A quoted value inside
+// double quotes would keep its single quotes literally and leave $(...) and
+// backticks in it to the shell, so actions may only be bare words.
+func quotedAction(command string) string {
+	var quote byte
+	for i := 0; i < len(command); i++ {
+		if strings.HasPrefix(command[i:], "{{") {
+			end := strings.Index(command[i:], "}}")
+			if end < 0 {
+				// Left for the template parser to report
+				return ""
+			}
+			if quote != 0 {
+				return command[i : i+end+2]
+			}
+			i += end + 1
+			continue
+		}
+
+		switch c := command[i]; {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			}
+		case c == '\\':
+			i++
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		}
+	}
+	return ""
+}
+
+func argType(arg config.CustomToolArg) string {
+	if arg.Type == "" {
+		return "string"
+	}
+	return arg.Type
+}
+
+// shellQuote quotes s as a single sh word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// RegisterCustomTools registers the tools declared under tools.custom and returns their names.
+// Invalid declarations are skipped and reported in the returned error.
+func RegisterCustomTools(reg registry.Registry, specs []config.CustomTool) ([]string, error) {
+	var names []string
+	var problems []string
+
+	taken := registry.ToolNames(reg)
+
+	for _, spec := range specs {
+		// Validate once up front so bad declarations are reported at startup
+		if _, err := NewCustomTool(spec); err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if taken[spec.Name] {
+			problems = append(problems, fmt.Sprintf("custom tool %s: name is already used by another tool", spec.Name))
+			continue
+		}
+
+		spec := spec
+		err := reg.Register(spec.Name, func(skipPermissions bool) tools.Tool {
+			tool, _ := NewCustomToolWithBypass(spec, skipPermissions)
+			return tool
+		})
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("custom tool %s: %v", spec.Name, err))
+			continue
+		}
+		names = append(names, spec.Name)
+		taken[spec.Name] = true
+		logger.Debug("Registered custom tool: %s", spec.Name)
+	}
+
+	if len(problems) > 0 {
+		return names, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return names, nil
+}
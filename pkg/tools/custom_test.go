@@ -0,0 +1,241 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/killallgit/ryan/pkg/config"
+	"github.com/killallgit/ryan/pkg/tools/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/tools"
+)
+
+func echoToolSpec() config.CustomTool {
+	return config.CustomTool{
+		Name:        "make_test",
+		Description: "Run tests for a target.",
+		Command:     "echo make test TARGET={{.target}} COUNT={{.count}} VERBOSE={{.verbose}}",
+		Args: []config.CustomToolArg{
+			{Name: "target", Required: true, Description: "make target"},
+			{Name: "count", Type: "integer"},
+			{Name: "verbose", Type: "boolean"},
+		},
+	}
+}
+
+func TestCustomToolRender(t *testing.T) {
+	tool, err := NewCustomToolWithBypass(echoToolSpec(), true)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr string
+	}{
+		{"all arguments", `{"target": "unit", "count": 3, "verbose": true}`,
+			"echo make test TARGET='unit' COUNT=3 VERBOSE=true", ""},
+		{"optional arguments omitted", `{"target": "unit"}`,
+			"echo make test TARGET='unit' COUNT= VERBOSE=", ""},
+		{"shell metacharacters are quoted", `{"target": "x'; rm -rf / #"}`,
+			`echo make test TARGET='x'\''; rm -rf / #' COUNT= VERBOSE=`, ""},
+		{"missing required", `{"count": 1}`, "", `missing required argument "target"`},
+		{"unknown argument", `{"target": "a", "extra": 1}`, "", "unknown argument(s) for make_test: extra"},
+		{"wrong type", `{"target": "a", "count": "3"}`, "", `"count" must be an integer`},
+		{"fractional integer", `{"target": "a", "count": 1.5}`, "", `"count" must be an integer`},
+		{"not an object", `unit`, "", "JSON object"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tool.render(tt.input)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCustomToolTemplateValues(t *testing.T) {
+	spec := config.CustomTool{
+		Name:    "build",
+		Command: `go build{{if .race}} -race{{end}}{{if .count}} -p {{.count}}{{end}} -o {{quote (printf "bin/%s" (raw .name))}}{{if eq .name "ryan"}} .{{end}}`,
+		Args: []config.CustomToolArg{
+			{Name: "name", Required: true},
+			{Name: "race", Type: "boolean"},
+			{Name: "count", Type: "integer"},
+		},
+	}
+	tool, err := NewCustomToolWithBypass(spec, true)
+	require.NoError(t, err)
+
+	got, err := tool.render(`{"name": "ryan", "race": false, "count": 0}`)
+	require.NoError(t, err)
+	assert.Equal(t, "go build -o 'bin/ryan' .", got, "false and zero are falsy")
+
+	got, err = tool.render(`{"name": "it's", "race": true, "count": 4}`)
+	require.NoError(t, err)
+	assert.Equal(t, `go build -race -p 4 -o 'bin/it'\''s'`, got)
+
+	got, err = tool.render(`{"name": "x"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "go build -o 'bin/x'", got, "omitted optional arguments are falsy")
+}
+
+func TestCustomToolSchema(t *testing.T) {
+	tool, err := NewCustomTool(echoToolSpec())
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"type": "object",
+		"additionalProperties": false,
+		"properties": {
+			"target": {"type": "string", "description": "make target"},
+			"count": {"type": "integer"},
+			"verbose": {"type": "boolean"}
+		},
+		"required": ["target"]
+	}`, string(tool.InputSchema()))
+	assert.Contains(t, tool.Description(), `"target": string (required) - make target`)
+}
+
+func TestCustomToolValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		spec config.CustomTool
+	}{
+		{"bad name", config.CustomTool{Name: "make test", Command: "make"}},
+		{"no command", config.CustomTool{Name: "empty"}},
+		{"bad template", config.CustomTool{Name: "t", Command: "make {{.target"}},
+		{"bad type", config.CustomTool{Name: "t", Command: "make", Args: []config.CustomToolArg{{Name: "a", Type: "list"}}}},
+		{"duplicate arg", config.CustomTool{Name: "t", Command: "make", Args: []config.CustomToolArg{{Name: "a"}, {Name: "a"}}}},
+		{"single-quoted arg", config.CustomTool{Name: "t", Command: "grep '{{.a}}'", Args: []config.CustomToolArg{{Name: "a"}}}},
+		{"double-quoted arg", config.CustomTool{Name: "t", Command: `echo "{{.a}}"`, Args: []config.CustomToolArg{{Name: "a"}}}},
+		{"arg inside a double-quoted word", config.CustomTool{Name: "t", Command: `echo "msg: {{.a}} done"`, Args: []config.CustomToolArg{{Name: "a", Type: "integer"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCustomTool(tt.spec)
+			assert.Error(t, err)
+		})
+	}
+
+	// Templates referring to undeclared arguments fail when rendered
+	tool, err := NewCustomToolWithBypass(config.CustomTool{Name: "t", Command: "make {{.target}}"}, true)
+	require.NoError(t, err)
+	_, err = tool.Call(context.Background(), "{}")
+	assert.ErrorContains(t, err, "target")
+}
+
+func TestCustomToolCall(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "marker.txt"), []byte("here"), 0644))
+
+	t.Run("runs in working dir", func(t *testing.T) {
+		tool, err := NewCustomToolWithBypass(config.CustomTool{
+			Name:       "show",
+			Command:    "cat {{.file}}",
+			Args:       []config.CustomToolArg{{Name: "file", Required: true}},
+			WorkingDir: dir,
+		}, true)
+		require.NoError(t, err)
+
+		output, err := tool.Call(ctx, `{"file": "marker.txt"}`)
+		require.NoError(t, err)
+		assert.Equal(t, "here", output)
+	})
+
+	t.Run("arguments never run as commands", func(t *testing.T) {
+		marker := filepath.Join(dir, "pwned")
+		input := `{"msg": "$(touch ` + marker + `) ` + "`touch " + marker + "`" + `"}`
+
+		_, err := NewCustomToolWithBypass(config.CustomTool{
+			Name:    "say",
+			Command: `echo "{{.msg}}"`,
+			Args:    []config.CustomToolArg{{Name: "msg"}},
+		}, true)
+		assert.ErrorContains(t, err, "inside shell quotes")
+
+		tool, err := NewCustomToolWithBypass(config.CustomTool{
+			Name:    "say",
+			Command: `echo "said:" {{.msg}} '{done}'`,
+			Args:    []config.CustomToolArg{{Name: "msg"}},
+		}, true)
+		require.NoError(t, err)
+		output, err := tool.Call(ctx, input)
+		require.NoError(t, err)
+		assert.Contains(t, output, "said: $(touch")
+		assert.NoFileExists(t, marker)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		tool, err := NewCustomToolWithBypass(config.CustomTool{Name: "slow", Command: "sleep 5", Timeout: 1}, true)
+		require.NoError(t, err)
+
+		_, err = tool.Call(ctx, "")
+		assert.ErrorContains(t, err, "timed out")
+	})
+
+	t.Run("failure includes output", func(t *testing.T) {
+		tool, err := NewCustomToolWithBypass(config.CustomTool{Name: "fail", Command: "echo broken >&2; exit 2"}, true)
+		require.NoError(t, err)
+
+		_, err = tool.Call(ctx, "{}")
+		assert.ErrorContains(t, err, "broken")
+	})
+}
+
+func TestCustomToolPermissions(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".ryan"), 0755))
+	settings := `{"permissions": {"allow": ["Custom(make_test:*)"]}}`
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".ryan", "settings.json"), []byte(settings), 0644))
+
+	allowed, err := NewCustomTool(echoToolSpec())
+	require.NoError(t, err)
+	output, err := allowed.Call(context.Background(), `{"target": "unit"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "make test TARGET=unit COUNT= VERBOSE=", output)
+
+	denied, err := NewCustomTool(config.CustomTool{Name: "deploy", Command: "echo deploying"})
+	require.NoError(t, err)
+	_, err = denied.Call(context.Background(), "{}")
+	assert.ErrorContains(t, err, "Custom(deploy echo deploying)")
+}
+
+func TestRegisterCustomTools(t *testing.T) {
+	reg := registry.New()
+	reg.Register("ripgrep", func(skipPermissions bool) tools.Tool {
+		return NewRipgrepToolWithBypass(skipPermissions)
+	})
+
+	names, err := RegisterCustomTools(reg, []config.CustomTool{
+		echoToolSpec(),
+		{Name: "search", Command: "echo clash"},
+		{Name: "bad name", Command: "echo"},
+		{Name: "make_test", Command: "echo again"},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "search: name is already used")
+	assert.Contains(t, err.Error(), "bad name")
+	assert.Equal(t, []string{"make_test"}, names)
+
+	tool, err := reg.Get("make_test", true)
+	require.NoError(t, err)
+	_, ok := tool.(registry.SchemaProvider)
+	assert.True(t, ok)
+
+	settings := &config.Settings{}
+	settings.Tools.Enabled = true
+	enabled := reg.GetEnabled(settings, true)
+	require.Len(t, enabled, 1)
+	assert.Equal(t, "make_test", enabled[0].Name())
+}
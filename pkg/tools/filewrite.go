@@ -6,12 +6,12 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
 )
 
 // FileWriteTool implements file writing with permission checking
 type FileWriteTool struct {
 	*SecuredTool
+	checkpointed
 }
 
 // NewFileWriteTool creates a new file write tool
@@ -62,17 +62,9 @@ func (t *FileWriteTool) Call(ctx context.Context, input string) (string, error)
 		return "", fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	// Create backup if file exists
-	if stat, err := os.Stat(path); err == nil && stat.Mode().IsRegular() {
-		backupPath := fmt.Sprintf("%s.backup.%d", path, time.Now().Unix())
-		data, err := os.ReadFile(path)
-		if err != nil {
-			return "", fmt.Errorf("failed to read existing file for backup: %w", err)
-		}
-		if err := os.WriteFile(backupPath, data, stat.Mode()); err != nil {
-			// Log warning but don't fail
-			fmt.Fprintf(os.Stderr, "Warning: failed to create backup: %v\n", err)
-		}
+	// Record the pre-image so the change can be rewound
+	if err := t.checkpoints.Record(path); err != nil {
+		return "", fmt.Errorf("failed to record checkpoint: %w", err)
 	}
 
 	// Write file
@@ -0,0 +1,428 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// skippedElements are dropped entirely when converting HTML to Markdown
+var skippedElements = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Nav:      true,
+	atom.Svg:      true,
+	atom.Iframe:   true,
+	atom.Template: true,
+	atom.Head:     true,
+	atom.Form:     true,
+	atom.Button:   true,
+}
+
+// blockElements are separated from their surroundings by blank lines
+var blockElements = map[atom.Atom]bool{
+	atom.P:          true,
+	atom.Div:        true,
+	atom.Section:    true,
+	atom.Article:    true,
+	atom.Main:       true,
+	atom.Header:     true,
+	atom.Footer:     true,
+	atom.Aside:      true,
+	atom.Figure:     true,
+	atom.Figcaption: true,
+	atom.Dl:         true,
+	atom.Dt:         true,
+	atom.Dd:         true,
+	atom.Details:    true,
+	atom.Summary:    true,
+	atom.Body:       true,
+	atom.Html:       true,
+}
+
+var (
+	whitespaceRun = regexp.MustCompile(`[ \t\r\n\f]+`)
+	blankLineRun  = regexp.MustCompile(`\n{3,}`)
+)
+
+// HTMLToMarkdownOptions controls HTML to Markdown conversion
+type HTMLToMarkdownOptions struct {
+	// BaseURL is used to resolve relative links and images
+	BaseURL *url.URL
+
+	// Selector restricts conversion to elements matching a CSS selector
+	Selector string
+
+	// MainContent restricts conversion to the page's main content
+	// (<main>, <article> or [role=main]) when present
+	MainContent bool
+}
+
+// HTMLToMarkdown converts an HTML document to clean Markdown, dropping scripts,
+// styles and navigation while keeping headings, links, lists and code blocks
+func HTMLToMarkdown(r io.Reader, opts HTMLToMarkdownOptions) (string, string, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	title := strings.TrimSpace(doc.Find("title").First().Text())
+
+	selection := doc.Find("body")
+	if selection.Length() == 0 {
+		selection = doc.Selection
+	}
+
+	if opts.MainContent {
+		for _, candidate := range []string{"main", "article", "[role=main]"} {
+			if found := doc.Find(candidate); found.Length() > 0 {
+				selection = found.First()
+				break
+			}
+		}
+	}
+
+	if opts.Selector != "" {
+		found := doc.Find(opts.Selector)
+		if found.Length() == 0 {
+			return "", title, fmt.Errorf("selector %q matched no elements", opts.Selector)
+		}
+		selection = found
+	}
+
+	c := &markdownConverter{base: opts.BaseURL}
+	var b strings.Builder
+	for _, node := range selection.Nodes {
+		b.WriteString(c.node(node))
+	}
+
+	return cleanMarkdown(b.String()), title, nil
+}
+
+// markdownConverter renders HTML nodes as Markdown
+type markdownConverter struct {
+	base      *url.URL
+	listDepth int
+}
+
+// children renders all child nodes of n
+func (c *markdownConverter) children(n *html.Node) string {
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(c.node(child))
+	}
+	return b.String()
+}
+
+// node renders a single node
+func (c *markdownConverter) node(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return whitespaceRun.ReplaceAllString(n.Data, " ")
+	case html.ElementNode:
+	case html.DocumentNode:
+		return c.children(n)
+	default:
+		return ""
+	}
+
+	if skippedElements[n.DataAtom] || attr(n, "hidden") != "" || attr(n, "aria-hidden") == "true" {
+		return ""
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		text := strings.TrimSpace(c.inline(n))
+		if text == "" {
+			return ""
+		}
+		return c.block(strings.Repeat("#", level) + " " + text)
+	case atom.Pre:
+		return c.block(c.codeBlock(n))
+	case atom.Code, atom.Kbd, atom.Samp, atom.Tt:
+		text := textContent(n)
+		if strings.TrimSpace(text) == "" {
+			return ""
+		}
+		fence := "`"
+		if strings.Contains(text, "`") {
+			fence = "``"
+		}
+		return fence + text + fence
+	case atom.A:
+		text := strings.TrimSpace(c.children(n))
+		href := c.resolve(attr(n, "href"))
+		if href == "" || strings.HasPrefix(href, "javascript:") {
+			return text
+		}
+		if text == "" {
+			text = href
+		}
+		return fmt.Sprintf("[%s](%s)", text, href)
+	case atom.Img:
+		src := c.resolve(attr(n, "src"))
+		if src == "" || strings.HasPrefix(src, "data:") {
+			return ""
+		}
+		return fmt.Sprintf("![%s](%s)", attr(n, "alt"), src)
+	case atom.Strong, atom.B:
+		return wrapInline(c.children(n), "**")
+	case atom.Em, atom.I:
+		return wrapInline(c.children(n), "*")
+	case atom.Del, atom.S:
+		return wrapInline(c.children(n), "~~")
+	case atom.Br:
+		return "  \n"
+	case atom.Hr:
+		return c.block("---")
+	case atom.Blockquote:
+		inner := cleanMarkdown(c.children(n))
+		lines := strings.Split(inner, "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight("> "+line, " ")
+		}
+		return c.block(strings.Join(lines, "\n"))
+	case atom.Ul, atom.Ol:
+		return c.list(n)
+	case atom.Table:
+		return c.block(c.table(n))
+	}
+
+	if blockElements[n.DataAtom] {
+		return c.block(c.children(n))
+	}
+
+	return c.children(n)
+}
+
+// inline renders children of n on a single line
+func (c *markdownConverter) inline(n *html.Node) string {
+	return whitespaceRun.ReplaceAllString(c.children(n), " ")
+}
+
+// block surrounds content with blank lines
+func (c *markdownConverter) block(content string) string {
+	if strings.TrimSpace(content) == "" {
+		return ""
+	}
+	return "\n\n" + content + "\n\n"
+}
+
+// codeBlock renders a <pre> element as a fenced code block
+func (c *markdownConverter) codeBlock(n *html.Node) string {
+	language := codeLanguage(n)
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.DataAtom == atom.Code && language == "" {
+			language = codeLanguage(child)
+		}
+	}
+
+	code := strings.Trim(textContent(n), "\n")
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence + language + "\n" + code + "\n" + fence
+}
+
+// list renders an ordered or unordered list, indenting nested lists
+func (c *markdownConverter) list(n *html.Node) string {
+	c.listDepth++
+	defer func() { c.listDepth-- }()
+
+	ordered := n.DataAtom == atom.Ol
+	index := 1
+	var items []string
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode || child.DataAtom != atom.Li {
+			continue
+		}
+
+		marker := "- "
+		if ordered {
+			marker = fmt.Sprintf("%d. ", index)
+			index++
+		}
+
+		content := strings.TrimSpace(blankLineRun.ReplaceAllString(c.children(child), "\n\n"))
+		lines := strings.Split(content, "\n")
+		for i := 1; i < len(lines); i++ {
+			if strings.TrimSpace(lines[i]) != "" {
+				lines[i] = strings.Repeat(" ", len(marker)) + lines[i]
+			}
+		}
+		items = append(items, marker+strings.Join(lines, "\n"))
+	}
+
+	if len(items) == 0 {
+		return ""
+	}
+
+	content := strings.Join(items, "\n")
+	if c.listDepth > 1 {
+		return "\n" + content + "\n"
+	}
+	return c.block(content)
+}
+
+// table renders a table as a Markdown pipe table
+func (c *markdownConverter) table(n *html.Node) string {
+	var rows [][]string
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			if child.DataAtom == atom.Tr {
+				var cells []string
+				for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.DataAtom == atom.Td || cell.DataAtom == atom.Th) {
+						text := strings.TrimSpace(c.inline(cell))
+						cells = append(cells, strings.ReplaceAll(text, "|", `\|`))
+					}
+				}
+				if len(cells) > 0 {
+					rows = append(rows, cells)
+				}
+				continue
+			}
+			walk(child)
+		}
+	}
+	walk(n)
+
+	if len(rows) == 0 {
+		return ""
+	}
+
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+
+	var b strings.Builder
+	for i, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			b.WriteString("|" + strings.Repeat(" --- |", columns) + "\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// resolve resolves a link against the base URL
+func (c *markdownConverter) resolve(href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || c.base == nil {
+		return href
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return c.base.ResolveReference(ref).String()
+}
+
+// codeLanguage extracts a language hint from a language-* or lang-* class
+func codeLanguage(n *html.Node) string {
+	for _, class := range strings.Fields(attr(n, "class")) {
+		for _, prefix := range []string{"language-", "lang-"} {
+			if strings.HasPrefix(class, prefix) {
+				return strings.TrimPrefix(class, prefix)
+			}
+		}
+	}
+	return ""
+}
+
+// wrapInline wraps text in an inline marker, keeping surrounding spaces outside
+func wrapInline(text, marker string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	leading := text[:len(text)-len(strings.TrimLeft(text, " "))]
+	trailing := text[len(strings.TrimRight(text, " ")):]
+	return leading + marker + trimmed + marker + trailing
+}
+
+// textContent returns the raw text of a node and its descendants
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.DataAtom == atom.Br {
+			b.WriteString("\n")
+			continue
+		}
+		b.WriteString(textContent(child))
+	}
+	return b.String()
+}
+
+// attr returns the value of an attribute
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// cleanMarkdown normalizes spacing: trims line edges outside code fences and
+// collapses runs of blank lines
+func cleanMarkdown(md string) string {
+	lines := strings.Split(md, "\n")
+	inFence := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+			lines[i] = trimmed
+			continue
+		}
+		if inFence {
+			continue
+		}
+		// Preserve list indentation and hard line breaks, drop stray leading spaces
+		if strings.HasPrefix(strings.TrimLeft(line, " "), "- ") || isOrderedItem(strings.TrimLeft(line, " ")) {
+			lines[i] = strings.TrimRight(line, " ")
+			continue
+		}
+		if strings.HasSuffix(line, "  ") && trimmed != "" {
+			lines[i] = strings.TrimLeft(line, " ")
+			continue
+		}
+		lines[i] = trimmed
+	}
+	return strings.TrimSpace(blankLineRun.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+// isOrderedItem reports whether a line starts with an ordered list marker
+func isOrderedItem(line string) bool {
+	dot := strings.Index(line, ". ")
+	if dot <= 0 {
+		return false
+	}
+	for _, r := range line[:dot] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
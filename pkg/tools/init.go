@@ -23,6 +23,11 @@ func init() {
 		return NewFileWriteToolWithBypass(skipPermissions)
 	})
 
+	// Register patch tool
+	registry.Global().Register("apply_patch", func(skipPermissions bool) tools.Tool {
+		return NewPatchToolWithBypass(skipPermissions)
+	})
+
 	// Register git tool
 	registry.Global().Register("git", func(skipPermissions bool) tools.Tool {
 		return NewGitToolWithBypass(skipPermissions)
@@ -0,0 +1,255 @@
+// Package output keeps large tool results out of the conversation. Results over
+// a token budget are cut down to their head and tail, and the full text is
+// stored in a session scratch directory where read_output can page through it.
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/killallgit/ryan/pkg/config"
+	"github.com/killallgit/ryan/pkg/logger"
+	"github.com/killallgit/ryan/pkg/tools/registry"
+	"github.com/tmc/langchaingo/tools"
+)
+
+// headShare is the part of the budget spent on the start of the output; the rest goes to the end
+const headShare = 0.6
+
+// DefaultDir returns the scratch directory for a session
+func DefaultDir(sessionID string) string {
+	return filepath.Join(config.BuildSettingsPath("scratch"), sessionID)
+}
+
+// Governor caps tool output to a token budget and spills the rest to disk
+type Governor struct {
+	mu        sync.Mutex
+	dir       string
+	maxTokens int
+	count     func(string) int
+	next      int
+}
+
+// NewGovernor creates a governor that stores full outputs in dir. The directory
+// is created on the first spill. count measures tokens in a piece of text.
+func NewGovernor(dir string, maxTokens int, count func(string) int) *Governor {
+	return &Governor{dir: dir, maxTokens: maxTokens, count: count}
+}
+
+// MaxTokens returns the per-result token budget
+func (g *Governor) MaxTokens() int {
+	return g.maxTokens
+}
+
+// WrapAll governs the output of every tool
+func (g *Governor) WrapAll(agentTools []tools.Tool) []tools.Tool {
+	wrapped := make([]tools.Tool, len(agentTools))
+	for i, tool := range agentTools {
+		wrapped[i] = g.Wrap(tool)
+	}
+	return wrapped
+}
+
+// Wrap governs the output of a tool, keeping its input schema if it has one
+func (g *Governor) Wrap(tool tools.Tool) tools.Tool {
+	governed := &governedTool{Tool: tool, governor: g}
+	if provider, ok := tool.(registry.SchemaProvider); ok {
+		return &governedSchemaTool{governedTool: governed, schema: provider}
+	}
+	return governed
+}
+
+// Limit returns output unchanged if it fits the budget. Otherwise the full text
+// is saved and a head/tail excerpt with a handle for read_output is returned.
+func (g *Governor) Limit(toolName, output string) string {
+	if g.maxTokens <= 0 || g.count(output) <= g.maxTokens {
+		return output
+	}
+
+	handle, err := g.save(output)
+	if err != nil {
+		logger.Warn("Could not save output of %s: %v", toolName, err)
+	}
+
+	lines := strings.Split(output, "\n")
+	headBudget := int(float64(g.maxTokens) * headShare)
+	tailBudget := g.maxTokens - headBudget
+
+	head := g.takeLines(lines, headBudget, false)
+	tail := g.takeLines(lines[head:], tailBudget, true)
+	omitted := len(lines) - head - tail
+
+	var notice string
+	if handle == "" {
+		notice = fmt.Sprintf("[... %d lines omitted from %s output ...]", omitted, toolName)
+	} else {
+		notice = fmt.Sprintf("[... %d of %d lines omitted. Full output saved as %s; "+
+			`use read_output with {"handle": "%s", "offset": %d} to read the omitted lines ...]`,
+			omitted, len(lines), handle, handle, head+1)
+	}
+
+	parts := make([]string, 0, 3)
+	if head > 0 {
+		parts = append(parts, strings.Join(lines[:head], "\n"))
+	} else {
+		// The first line alone is over budget, so show its beginning
+		parts = append(parts, truncateLine(lines[0], headBudget))
+	}
+	parts = append(parts, notice)
+	if tail > 0 {
+		parts = append(parts, strings.Join(lines[len(lines)-tail:], "\n"))
+	}
+	return strings.Join(parts, "\n")
+}
+
+// takeLines counts how many lines fit the budget, from the start or from the end.
+// A single line longer than the budget is never taken.
+func (g *Governor) takeLines(lines []string, budget int, fromEnd bool) int {
+	used := 0
+	for taken := 0; taken < len(lines); taken++ {
+		line := lines[taken]
+		if fromEnd {
+			line = lines[len(lines)-1-taken]
+		}
+		used += g.count(line) + 1
+		if used > budget {
+			return taken
+		}
+	}
+	return len(lines)
+}
+
+// save stores the full output and returns its handle
+func (g *Governor) save(output string) (string, error) {
+	g.mu.Lock()
+	g.next++
+	handle := fmt.Sprintf("out-%d", g.next)
+	g.mu.Unlock()
+
+	if err := os.MkdirAll(g.dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create scratch directory: %w", err)
+	}
+	if err := os.WriteFile(g.path(handle), []byte(output), 0644); err != nil {
+		return "", fmt.Errorf("failed to save output: %w", err)
+	}
+	return handle, nil
+}
+
+// Read returns up to limit lines of a saved output starting at the 1-based line offset,
+// trimmed to the token budget
+func (g *Governor) Read(handle string, offset, limit int) (string, error) {
+	if !validHandle(handle) {
+		return "", fmt.Errorf("invalid handle %q", handle)
+	}
+
+	data, err := os.ReadFile(g.path(handle))
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("output %s not found (saved outputs are removed when the session ends)", handle)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read output: %w", err)
+	}
+
+	lines := strings.Split(string(data), "\n")
+	if offset < 1 {
+		offset = 1
+	}
+	if offset > len(lines) {
+		return "", fmt.Errorf("offset %d is past the end of %s (%d lines)", offset, handle, len(lines))
+	}
+
+	end := len(lines)
+	if limit > 0 && offset-1+limit < end {
+		end = offset - 1 + limit
+	}
+	page := lines[offset-1 : end]
+
+	// Never return more than the budget, even if the requested page is larger
+	if g.maxTokens > 0 {
+		if fit := g.takeLines(page, g.maxTokens, false); fit == 0 {
+			page = []string{truncateLine(page[0], g.maxTokens)}
+			end = offset
+		} else if fit < len(page) {
+			page = page[:fit]
+			end = offset - 1 + fit
+		}
+	}
+
+	header := fmt.Sprintf("[%s lines %d-%d of %d]", handle, offset, end, len(lines))
+	if end < len(lines) {
+		header += fmt.Sprintf(` - continue with {"handle": "%s", "offset": %d}`, handle, end+1)
+	}
+	return header + "\n" + strings.Join(page, "\n"), nil
+}
+
+// Close removes the session's saved outputs
+func (g *Governor) Close() error {
+	return os.RemoveAll(g.dir)
+}
+
+func (g *Governor) path(handle string) string {
+	return filepath.Join(g.dir, handle+".txt")
+}
+
+// truncateLine shortens a line to roughly budget tokens, assuming ~4 characters per token
+func truncateLine(line string, budget int) string {
+	runes := []rune(line)
+	if len(runes) <= budget*4 {
+		return line
+	}
+	return string(runes[:budget*4]) + " [line truncated]"
+}
+
+func validHandle(handle string) bool {
+	if !strings.HasPrefix(handle, "out-") || len(handle) == len("out-") {
+		return false
+	}
+	for _, r := range handle[len("out-"):] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// governedTool limits the output and error text of a wrapped tool
+type governedTool struct {
+	tools.Tool
+	governor *Governor
+}
+
+// Call runs the tool and limits what it returns
+func (t *governedTool) Call(ctx context.Context, input string) (string, error) {
+	result, err := t.Tool.Call(ctx, input)
+	if err != nil {
+		limited := t.governor.Limit(t.Name(), err.Error())
+		if limited != err.Error() {
+			err = &limitedError{message: limited, err: err}
+		}
+	}
+	return t.governor.Limit(t.Name(), result), err
+}
+
+type governedSchemaTool struct {
+	*governedTool
+	schema registry.SchemaProvider
+}
+
+// InputSchema returns the wrapped tool's schema
+func (t *governedSchemaTool) InputSchema() json.RawMessage {
+	return t.schema.InputSchema()
+}
+
+// limitedError shortens an error message while keeping the original error for errors.Is/As
+type limitedError struct {
+	message string
+	err     error
+}
+
+func (e *limitedError) Error() string { return e.message }
+func (e *limitedError) Unwrap() error { return e.err }
@@ -0,0 +1,141 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/killallgit/ryan/pkg/tools/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/tools"
+)
+
+// wordCount treats every whitespace-separated word as one token
+func wordCount(s string) int {
+	return len(strings.Fields(s))
+}
+
+func numberedLines(n int) string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i+1)
+	}
+	return strings.Join(lines, "\n")
+}
+
+type fixedTool struct {
+	output string
+	err    error
+}
+
+func (f fixedTool) Name() string        { return "noisy" }
+func (f fixedTool) Description() string { return "Prints a lot" }
+func (f fixedTool) Call(ctx context.Context, input string) (string, error) {
+	return f.output, f.err
+}
+
+func TestLimitKeepsHeadAndTail(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "session")
+	governor := NewGovernor(dir, 30, wordCount)
+
+	short := numberedLines(5)
+	assert.Equal(t, short, governor.Limit("noisy", short))
+	assert.NoDirExists(t, dir, "nothing is saved until output is over budget")
+
+	full := numberedLines(1000)
+	limited := governor.Limit("noisy", full)
+
+	assert.True(t, strings.HasPrefix(limited, "line 1\nline 2\n"))
+	assert.True(t, strings.HasSuffix(limited, "line 999\nline 1000"))
+	assert.Contains(t, limited, "Full output saved as out-1")
+	assert.Less(t, wordCount(limited), 60)
+
+	saved, err := os.ReadFile(filepath.Join(dir, "out-1.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, full, string(saved))
+
+	// The notice points at the first omitted line
+	assert.Contains(t, limited, `"offset": 7`)
+	assert.NotContains(t, limited, "line 7\n")
+}
+
+func TestLimitLongSingleLine(t *testing.T) {
+	governor := NewGovernor(t.TempDir(), 10, wordCount)
+	line := strings.Repeat("word ", 500)
+
+	limited := governor.Limit("noisy", line)
+	assert.Contains(t, limited, "[line truncated]")
+	assert.Contains(t, limited, "out-1")
+	assert.Less(t, len(limited), 400)
+}
+
+func TestReadPagesThroughSavedOutput(t *testing.T) {
+	governor := NewGovernor(t.TempDir(), 30, wordCount)
+	governor.Limit("noisy", numberedLines(100))
+	tool := NewReadOutputTool(governor)
+	ctx := context.Background()
+
+	page, err := tool.Call(ctx, `{"handle": "out-1", "offset": 10, "limit": 3}`)
+	require.NoError(t, err)
+	assert.Equal(t, "[out-1 lines 10-12 of 100] - continue with {\"handle\": \"out-1\", \"offset\": 13}\nline 10\nline 11\nline 12", page)
+
+	// Pages are capped by the budget even when a larger limit is requested
+	page, err = tool.Call(ctx, `{"handle": "out-1", "offset": 1, "limit": 100}`)
+	require.NoError(t, err)
+	assert.Contains(t, page, "[out-1 lines 1-10 of 100]")
+
+	page, err = tool.Call(ctx, "out-1")
+	require.NoError(t, err)
+	assert.Contains(t, page, "line 1\n")
+
+	_, err = tool.Call(ctx, `{"handle": "out-1", "offset": 500}`)
+	assert.ErrorContains(t, err, "past the end")
+
+	_, err = tool.Call(ctx, `{"handle": "out-9"}`)
+	assert.ErrorContains(t, err, "not found")
+
+	_, err = tool.Call(ctx, `{"handle": "../../etc/passwd"}`)
+	assert.ErrorContains(t, err, "invalid handle")
+}
+
+func TestWrappedTools(t *testing.T) {
+	governor := NewGovernor(t.TempDir(), 30, wordCount)
+	cause := errors.New("exit status 1")
+
+	t.Run("output", func(t *testing.T) {
+		wrapped := governor.Wrap(fixedTool{output: numberedLines(500)})
+		assert.Equal(t, "noisy", wrapped.Name())
+
+		result, err := wrapped.Call(context.Background(), "")
+		require.NoError(t, err)
+		assert.Contains(t, result, "Full output saved as")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		wrapped := governor.Wrap(fixedTool{err: fmt.Errorf("go test failed: %w\n%s", cause, numberedLines(500))})
+
+		_, err := wrapped.Call(context.Background(), "")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Full output saved as")
+		assert.ErrorIs(t, err, cause)
+	})
+
+	t.Run("schema is kept", func(t *testing.T) {
+		var plain tools.Tool = governor.Wrap(fixedTool{})
+		_, ok := plain.(registry.SchemaProvider)
+		assert.False(t, ok)
+
+		withSchema := governor.Wrap(NewReadOutputTool(governor))
+		provider, ok := withSchema.(registry.SchemaProvider)
+		require.True(t, ok)
+		assert.Contains(t, string(provider.InputSchema()), `"handle"`)
+	})
+
+	require.NoError(t, governor.Close())
+	assert.NoDirExists(t, governor.dir)
+}
@@ -0,0 +1,68 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// defaultReadLimit is the page size when read_output is called without a limit
+const defaultReadLimit = 200
+
+// ReadOutputTool pages through tool outputs saved by a Governor
+type ReadOutputTool struct {
+	governor *Governor
+}
+
+// NewReadOutputTool creates a read_output tool for the governor's saved outputs
+func NewReadOutputTool(governor *Governor) *ReadOutputTool {
+	return &ReadOutputTool{governor: governor}
+}
+
+// Name returns the tool name
+func (t *ReadOutputTool) Name() string {
+	return "read_output"
+}
+
+// Description returns the tool description
+func (t *ReadOutputTool) Description() string {
+	return `Read lines of a long tool output that was shortened. Use the handle from the "Full output saved as" notice. ` +
+		`Input: JSON {"handle": "out-1", "offset": 1, "limit": 200} where offset is the first line (1-based)`
+}
+
+// InputSchema returns the JSON schema of the tool arguments
+func (t *ReadOutputTool) InputSchema() json.RawMessage {
+	return json.RawMessage(`{"type":"object","properties":{` +
+		`"handle":{"type":"string","description":"Handle of the saved output, e.g. out-1"},` +
+		`"offset":{"type":"integer","description":"First line to read (1-based)"},` +
+		`"limit":{"type":"integer","description":"Number of lines to read"}},"required":["handle"]}`)
+}
+
+// Call returns a page of a saved output
+func (t *ReadOutputTool) Call(ctx context.Context, input string) (string, error) {
+	var args struct {
+		Handle string `json:"handle"`
+		Offset int    `json:"offset"`
+		Limit  int    `json:"limit"`
+	}
+
+	trimmed := strings.TrimSpace(input)
+	if strings.HasPrefix(trimmed, "{") {
+		if err := json.Unmarshal([]byte(trimmed), &args); err != nil {
+			return "", fmt.Errorf("invalid JSON input: %w", err)
+		}
+	} else {
+		// Accept a bare handle
+		args.Handle = trimmed
+	}
+
+	if args.Handle == "" {
+		return "", fmt.Errorf("handle is required")
+	}
+	if args.Limit <= 0 {
+		args.Limit = defaultReadLimit
+	}
+
+	return t.governor.Read(args.Handle, args.Offset, args.Limit)
+}
@@ -0,0 +1,372 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/killallgit/ryan/pkg/logger"
+)
+
+// defaultPatchFuzz is the number of context lines that may be ignored at each
+// end of a hunk when it does not match exactly
+const defaultPatchFuzz = 2
+
+// PatchOptions contains the structured arguments accepted by the patch tool
+type PatchOptions struct {
+	// Patch is the unified diff to apply
+	Patch string `json:"patch"`
+
+	// DryRun checks that the patch applies without writing any files
+	DryRun bool `json:"dry_run,omitempty"`
+
+	// Fuzz overrides the number of context lines that may be ignored
+	Fuzz *int `json:"fuzz,omitempty"`
+}
+
+// PatchTool applies unified diffs across multiple files with permission checking
+type PatchTool struct {
+	*SecuredTool
+	checkpointed
+}
+
+// NewPatchTool creates a new patch tool
+func NewPatchTool() *PatchTool {
+	return NewPatchToolWithBypass(false)
+}
+
+// NewPatchToolWithBypass creates a new patch tool with optional permission bypass
+func NewPatchToolWithBypass(bypass bool) *PatchTool {
+	return &PatchTool{
+		SecuredTool: NewSecuredToolWithBypass(bypass),
+	}
+}
+
+// Name returns the tool name
+func (t *PatchTool) Name() string {
+	return "apply_patch"
+}
+
+// Description returns the tool description
+func (t *PatchTool) Description() string {
+	return `Apply a unified diff (diff -u / git diff format) to one or more files atomically: if any hunk fails, no file is changed. ` +
+		`Use /dev/null as the source to create a file or as the destination to delete one. Input: either the raw diff, ` +
+		`or a JSON object {"patch": "...", "dry_run": true, "fuzz": 2}`
+}
+
+// parsePatchInput accepts either a raw unified diff or a JSON object of PatchOptions
+func parsePatchInput(input string) (PatchOptions, error) {
+	opts := PatchOptions{Patch: input}
+
+	if strings.HasPrefix(strings.TrimSpace(input), "{") {
+		if err := json.Unmarshal([]byte(strings.TrimSpace(input)), &opts); err != nil {
+			return opts, fmt.Errorf("invalid JSON input: %w", err)
+		}
+	}
+
+	if strings.TrimSpace(opts.Patch) == "" {
+		return opts, fmt.Errorf("patch cannot be empty")
+	}
+	if opts.Fuzz != nil && *opts.Fuzz < 0 {
+		return opts, fmt.Errorf("fuzz cannot be negative")
+	}
+
+	return opts, nil
+}
+
+// pendingFile is the planned result of patching a single file
+type pendingFile struct {
+	patch   FilePatch
+	content string
+	mode    os.FileMode
+	results []HunkResult
+}
+
+// fileSnapshot records the state of a file before it was modified
+type fileSnapshot struct {
+	path    string
+	existed bool
+	content []byte
+	mode    os.FileMode
+}
+
+// Call executes the patch operation
+func (t *PatchTool) Call(ctx context.Context, input string) (string, error) {
+	opts, err := parsePatchInput(input)
+	if err != nil {
+		return "", err
+	}
+
+	fuzz := defaultPatchFuzz
+	if opts.Fuzz != nil {
+		fuzz = *opts.Fuzz
+	}
+
+	patches, err := ParseUnifiedDiff(opts.Patch)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse patch: %w", err)
+	}
+
+	// Validate permissions for every touched path before doing any work
+	for _, p := range patches {
+		for _, path := range []string{p.OldPath, p.NewPath} {
+			if path == devNull {
+				continue
+			}
+			if err := t.ValidateAccess("FileWrite", path); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	// Compute every result in memory so nothing is written unless all hunks apply
+	pending, err := planPatches(patches, fuzz)
+	if err != nil {
+		return "", err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	summary := summarizePatches(pending)
+	if opts.DryRun {
+		return "Dry run: patch applies cleanly\n" + summary, nil
+	}
+
+	// Record pre-images of every touched file so the change can be rewound
+	for _, f := range pending {
+		for _, path := range []string{f.patch.OldPath, f.patch.NewPath} {
+			if path == devNull {
+				continue
+			}
+			if err := t.checkpoints.Record(path); err != nil {
+				return "", fmt.Errorf("failed to record checkpoint: %w", err)
+			}
+		}
+	}
+
+	if err := commitPatches(pending); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Successfully patched %d file(s)\n%s", len(pending), summary), nil
+}
+
+// planPatches applies all hunks in memory and returns the resulting files
+func planPatches(patches []FilePatch, fuzz int) ([]pendingFile, error) {
+	// Later patches to the same file build on earlier ones
+	contents := make(map[string]string)
+	modes := make(map[string]os.FileMode)
+	exists := make(map[string]bool)
+
+	read := func(path string) (string, os.FileMode, bool, error) {
+		if content, ok := contents[path]; ok {
+			return content, modes[path], exists[path], nil
+		}
+		stat, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			return "", 0644, false, nil
+		}
+		if err != nil {
+			return "", 0, false, fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		if !stat.Mode().IsRegular() {
+			return "", 0, false, fmt.Errorf("%s is not a regular file", path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", 0, false, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		return string(data), stat.Mode().Perm(), true, nil
+	}
+
+	var pending []pendingFile
+	for _, p := range patches {
+		original := ""
+		mode := os.FileMode(0644)
+
+		if p.IsCreate() {
+			if _, _, found, err := read(p.NewPath); err != nil {
+				return nil, err
+			} else if found {
+				return nil, fmt.Errorf("cannot create %s: file already exists", p.NewPath)
+			}
+		} else {
+			content, m, found, err := read(p.OldPath)
+			if err != nil {
+				return nil, err
+			}
+			if !found {
+				return nil, fmt.Errorf("cannot patch %s: file does not exist", p.OldPath)
+			}
+			original, mode = content, m
+		}
+
+		content, results, err := applyHunks(original, p.Hunks, fuzz)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply patch to %s: %w", p.Path(), err)
+		}
+
+		if p.IsDelete() && content != "" {
+			return nil, fmt.Errorf("cannot delete %s: patch does not remove all of its content", p.OldPath)
+		}
+
+		if !p.IsCreate() && p.OldPath != p.NewPath {
+			// Renamed files free their old path
+			contents[p.OldPath] = ""
+			modes[p.OldPath] = mode
+			exists[p.OldPath] = false
+		}
+		contents[p.Path()] = content
+		modes[p.Path()] = mode
+		exists[p.Path()] = !p.IsDelete()
+
+		pending = append(pending, pendingFile{patch: p, content: content, mode: mode, results: results})
+	}
+
+	return pending, nil
+}
+
+// commitPatches writes all pending files, restoring the originals if any write fails
+func commitPatches(pending []pendingFile) error {
+	var snapshots []fileSnapshot
+	snapshotted := make(map[string]bool)
+
+	snapshot := func(path string) error {
+		if snapshotted[path] {
+			return nil
+		}
+		snap := fileSnapshot{path: path}
+		if stat, err := os.Stat(path); err == nil {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			snap.existed, snap.content, snap.mode = true, data, stat.Mode().Perm()
+		}
+		snapshots = append(snapshots, snap)
+		snapshotted[path] = true
+		return nil
+	}
+
+	rollback := func(cause error) error {
+		for i := len(snapshots) - 1; i >= 0; i-- {
+			snap := snapshots[i]
+			var err error
+			if snap.existed {
+				err = writeFileAtomic(snap.path, snap.content, snap.mode)
+			} else {
+				err = os.Remove(snap.path)
+				if os.IsNotExist(err) {
+					err = nil
+				}
+			}
+			if err != nil {
+				logger.Warn("Failed to roll back %s: %v", snap.path, err)
+			}
+		}
+		return fmt.Errorf("patch rolled back: %w", cause)
+	}
+
+	for _, f := range pending {
+		p := f.patch
+		renamed := !p.IsCreate() && !p.IsDelete() && p.OldPath != p.NewPath
+
+		for _, path := range []string{p.OldPath, p.NewPath} {
+			if path == devNull {
+				continue
+			}
+			if err := snapshot(path); err != nil {
+				return rollback(err)
+			}
+		}
+
+		if p.IsDelete() {
+			if err := os.Remove(p.OldPath); err != nil {
+				return rollback(fmt.Errorf("failed to delete %s: %w", p.OldPath, err))
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(p.NewPath), 0755); err != nil {
+			return rollback(fmt.Errorf("failed to create directory: %w", err))
+		}
+		if err := writeFileAtomic(p.NewPath, []byte(f.content), f.mode); err != nil {
+			return rollback(fmt.Errorf("failed to write %s: %w", p.NewPath, err))
+		}
+
+		if renamed {
+			if err := os.Remove(p.OldPath); err != nil {
+				return rollback(fmt.Errorf("failed to remove %s: %w", p.OldPath, err))
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into place
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".patch-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// summarizePatches describes what each file patch does
+func summarizePatches(pending []pendingFile) string {
+	var b strings.Builder
+	for _, f := range pending {
+		p := f.patch
+		switch {
+		case p.IsCreate():
+			fmt.Fprintf(&b, "  created %s", p.NewPath)
+		case p.IsDelete():
+			fmt.Fprintf(&b, "  deleted %s", p.OldPath)
+		case p.OldPath != p.NewPath:
+			fmt.Fprintf(&b, "  renamed %s -> %s", p.OldPath, p.NewPath)
+		default:
+			fmt.Fprintf(&b, "  patched %s", p.NewPath)
+		}
+		fmt.Fprintf(&b, " (%d hunk(s))\n", len(f.results))
+
+		for n, r := range f.results {
+			if r.Offset == 0 && r.Fuzz == 0 {
+				continue
+			}
+			fmt.Fprintf(&b, "    hunk #%d applied at line %d", n+1, r.Line)
+			if r.Offset != 0 {
+				fmt.Fprintf(&b, " (offset %d lines)", r.Offset)
+			}
+			if r.Fuzz != 0 {
+				fmt.Fprintf(&b, " (fuzz %d)", r.Fuzz)
+			}
+			b.WriteString("\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,363 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/killallgit/ryan/pkg/checkpoint"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const patchTestFile = `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println("hello")
+}
+
+func helper() int {
+	return 1
+}
+`
+
+func writePatchTestFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func readPatchTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestParseUnifiedDiff(t *testing.T) {
+	diff := `diff --git a/main.go b/main.go
+index 123..456 100644
+--- a/main.go
++++ b/main.go
+@@ -5,3 +5,3 @@ func main() {
+ func main() {
+-	fmt.Println("hello")
++	fmt.Println("world")
+ }
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1,2 @@
++one
++two
+\ No newline at end of file
+`
+
+	patches, err := ParseUnifiedDiff(diff)
+	require.NoError(t, err)
+	require.Len(t, patches, 2)
+
+	assert.Equal(t, "main.go", patches[0].OldPath)
+	assert.Equal(t, "main.go", patches[0].NewPath)
+	require.Len(t, patches[0].Hunks, 1)
+	assert.Equal(t, 5, patches[0].Hunks[0].OldStart)
+	assert.Len(t, patches[0].Hunks[0].Lines, 4)
+
+	assert.True(t, patches[1].IsCreate())
+	assert.Equal(t, "new.txt", patches[1].Path())
+	assert.True(t, patches[1].Hunks[0].NewNoEOL)
+
+	_, err = ParseUnifiedDiff("not a diff")
+	assert.Error(t, err)
+
+	_, err = ParseUnifiedDiff("--- a.go\n+++ a.go\n@@ bad @@\n")
+	assert.Error(t, err)
+}
+
+func TestParseUnifiedDiffBlankLineBetweenFiles(t *testing.T) {
+	diff := `--- a/one.txt
++++ b/one.txt
+@@ -1,2 +1,2 @@
+ first
+-old
++new
+
+--- a/two.txt
++++ b/two.txt
+@@ -1 +1 @@
+-a
++b
+`
+
+	patches, err := ParseUnifiedDiff(diff)
+	require.NoError(t, err)
+	require.Len(t, patches, 2)
+	assert.Len(t, patches[0].Hunks[0].Lines, 3, "the separating blank line is not context")
+	assert.Equal(t, "two.txt", patches[1].Path())
+
+	// A stripped blank context line inside the hunk is still kept
+	patches, err = ParseUnifiedDiff("--- a/x\n+++ b/x\n@@ -1,3 +1,3 @@\n a\n\n-b\n+c\n")
+	require.NoError(t, err)
+	assert.Equal(t, []HunkLine{{Op: ' ', Text: "a"}, {Op: ' '}, {Op: '-', Text: "b"}, {Op: '+', Text: "c"}}, patches[0].Hunks[0].Lines)
+}
+
+func TestParseUnifiedDiffHeaderLikeLines(t *testing.T) {
+	// Removing "-- old" and adding "++ new" produces lines that look like file headers
+	diff := `--- a/query.sql
++++ b/query.sql
+@@ -1,2 +1,2 @@
+ SELECT 1;
+--- old comment
++++ new comment
+--- a/other.txt
++++ b/other.txt
+@@ -1 +1 @@
+-a
++b
+`
+
+	patches, err := ParseUnifiedDiff(diff)
+	require.NoError(t, err)
+	require.Len(t, patches, 2)
+
+	hunk := patches[0].Hunks[0]
+	assert.Equal(t, 2, hunk.OldCount)
+	assert.Equal(t, 2, hunk.NewCount)
+	assert.Equal(t, []HunkLine{
+		{Op: ' ', Text: "SELECT 1;"},
+		{Op: '-', Text: "-- old comment"},
+		{Op: '+', Text: "++ new comment"},
+	}, hunk.Lines)
+
+	assert.Equal(t, "other.txt", patches[1].Path())
+	assert.Equal(t, 1, patches[1].Hunks[0].OldCount)
+}
+
+func TestApplyHunks(t *testing.T) {
+	t.Run("exact match", func(t *testing.T) {
+		hunks := []Hunk{{OldStart: 5, NewStart: 5, Lines: []HunkLine{
+			{' ', "func main() {"},
+			{'-', `	fmt.Println("hello")`},
+			{'+', `	fmt.Println("world")`},
+			{' ', "}"},
+		}}}
+		result, results, err := applyHunks(patchTestFile, hunks, 0)
+		require.NoError(t, err)
+		assert.Equal(t, strings.Replace(patchTestFile, "hello", "world", 1), result)
+		assert.Equal(t, HunkResult{Line: 5}, results[0])
+	})
+
+	t.Run("offset", func(t *testing.T) {
+		hunks := []Hunk{{OldStart: 1, NewStart: 1, Lines: []HunkLine{
+			{' ', "func helper() int {"},
+			{'-', "	return 1"},
+			{'+', "	return 2"},
+		}}}
+		result, results, err := applyHunks(patchTestFile, hunks, 0)
+		require.NoError(t, err)
+		assert.Contains(t, result, "return 2")
+		assert.Equal(t, 8, results[0].Offset)
+	})
+
+	t.Run("fuzz ignores stale context", func(t *testing.T) {
+		hunks := []Hunk{{OldStart: 9, NewStart: 9, Lines: []HunkLine{
+			{' ', "func helper() int64 {"},
+			{'-', "	return 1"},
+			{'+', "	return 2"},
+			{' ', "}"},
+		}}}
+		_, _, err := applyHunks(patchTestFile, hunks, 0)
+		assert.Error(t, err)
+
+		result, results, err := applyHunks(patchTestFile, hunks, 1)
+		require.NoError(t, err)
+		assert.Contains(t, result, "func helper() int {\n\treturn 2\n}")
+		assert.Equal(t, 1, results[0].Fuzz)
+	})
+
+	t.Run("trailing whitespace tolerated", func(t *testing.T) {
+		hunks := []Hunk{{OldStart: 3, NewStart: 3, Lines: []HunkLine{
+			{' ', `import "fmt"   `},
+			{'+', `import "os"`},
+		}}}
+		result, _, err := applyHunks(patchTestFile, hunks, 0)
+		require.NoError(t, err)
+		assert.Contains(t, result, "import \"fmt\"\nimport \"os\"\n")
+	})
+
+	t.Run("missing newline at end of file", func(t *testing.T) {
+		hunks := []Hunk{{OldStart: 1, NewStart: 1, Lines: []HunkLine{
+			{'-', "a"},
+			{'+', "b"},
+		}, NewNoEOL: true}}
+		result, _, err := applyHunks("a\n", hunks, 0)
+		require.NoError(t, err)
+		assert.Equal(t, "b", result)
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		hunks := []Hunk{{OldStart: 1, NewStart: 1, Lines: []HunkLine{
+			{'-', "does not exist"},
+		}}}
+		_, _, err := applyHunks(patchTestFile, hunks, 2)
+		assert.Error(t, err)
+	})
+}
+
+func TestPatchTool(t *testing.T) {
+	tool := NewPatchToolWithBypass(true)
+	ctx := context.Background()
+
+	t.Run("multi-file apply with create and delete", func(t *testing.T) {
+		dir := t.TempDir()
+		mainPath := writePatchTestFile(t, dir, "main.go", patchTestFile)
+		oldPath := writePatchTestFile(t, dir, "old.txt", "remove me\n")
+		newPath := filepath.Join(dir, "sub", "new.txt")
+
+		diff := fmt.Sprintf(`--- %[1]s
++++ %[1]s
+@@ -5,3 +5,3 @@
+ func main() {
+-	fmt.Println("hello")
++	fmt.Println("world")
+ }
+@@ -9,3 +9,3 @@
+ func helper() int {
+-	return 1
++	return 2
+ }
+--- %[2]s
++++ /dev/null
+@@ -1 +0,0 @@
+-remove me
+--- /dev/null
++++ %[3]s
+@@ -0,0 +1 @@
++created
+`, mainPath, oldPath, newPath)
+
+		result, err := tool.Call(ctx, diff)
+		require.NoError(t, err)
+		assert.Contains(t, result, "Successfully patched 3 file(s)")
+
+		content := readPatchTestFile(t, mainPath)
+		assert.Contains(t, content, `fmt.Println("world")`)
+		assert.Contains(t, content, "return 2")
+		assert.NoFileExists(t, oldPath)
+		assert.Equal(t, "created\n", readPatchTestFile(t, newPath))
+	})
+
+	t.Run("dry run does not write", func(t *testing.T) {
+		dir := t.TempDir()
+		path := writePatchTestFile(t, dir, "main.go", patchTestFile)
+		diff := fmt.Sprintf("--- %[1]s\n+++ %[1]s\n@@ -6 +6 @@\n-\tfmt.Println(\"hello\")\n+\tfmt.Println(\"world\")\n", path)
+
+		input := fmt.Sprintf(`{"patch": %q, "dry_run": true}`, diff)
+		result, err := tool.Call(ctx, input)
+		require.NoError(t, err)
+		assert.Contains(t, result, "Dry run: patch applies cleanly")
+		assert.Equal(t, patchTestFile, readPatchTestFile(t, path))
+	})
+
+	t.Run("failed hunk leaves every file untouched", func(t *testing.T) {
+		dir := t.TempDir()
+		first := writePatchTestFile(t, dir, "first.txt", "one\n")
+		second := writePatchTestFile(t, dir, "second.txt", "two\n")
+
+		diff := fmt.Sprintf(`--- %s
++++ %[1]s
+@@ -1 +1 @@
+-one
++ONE
+--- %s
++++ %[2]s
+@@ -1 +1 @@
+-three
++THREE
+`, first, second)
+
+		_, err := tool.Call(ctx, diff)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "second.txt")
+		assert.Equal(t, "one\n", readPatchTestFile(t, first))
+		assert.Equal(t, "two\n", readPatchTestFile(t, second))
+	})
+
+	t.Run("creating an existing file fails", func(t *testing.T) {
+		dir := t.TempDir()
+		path := writePatchTestFile(t, dir, "exists.txt", "here\n")
+		diff := fmt.Sprintf("--- /dev/null\n+++ %s\n@@ -0,0 +1 @@\n+new\n", path)
+
+		_, err := tool.Call(ctx, diff)
+		assert.Error(t, err)
+		assert.Equal(t, "here\n", readPatchTestFile(t, path))
+	})
+}
+
+func TestPatchToolRollback(t *testing.T) {
+	dir := t.TempDir()
+	first := writePatchTestFile(t, dir, "first.txt", "one\n")
+	blocker := writePatchTestFile(t, dir, "blocker", "not a directory\n")
+
+	// The second write fails because its parent is a regular file
+	err := commitPatches([]pendingFile{
+		{patch: FilePatch{OldPath: first, NewPath: first}, content: "ONE\n", mode: 0644},
+		{patch: FilePatch{OldPath: devNull, NewPath: filepath.Join(blocker, "new.txt")}, content: "new\n", mode: 0644},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "patch rolled back")
+	assert.Equal(t, "one\n", readPatchTestFile(t, first))
+}
+
+func TestPatchToolPermissions(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	dir := t.TempDir()
+	path := writePatchTestFile(t, dir, "main.go", patchTestFile)
+	diff := fmt.Sprintf("--- %[1]s\n+++ %[1]s\n@@ -6 +6 @@\n-\tfmt.Println(\"hello\")\n+\tfmt.Println(\"world\")\n", path)
+
+	// Default permissions do not include FileWrite
+	tool := NewPatchTool()
+	_, err := tool.Call(context.Background(), diff)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "FileWrite")
+	assert.Equal(t, patchTestFile, readPatchTestFile(t, path))
+}
+
+func TestFileChangesAreCheckpointed(t *testing.T) {
+	store, err := checkpoint.NewStore(t.TempDir(), "session_test")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	path := writePatchTestFile(t, dir, "main.go", patchTestFile)
+	written := filepath.Join(dir, "written.txt")
+	ctx := context.Background()
+
+	store.BeginTurn("edit files")
+	diff := fmt.Sprintf("--- %[1]s\n+++ %[1]s\n@@ -6 +6 @@\n-\tfmt.Println(\"hello\")\n+\tfmt.Println(\"world\")\n", path)
+	patchTool := NewPatchToolWithBypass(true)
+	patchTool.SetCheckpoints(store)
+	_, err = patchTool.Call(ctx, diff)
+	require.NoError(t, err)
+	writeTool := NewFileWriteToolWithBypass(true)
+	writeTool.SetCheckpoints(store)
+	_, err = writeTool.Call(ctx, written+":::content")
+	require.NoError(t, err)
+
+	// No backup files are left next to the originals
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	restored, err := store.Rewind(1)
+	require.NoError(t, err)
+	assert.Len(t, restored, 2)
+	assert.Equal(t, patchTestFile, readPatchTestFile(t, path))
+	assert.NoFileExists(t, written)
+}
@@ -1,6 +1,8 @@
 package registry
 
 import (
+	"encoding/json"
+
 	"github.com/killallgit/ryan/pkg/config"
 	"github.com/tmc/langchaingo/tools"
 )
@@ -22,6 +24,9 @@ type Registry interface {
 	// GetEnabled returns all enabled tools based on configuration
 	GetEnabled(settings *config.Settings, skipPermissions bool) []tools.Tool
 
+	// Unregister removes a tool factory by name
+	Unregister(name string)
+
 	// IsRegistered checks if a tool is registered
 	IsRegistered(name string) bool
 
@@ -29,6 +34,11 @@ type Registry interface {
 	Clear()
 }
 
+// SchemaProvider is implemented by tools that accept a JSON object described by a JSON schema
+type SchemaProvider interface {
+	InputSchema() json.RawMessage
+}
+
 // ToolInfo contains metadata about a registered tool
 type ToolInfo struct {
 	Name        string
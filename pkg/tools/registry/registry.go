@@ -2,6 +2,7 @@ package registry
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/killallgit/ryan/pkg/config"
@@ -95,16 +96,23 @@ func (r *toolRegistry) GetEnabled(settings *config.Settings, skipPermissions boo
 
 	// Check each tool type and add if enabled
 	toolConfigs := map[string]bool{
-		"file_read":  settings.Tools.File.Read.Enabled,
-		"file_write": settings.Tools.File.Write.Enabled,
-		"git":        settings.Tools.Git.Enabled,
-		"ripgrep":    settings.Tools.Search.Enabled,
-		"webfetch":   settings.Tools.Web.Enabled,
-		"bash":       settings.Tools.Bash.Enabled,
+		"file_read":   settings.Tools.File.Read.Enabled,
+		"file_write":  settings.Tools.File.Write.Enabled,
+		"apply_patch": settings.Tools.Patch.Enabled,
+		"git":         settings.Tools.Git.Enabled,
+		"ripgrep":     settings.Tools.Search.Enabled,
+		"webfetch":    settings.Tools.Web.Enabled,
+		"bash":        settings.Tools.Bash.Enabled,
 	}
 
-	for toolName, isEnabled := range toolConfigs {
-		if !isEnabled {
+	builtinNames := make([]string, 0, len(toolConfigs))
+	for toolName := range toolConfigs {
+		builtinNames = append(builtinNames, toolName)
+	}
+	sort.Strings(builtinNames)
+
+	for _, toolName := range builtinNames {
+		if !toolConfigs[toolName] {
 			continue
 		}
 
@@ -119,10 +127,46 @@ func (r *toolRegistry) GetEnabled(settings *config.Settings, skipPermissions boo
 		logger.Debug("Added %s tool", toolName)
 	}
 
+	// Tools registered at runtime (e.g. from MCP servers) are enabled by their own configuration
+	var dynamicNames []string
+	for toolName := range r.factories {
+		if _, builtin := toolConfigs[toolName]; !builtin {
+			dynamicNames = append(dynamicNames, toolName)
+		}
+	}
+	sort.Strings(dynamicNames)
+
+	for _, toolName := range dynamicNames {
+		enabledTools = append(enabledTools, r.factories[toolName](skipPermissions))
+		logger.Debug("Added %s tool", toolName)
+	}
+
 	logger.Info("Initialized %d tools", len(enabledTools))
 	return enabledTools
 }
 
+// Unregister removes a tool factory by name
+func (r *toolRegistry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.factories, name)
+	logger.Debug("Unregistered tool: %s", name)
+}
+
+// ToolNames returns the names reported by every registered tool. Registry keys
+// can differ from tool names (ripgrep is "search"), so dynamic tools check both.
+func ToolNames(r Registry) map[string]bool {
+	names := make(map[string]bool)
+	for _, key := range r.GetAll() {
+		names[key] = true
+		if tool, err := r.Get(key, true); err == nil {
+			names[tool.Name()] = true
+		}
+	}
+	return names
+}
+
 // IsRegistered checks if a tool is registered
 func (r *toolRegistry) IsRegistered(name string) bool {
 	r.mu.RLock()
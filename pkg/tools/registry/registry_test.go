@@ -172,6 +172,37 @@ func TestGetEnabled(t *testing.T) {
 	assert.NotContains(t, toolNames, "file_write")
 }
 
+func TestGetEnabledIncludesDynamicTools(t *testing.T) {
+	r := New()
+	r.Register("bash", func(skipPermissions bool) tools.Tool {
+		return &mockTool{name: "bash", desc: "Bash"}
+	})
+	r.Register("mcp__fs__write", func(skipPermissions bool) tools.Tool {
+		return &mockTool{name: "mcp__fs__write", desc: "Remote write"}
+	})
+	r.Register("mcp__fs__read", func(skipPermissions bool) tools.Tool {
+		return &mockTool{name: "mcp__fs__read", desc: "Remote read"}
+	})
+
+	settings := &config.Settings{}
+	settings.Tools.Enabled = true
+	settings.Tools.Bash.Enabled = true
+
+	// Builtins come first, then runtime-registered tools in name order
+	var names []string
+	for _, tool := range r.GetEnabled(settings, false) {
+		names = append(names, tool.Name())
+	}
+	assert.Equal(t, []string{"bash", "mcp__fs__read", "mcp__fs__write"}, names)
+
+	r.Unregister("mcp__fs__read")
+	assert.False(t, r.IsRegistered("mcp__fs__read"))
+	assert.Len(t, r.GetEnabled(settings, false), 2)
+
+	settings.Tools.Enabled = false
+	assert.Empty(t, r.GetEnabled(settings, false))
+}
+
 func TestGlobalRegistry(t *testing.T) {
 	// Test that global registry is initialized
 	g := Global()
@@ -0,0 +1,52 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/killallgit/ryan/pkg/memory"
+)
+
+// RememberTool saves a fact to the project's long-term memory
+type RememberTool struct {
+	memory  *memory.LongTermMemory
+	session string
+}
+
+// NewRememberTool creates a remember tool that records facts for the given session
+func NewRememberTool(longTerm *memory.LongTermMemory, session string) *RememberTool {
+	return &RememberTool{memory: longTerm, session: session}
+}
+
+// Name returns the tool name
+func (t *RememberTool) Name() string {
+	return "remember"
+}
+
+// Description returns the tool description
+func (t *RememberTool) Description() string {
+	return `Save a fact about this project for future sessions, such as a decision, a convention or a user preference. ` +
+		`Keep it to one self-contained sentence. Input: JSON {"fact": "text"} or the plain text`
+}
+
+// Call saves the fact
+func (t *RememberTool) Call(ctx context.Context, input string) (string, error) {
+	fact := strings.TrimSpace(input)
+	if strings.HasPrefix(fact, "{") {
+		var args struct {
+			Fact string `json:"fact"`
+		}
+		if err := json.Unmarshal([]byte(fact), &args); err != nil {
+			return "", fmt.Errorf("invalid JSON input: %w", err)
+		}
+		fact = args.Fact
+	}
+
+	entry, err := t.memory.RememberFact(ctx, t.session, fact)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Remembered (%s): %s", entry.ID, entry.Content), nil
+}
@@ -0,0 +1,40 @@
+package tools
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/killallgit/ryan/pkg/embeddings"
+	"github.com/killallgit/ryan/pkg/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRememberTool(t *testing.T) {
+	longTerm, err := memory.NewLongTermMemory(filepath.Join(t.TempDir(), "memory.db"), "/src/ryan", embeddings.NewLocalEmbedder(64))
+	require.NoError(t, err)
+	t.Cleanup(func() { longTerm.Close() })
+
+	tool := NewRememberTool(longTerm, "session_test")
+	ctx := context.Background()
+
+	result, err := tool.Call(ctx, `{"fact": "Use testify for assertions"}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "Remembered (mem-")
+
+	_, err = tool.Call(ctx, "Errors are wrapped with %w")
+	require.NoError(t, err)
+
+	entries, err := longTerm.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "Errors are wrapped with %w", entries[0].Content)
+	assert.Equal(t, memory.KindFact, entries[0].Kind)
+	assert.Equal(t, "session_test", entries[0].Session)
+
+	for _, input := range []string{"", `{"fact": ""}`, `{"fact": `} {
+		_, err := tool.Call(ctx, input)
+		assert.Error(t, err, input)
+	}
+}
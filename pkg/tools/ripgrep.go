@@ -1,14 +1,76 @@
 package tools
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
 
+// Search output modes
+const (
+	SearchOutputContent          = "content"
+	SearchOutputFilesWithMatches = "files_with_matches"
+	SearchOutputCount            = "count"
+)
+
+// SearchOptions contains the structured arguments accepted by the search tool
+type SearchOptions struct {
+	// Pattern is the regular expression to search for
+	Pattern string `json:"pattern"`
+
+	// Path is the file or directory to search (defaults to the current directory)
+	Path string `json:"path,omitempty"`
+
+	// Glob limits the search to files matching the glob (e.g. "*.go")
+	Glob string `json:"glob,omitempty"`
+
+	// Type limits the search to a ripgrep file type (e.g. "go", "py")
+	Type string `json:"type,omitempty"`
+
+	// CaseInsensitive enables case-insensitive matching
+	CaseInsensitive bool `json:"case_insensitive,omitempty"`
+
+	// Context is the number of lines to show before and after each match
+	Context int `json:"context,omitempty"`
+
+	// Multiline allows patterns to span lines
+	Multiline bool `json:"multiline,omitempty"`
+
+	// OutputMode is one of content, files_with_matches or count
+	OutputMode string `json:"output_mode,omitempty"`
+
+	// MaxResults overrides the global result cap
+	MaxResults int `json:"max_results,omitempty"`
+}
+
+// searchTypeGlobs maps common ripgrep file types to globs for the grep fallback
+var searchTypeGlobs = map[string][]string{
+	"go":   {"*.go"},
+	"py":   {"*.py"},
+	"js":   {"*.js", "*.jsx", "*.mjs", "*.cjs"},
+	"ts":   {"*.ts", "*.tsx"},
+	"rust": {"*.rs"},
+	"java": {"*.java"},
+	"c":    {"*.c", "*.h"},
+	"cpp":  {"*.cpp", "*.cc", "*.cxx", "*.hpp", "*.hh", "*.h"},
+	"md":   {"*.md", "*.markdown"},
+	"json": {"*.json"},
+	"yaml": {"*.yaml", "*.yml"},
+	"sh":   {"*.sh", "*.bash"},
+	"html": {"*.html", "*.htm"},
+	"css":  {"*.css", "*.scss"},
+	"sql":  {"*.sql"},
+}
+
 // RipgrepTool implements code searching with ripgrep
 type RipgrepTool struct {
 	*SecuredTool
@@ -27,7 +89,7 @@ func NewRipgrepToolWithBypass(bypass bool) *RipgrepTool {
 	return &RipgrepTool{
 		SecuredTool: NewSecuredToolWithBypass(bypass),
 		timeout:     30 * time.Second,
-		maxResults:  1000,
+		maxResults:  200,
 		maxFileSize: "50M",
 	}
 }
@@ -39,34 +101,86 @@ func (t *RipgrepTool) Name() string {
 
 // Description returns the tool description
 func (t *RipgrepTool) Description() string {
-	return "Search files using ripgrep. Input: search pattern (supports regex)"
+	return `Search files using ripgrep. Input: either a bare search pattern (supports regex), or a JSON object ` +
+		`{"pattern": "...", "path": "dir or file", "glob": "*.go", "type": "go", "case_insensitive": true, ` +
+		`"context": 2, "multiline": false, "output_mode": "content|files_with_matches|count"}`
 }
 
 // Call executes the ripgrep search
 func (t *RipgrepTool) Call(ctx context.Context, input string) (string, error) {
-	// Trim whitespace from input
-	pattern := strings.TrimSpace(input)
-	if pattern == "" {
-		return "", fmt.Errorf("search pattern cannot be empty")
+	opts, err := parseSearchInput(input)
+	if err != nil {
+		return "", err
 	}
 
-	// Validate permissions
-	if err := t.ValidateAccess("Ripgrep", pattern); err != nil {
+	// Validate permissions. An explicit path is checked as RipgrepPath(<absolute path>)
+	// so directory patterns such as RipgrepPath(/src/project/*) can confine searches.
+	if err := t.ValidateAccess("Ripgrep", opts.Pattern); err != nil {
 		return "", err
 	}
+	if opts.Path != "" {
+		root, err := filepath.Abs(opts.Path)
+		if err != nil {
+			return "", fmt.Errorf("invalid search path %q: %w", opts.Path, err)
+		}
+		if err := t.ValidateAccess("RipgrepPath", root); err != nil {
+			return "", err
+		}
+	}
+
+	if opts.MaxResults <= 0 || opts.MaxResults > t.maxResults {
+		opts.MaxResults = t.maxResults
+	}
 
 	// Check if ripgrep is available
 	if !t.isRipgrepAvailable() {
-		return t.fallbackToGrep(ctx, pattern)
+		return t.fallbackToGrep(ctx, opts)
+	}
+
+	// Create context with timeout
+	cmdCtx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	// Execute ripgrep
+	cmd := exec.CommandContext(cmdCtx, "rg", t.buildRipgrepArgs(opts)...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+
+	// Handle errors
+	if err != nil {
+		if cmdCtx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("search timed out after %v", t.timeout)
+		}
+
+		// Exit code 1 means no matches found (not an error)
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return "No matches found", nil
+		}
+
+		// Exit code 2 with results means some files could not be read
+		if stdout.Len() == 0 {
+			return stderr.String(), fmt.Errorf("ripgrep failed: %w", err)
+		}
 	}
 
-	// Build ripgrep command with safety constraints
+	results, err := parseRipgrepJSON(&stdout, opts.MaxResults)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse ripgrep output: %w", err)
+	}
+
+	return results.format(opts.OutputMode), nil
+}
+
+// buildRipgrepArgs builds the ripgrep command line for the given options
+func (t *RipgrepTool) buildRipgrepArgs(opts SearchOptions) []string {
 	args := []string{
-		"--max-count", fmt.Sprintf("%d", t.maxResults),
+		"--json",
+		"--max-count", strconv.Itoa(opts.MaxResults),
 		"--max-filesize", t.maxFileSize,
-		"--line-number",
-		"--with-filename",
-		"--color", "never", // Disable color for clean output
 		// Exclude common directories
 		"--glob", "!.git/**",
 		"--glob", "!node_modules/**",
@@ -78,74 +192,292 @@ func (t *RipgrepTool) Call(ctx context.Context, input string) (string, error) {
 		"--glob", "!target/**",
 		"--glob", "!*.min.js",
 		"--glob", "!*.min.css",
-		pattern,
 	}
 
-	// Create context with timeout
-	cmdCtx, cancel := context.WithTimeout(ctx, t.timeout)
-	defer cancel()
+	if opts.Glob != "" {
+		args = append(args, "--glob", opts.Glob)
+	}
+	if opts.Type != "" {
+		args = append(args, "--type", opts.Type)
+	}
+	if opts.CaseInsensitive {
+		args = append(args, "--ignore-case")
+	}
+	if opts.Context > 0 && opts.OutputMode == SearchOutputContent {
+		args = append(args, "--context", strconv.Itoa(opts.Context))
+	}
+	if opts.Multiline {
+		args = append(args, "--multiline", "--multiline-dotall")
+	}
 
-	// Execute ripgrep
-	cmd := exec.CommandContext(cmdCtx, "rg", args...)
+	args = append(args, "--regexp", opts.Pattern, "--")
+	if opts.Path != "" {
+		args = append(args, opts.Path)
+	}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	return args
+}
 
-	err := cmd.Run()
+// jsonObjectStart matches input that opens a JSON object rather than a regex such as {\d+}
+var jsonObjectStart = regexp.MustCompile(`^\{\s*("|\})`)
+
+// parseSearchInput accepts either a bare pattern or a JSON object of SearchOptions
+func parseSearchInput(input string) (SearchOptions, error) {
+	trimmed := strings.TrimSpace(input)
 
-	// Get output
-	output := stdout.String()
-	if stderr.Len() > 0 && err != nil {
-		// Only include stderr if there was an error
-		output += "\n" + stderr.String()
+	opts := SearchOptions{Pattern: trimmed}
+	if jsonObjectStart.MatchString(trimmed) {
+		opts = SearchOptions{}
+		if err := json.Unmarshal([]byte(trimmed), &opts); err != nil {
+			return opts, fmt.Errorf("invalid JSON input: %w", err)
+		}
 	}
 
-	// Handle errors
-	if err != nil {
-		if cmdCtx.Err() == context.DeadlineExceeded {
-			return output, fmt.Errorf("search timed out after %v", t.timeout)
+	if opts.Pattern == "" {
+		return opts, fmt.Errorf("search pattern cannot be empty")
+	}
+
+	switch opts.OutputMode {
+	case "":
+		opts.OutputMode = SearchOutputContent
+	case SearchOutputContent, SearchOutputFilesWithMatches, SearchOutputCount:
+	default:
+		return opts, fmt.Errorf("invalid output_mode %q (use %s, %s or %s)",
+			opts.OutputMode, SearchOutputContent, SearchOutputFilesWithMatches, SearchOutputCount)
+	}
+
+	if opts.Context < 0 {
+		opts.Context = 0
+	}
+
+	return opts, nil
+}
+
+// searchLine is a single matched or context line
+type searchLine struct {
+	number  int
+	text    string
+	isMatch bool
+}
+
+// fileResults groups the lines found in a single file
+type fileResults struct {
+	path    string
+	matches int
+	lines   []searchLine
+}
+
+// searchResults holds grouped search results with a global result cap
+type searchResults struct {
+	files        []*fileResults
+	byPath       map[string]*fileResults
+	totalMatches int
+	maxResults   int
+	truncated    bool
+	note         string
+}
+
+func newSearchResults(maxResults int) *searchResults {
+	return &searchResults{
+		byPath:     make(map[string]*fileResults),
+		maxResults: maxResults,
+	}
+}
+
+// file returns the group for a path, creating it if necessary
+func (r *searchResults) file(path string) *fileResults {
+	if f, ok := r.byPath[path]; ok {
+		return f
+	}
+	f := &fileResults{path: path}
+	r.byPath[path] = f
+	r.files = append(r.files, f)
+	return f
+}
+
+// addLine records a line, enforcing the global cap on matched lines
+func (r *searchResults) addLine(path string, number int, text string, isMatch bool) {
+	f := r.file(path)
+	if isMatch {
+		r.totalMatches++
+		f.matches++
+		if r.totalMatches > r.maxResults {
+			r.truncated = true
+			return
 		}
+	} else if r.truncated {
+		return
+	}
+	f.lines = append(f.lines, searchLine{number: number, text: strings.TrimRight(text, "\r\n"), isMatch: isMatch})
+}
 
-		// Exit code 1 means no matches found (not an error)
-		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
-			return "No matches found", nil
+// continueMatch records an additional line of a multiline match without counting it
+func (r *searchResults) continueMatch(path string, number int, text string) {
+	if r.truncated {
+		return
+	}
+	f := r.file(path)
+	f.lines = append(f.lines, searchLine{number: number, text: strings.TrimRight(text, "\r\n"), isMatch: true})
+}
+
+// format renders the results for the given output mode
+func (r *searchResults) format(mode string) string {
+	if r.totalMatches == 0 {
+		if r.note != "" {
+			return "No matches found " + r.note
+		}
+		return "No matches found"
+	}
+
+	var b strings.Builder
+	switch mode {
+	case SearchOutputFilesWithMatches:
+		for i, f := range r.files {
+			if i >= r.maxResults {
+				r.truncated = true
+				break
+			}
+			b.WriteString(f.path + "\n")
 		}
+	case SearchOutputCount:
+		for i, f := range r.files {
+			if i >= r.maxResults {
+				r.truncated = true
+				break
+			}
+			fmt.Fprintf(&b, "%s: %d\n", f.path, f.matches)
+		}
+	default:
+		for _, f := range r.files {
+			if len(f.lines) == 0 {
+				continue
+			}
+			b.WriteString(f.path + "\n")
+			prev := 0
+			for _, line := range f.lines {
+				if prev > 0 && line.number > prev+1 {
+					b.WriteString("  --\n")
+				}
+				sep := "-"
+				if line.isMatch {
+					sep = ":"
+				}
+				fmt.Fprintf(&b, "  %d%s %s\n", line.number, sep, line.text)
+				prev = line.number
+			}
+			b.WriteString("\n")
+		}
+	}
 
-		return output, fmt.Errorf("ripgrep failed: %w", err)
+	fmt.Fprintf(&b, "[Found %d matches in %d files]", r.totalMatches, len(r.files))
+	if r.truncated {
+		fmt.Fprintf(&b, "\n[Results truncated at %d matches]", r.maxResults)
+	}
+	if r.note != "" {
+		b.WriteString("\n" + r.note)
 	}
 
-	// Check if we hit the result limit
-	lineCount := strings.Count(output, "\n")
-	if lineCount >= t.maxResults {
-		output += fmt.Sprintf("\n\n[Results truncated at %d matches]", t.maxResults)
+	return strings.TrimSpace(b.String())
+}
+
+// rgText is ripgrep's representation of paths and lines, which may be raw bytes
+type rgText struct {
+	Text  string `json:"text"`
+	Bytes string `json:"bytes"`
+}
+
+func (t rgText) String() string {
+	if t.Text != "" || t.Bytes == "" {
+		return t.Text
 	}
+	decoded, err := base64.StdEncoding.DecodeString(t.Bytes)
+	if err != nil {
+		return t.Bytes
+	}
+	return string(decoded)
+}
+
+// rgMessage is a single line of ripgrep's --json output
+type rgMessage struct {
+	Type string `json:"type"`
+	Data struct {
+		Path       rgText `json:"path"`
+		Lines      rgText `json:"lines"`
+		LineNumber int    `json:"line_number"`
+	} `json:"data"`
+}
 
-	if output == "" {
-		return "No matches found", nil
+// parseRipgrepJSON parses ripgrep's --json output into grouped results
+func parseRipgrepJSON(output *bytes.Buffer, maxResults int) (*searchResults, error) {
+	results := newSearchResults(maxResults)
+
+	scanner := bufio.NewScanner(output)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		var msg rgMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			return nil, err
+		}
+
+		switch msg.Type {
+		case "match", "context":
+			// Multiline matches span several lines; number each of them
+			path := msg.Data.Path.String()
+			lines := strings.Split(strings.TrimRight(msg.Data.Lines.String(), "\n"), "\n")
+			for i, line := range lines {
+				if msg.Type == "match" && i > 0 {
+					results.continueMatch(path, msg.Data.LineNumber+i, line)
+					continue
+				}
+				results.addLine(path, msg.Data.LineNumber+i, line, msg.Type == "match")
+			}
+		}
 	}
 
-	return output, nil
+	return results, scanner.Err()
 }
 
 // isRipgrepAvailable checks if ripgrep is installed
 func (t *RipgrepTool) isRipgrepAvailable() bool {
-	cmd := exec.Command("which", "rg")
-	err := cmd.Run()
+	_, err := exec.LookPath("rg")
 	return err == nil
 }
 
 // fallbackToGrep uses standard grep if ripgrep is not available
-func (t *RipgrepTool) fallbackToGrep(ctx context.Context, pattern string) (string, error) {
+func (t *RipgrepTool) fallbackToGrep(ctx context.Context, opts SearchOptions) (string, error) {
 	// Create context with timeout
 	cmdCtx, cancel := context.WithTimeout(ctx, t.timeout)
 	defer cancel()
 
-	// Build grep command
+	// Build grep command. Include filters must come first: grep only skips
+	// files matching no filter when the first filter is an --include.
 	args := []string{
 		"-r", // Recursive
 		"-n", // Line numbers
 		"-I", // Skip binary files
+		"-Z", // NUL after file names so paths can be parsed unambiguously
+		"-E", // Extended regex, closest to ripgrep syntax
+	}
+
+	// grep ORs its --include filters where ripgrep ANDs --glob and --type,
+	// so the two cannot be combined faithfully
+	if opts.Glob != "" && opts.Type != "" {
+		return "", fmt.Errorf("glob and type cannot be combined without ripgrep; use one of them or install ripgrep")
+	}
+	if opts.Glob != "" {
+		args = append(args, "--include="+opts.Glob)
+	}
+	if opts.Type != "" {
+		globs, ok := searchTypeGlobs[opts.Type]
+		if !ok {
+			return "", fmt.Errorf("unsupported file type %q for grep fallback", opts.Type)
+		}
+		for _, glob := range globs {
+			args = append(args, "--include="+glob)
+		}
+	}
+
+	args = append(args,
 		"--exclude-dir=.git",
 		"--exclude-dir=node_modules",
 		"--exclude-dir=vendor",
@@ -156,9 +488,20 @@ func (t *RipgrepTool) fallbackToGrep(ctx context.Context, pattern string) (strin
 		"--exclude=*.pyc",
 		"--exclude=*.min.js",
 		"--exclude=*.min.css",
-		pattern,
-		".",
+	)
+
+	if opts.CaseInsensitive {
+		args = append(args, "-i")
 	}
+	if opts.Context > 0 && opts.OutputMode == SearchOutputContent {
+		args = append(args, "-C", strconv.Itoa(opts.Context))
+	}
+
+	path := opts.Path
+	if path == "" {
+		path = "."
+	}
+	args = append(args, "-e", opts.Pattern, "--", path)
 
 	cmd := exec.CommandContext(cmdCtx, "grep", args...)
 
@@ -168,32 +511,53 @@ func (t *RipgrepTool) fallbackToGrep(ctx context.Context, pattern string) (strin
 
 	err := cmd.Run()
 
-	output := stdout.String()
+	note := "(using grep fallback - install ripgrep for better performance)"
+	if opts.Multiline {
+		note = "(using grep fallback - multiline matching is not supported, install ripgrep)"
+	}
 
 	if err != nil {
 		if cmdCtx.Err() == context.DeadlineExceeded {
-			return output, fmt.Errorf("search timed out after %v", t.timeout)
+			return "", fmt.Errorf("search timed out after %v", t.timeout)
 		}
 
 		// Exit code 1 means no matches found (not an error)
 		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
-			return "No matches found (using grep fallback)", nil
+			return "No matches found " + note, nil
 		}
 
-		return output, fmt.Errorf("grep failed: %w", err)
+		if stdout.Len() == 0 {
+			return stderr.String(), fmt.Errorf("grep failed: %w", err)
+		}
 	}
 
-	// Limit results
-	lines := strings.Split(output, "\n")
-	if len(lines) > t.maxResults {
-		lines = lines[:t.maxResults]
-		output = strings.Join(lines, "\n")
-		output += fmt.Sprintf("\n\n[Results truncated at %d matches (using grep fallback)]", t.maxResults)
-	}
+	results := parseGrepOutput(stdout.String(), opts.MaxResults)
+	results.note = note
+	return results.format(opts.OutputMode), nil
+}
+
+// parseGrepOutput parses `grep -rnZ` output into grouped results.
+// Matched lines look like "path\x00N:text" and context lines like "path\x00N-text".
+func parseGrepOutput(output string, maxResults int) *searchResults {
+	results := newSearchResults(maxResults)
 
-	if output == "" {
-		return "No matches found (using grep fallback)", nil
+	for _, line := range strings.Split(output, "\n") {
+		path, rest, found := strings.Cut(line, "\x00")
+		if !found {
+			// Group separators ("--") and blank lines carry no information
+			continue
+		}
+
+		end := strings.IndexAny(rest, ":-")
+		if end <= 0 {
+			continue
+		}
+		number, err := strconv.Atoi(rest[:end])
+		if err != nil {
+			continue
+		}
+		results.addLine(path, number, rest[end+1:], rest[end] == ':')
 	}
 
-	return output + "\n\n[Note: Using grep fallback - install ripgrep for better performance]", nil
+	return results
 }
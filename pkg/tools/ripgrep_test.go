@@ -0,0 +1,184 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSearchInput(t *testing.T) {
+	t.Run("bare pattern", func(t *testing.T) {
+		opts, err := parseSearchInput("  func main  ")
+		require.NoError(t, err)
+		assert.Equal(t, "func main", opts.Pattern)
+		assert.Equal(t, SearchOutputContent, opts.OutputMode)
+	})
+
+	t.Run("structured options", func(t *testing.T) {
+		opts, err := parseSearchInput(`{"pattern": "TODO", "path": "pkg", "glob": "*.go", "case_insensitive": true, "context": 2, "output_mode": "count"}`)
+		require.NoError(t, err)
+		assert.Equal(t, "TODO", opts.Pattern)
+		assert.Equal(t, "pkg", opts.Path)
+		assert.Equal(t, "*.go", opts.Glob)
+		assert.True(t, opts.CaseInsensitive)
+		assert.Equal(t, 2, opts.Context)
+		assert.Equal(t, SearchOutputCount, opts.OutputMode)
+	})
+
+	t.Run("regex starting with brace is a bare pattern", func(t *testing.T) {
+		opts, err := parseSearchInput(`{\d+}`)
+		require.NoError(t, err)
+		assert.Equal(t, `{\d+}`, opts.Pattern)
+	})
+
+	t.Run("malformed JSON is an error", func(t *testing.T) {
+		_, err := parseSearchInput(`{"pattern": "x",}`)
+		assert.ErrorContains(t, err, "invalid JSON input")
+	})
+
+	t.Run("empty pattern", func(t *testing.T) {
+		_, err := parseSearchInput(`{"path": "pkg"}`)
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid output mode", func(t *testing.T) {
+		_, err := parseSearchInput(`{"pattern": "x", "output_mode": "json"}`)
+		assert.Error(t, err)
+	})
+}
+
+func TestParseRipgrepJSON(t *testing.T) {
+	output := bytes.NewBufferString(`{"type":"begin","data":{"path":{"text":"a.go"}}}
+{"type":"context","data":{"path":{"text":"a.go"},"lines":{"text":"package a\n"},"line_number":1}}
+{"type":"match","data":{"path":{"text":"a.go"},"lines":{"text":"func Foo() {}\n"},"line_number":2}}
+{"type":"match","data":{"path":{"text":"a.go"},"lines":{"text":"func Bar() {}\n"},"line_number":9}}
+{"type":"end","data":{"path":{"text":"a.go"}}}
+{"type":"match","data":{"path":{"bytes":"Yi5nbw=="},"lines":{"text":"func Baz() {}\n"},"line_number":3}}
+{"type":"summary","data":{}}
+`)
+
+	results, err := parseRipgrepJSON(output, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 3, results.totalMatches)
+	require.Len(t, results.files, 2)
+	assert.Equal(t, "b.go", results.files[1].path)
+
+	content := results.format(SearchOutputContent)
+	assert.Contains(t, content, "a.go\n  1- package a\n  2: func Foo() {}\n  --\n  9: func Bar() {}")
+	assert.Contains(t, content, "[Found 3 matches in 2 files]")
+
+	assert.Equal(t, "a.go\nb.go\n[Found 3 matches in 2 files]", results.format(SearchOutputFilesWithMatches))
+	assert.Equal(t, "a.go: 2\nb.go: 1\n[Found 3 matches in 2 files]", results.format(SearchOutputCount))
+}
+
+func TestSearchResultsCap(t *testing.T) {
+	results := newSearchResults(2)
+	results.addLine("a.go", 1, "one", true)
+	results.addLine("a.go", 2, "two", true)
+	results.addLine("b.go", 1, "three", true)
+
+	content := results.format(SearchOutputContent)
+	assert.NotContains(t, content, "three")
+	assert.Contains(t, content, "[Results truncated at 2 matches]")
+}
+
+func TestGrepFallback(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc Hello() {}\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.md"), []byte("hello world\n"), 0644))
+
+	tool := NewRipgrepToolWithBypass(true)
+	ctx := context.Background()
+
+	t.Run("glob and case insensitive", func(t *testing.T) {
+		result, err := tool.fallbackToGrep(ctx, SearchOptions{
+			Pattern:         "hello",
+			Path:            dir,
+			Glob:            "*.go",
+			CaseInsensitive: true,
+			OutputMode:      SearchOutputContent,
+			MaxResults:      10,
+		})
+		require.NoError(t, err)
+		assert.Contains(t, result, "main.go")
+		assert.Contains(t, result, "3: func Hello() {}")
+		assert.NotContains(t, result, "notes.md")
+	})
+
+	t.Run("files with matches", func(t *testing.T) {
+		result, err := tool.fallbackToGrep(ctx, SearchOptions{
+			Pattern:    "ello",
+			Path:       dir,
+			OutputMode: SearchOutputFilesWithMatches,
+			MaxResults: 10,
+		})
+		require.NoError(t, err)
+		assert.Contains(t, result, "main.go")
+		assert.Contains(t, result, "notes.md")
+		assert.Contains(t, result, "[Found 2 matches in 2 files]")
+	})
+
+	t.Run("type filter and context", func(t *testing.T) {
+		result, err := tool.fallbackToGrep(ctx, SearchOptions{
+			Pattern:    "Hello",
+			Path:       dir,
+			Type:       "go",
+			Context:    1,
+			OutputMode: SearchOutputContent,
+			MaxResults: 10,
+		})
+		require.NoError(t, err)
+		assert.Contains(t, result, "2- ")
+		assert.Contains(t, result, "3: func Hello() {}")
+	})
+
+	t.Run("glob and type are not combined", func(t *testing.T) {
+		_, err := tool.fallbackToGrep(ctx, SearchOptions{
+			Pattern:    "Hello",
+			Path:       dir,
+			Glob:       "*.md",
+			Type:       "go",
+			OutputMode: SearchOutputContent,
+			MaxResults: 10,
+		})
+		assert.ErrorContains(t, err, "cannot be combined")
+	})
+
+	t.Run("no matches", func(t *testing.T) {
+		result, err := tool.fallbackToGrep(ctx, SearchOptions{
+			Pattern:    "nothing_here",
+			Path:       dir,
+			OutputMode: SearchOutputContent,
+			MaxResults: 10,
+		})
+		require.NoError(t, err)
+		assert.Contains(t, result, "No matches found")
+	})
+}
+
+func TestRipgrepValidatesSearchPath(t *testing.T) {
+	home := t.TempDir()
+	allowed := filepath.Join(home, "project")
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".ryan"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(allowed, "src"), 0755))
+	settings := `{"permissions": {"allow": ["Ripgrep(*)", "RipgrepPath(` + allowed + `/*)"]}}`
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".ryan", "settings.json"), []byte(settings), 0644))
+	t.Setenv("HOME", home)
+
+	tool := NewRipgrepTool()
+	ctx := context.Background()
+
+	_, err := tool.Call(ctx, `{"pattern": "secret", "path": "`+home+`"}`)
+	assert.ErrorContains(t, err, "permission denied")
+
+	_, err = tool.Call(ctx, `{"pattern": "secret", "path": "`+filepath.Join(allowed, "..", "..")+`"}`)
+	assert.ErrorContains(t, err, "permission denied", "paths are resolved before checking")
+
+	_, err = tool.Call(ctx, `{"pattern": "secret", "path": "`+filepath.Join(allowed, "src")+`"}`)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,116 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/killallgit/ryan/pkg/memory"
+)
+
+// TodoWriteTool replaces the agent's per-session task list
+type TodoWriteTool struct {
+	list *memory.TodoList
+}
+
+// NewTodoWriteTool creates a todo write tool backed by a session's todo list
+func NewTodoWriteTool(list *memory.TodoList) *TodoWriteTool {
+	return &TodoWriteTool{list: list}
+}
+
+// Name returns the tool name
+func (t *TodoWriteTool) Name() string {
+	return "todo_write"
+}
+
+// Description returns the tool description
+func (t *TodoWriteTool) Description() string {
+	return `Track progress on multi-step tasks. Replaces the whole todo list; send every item each time. ` +
+		`Mark one item in_progress while working on it and done as soon as it is finished. ` +
+		`Input: JSON {"todos": [{"id": "1", "content": "task", "status": "pending|in_progress|done"}]}`
+}
+
+// Call replaces the todo list
+func (t *TodoWriteTool) Call(ctx context.Context, input string) (string, error) {
+	todos, err := parseTodoInput(input)
+	if err != nil {
+		return "", err
+	}
+
+	saved, err := t.list.Set(todos)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Todo list updated (%s)\n%s", summarizeTodos(saved), memory.FormatTodos(saved)), nil
+}
+
+// parseTodoInput accepts {"todos": [...]} or a bare array of todos
+func parseTodoInput(input string) ([]memory.Todo, error) {
+	trimmed := strings.TrimSpace(input)
+	if trimmed == "" {
+		return nil, fmt.Errorf("input cannot be empty")
+	}
+
+	var todos []memory.Todo
+	if strings.HasPrefix(trimmed, "[") {
+		if err := json.Unmarshal([]byte(trimmed), &todos); err != nil {
+			return nil, fmt.Errorf("invalid JSON input: %w", err)
+		}
+		return todos, nil
+	}
+
+	var wrapper struct {
+		Todos *[]memory.Todo `json:"todos"`
+	}
+	if err := json.Unmarshal([]byte(trimmed), &wrapper); err != nil {
+		return nil, fmt.Errorf("invalid JSON input: %w", err)
+	}
+	if wrapper.Todos == nil {
+		return nil, fmt.Errorf(`input must contain a "todos" array`)
+	}
+	return *wrapper.Todos, nil
+}
+
+// summarizeTodos counts todos by status
+func summarizeTodos(todos []memory.Todo) string {
+	counts := make(map[memory.TodoStatus]int)
+	for _, todo := range todos {
+		counts[todo.Status]++
+	}
+	return fmt.Sprintf("%d done, %d in progress, %d pending",
+		counts[memory.TodoDone], counts[memory.TodoInProgress], counts[memory.TodoPending])
+}
+
+// TodoReadTool returns the agent's per-session task list
+type TodoReadTool struct {
+	list *memory.TodoList
+}
+
+// NewTodoReadTool creates a todo read tool backed by a session's todo list
+func NewTodoReadTool(list *memory.TodoList) *TodoReadTool {
+	return &TodoReadTool{list: list}
+}
+
+// Name returns the tool name
+func (t *TodoReadTool) Name() string {
+	return "todo_read"
+}
+
+// Description returns the tool description
+func (t *TodoReadTool) Description() string {
+	return "Read the current todo list to check progress on a multi-step task. Input is ignored."
+}
+
+// Call returns the todo list
+func (t *TodoReadTool) Call(ctx context.Context, input string) (string, error) {
+	todos, err := t.list.Get()
+	if err != nil {
+		return "", err
+	}
+	if len(todos) == 0 {
+		return "No todos. Use todo_write to plan multi-step tasks.", nil
+	}
+	return fmt.Sprintf("%s\n%s", summarizeTodos(todos), memory.FormatTodos(todos)), nil
+}
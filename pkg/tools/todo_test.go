@@ -0,0 +1,47 @@
+package tools
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/killallgit/ryan/pkg/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTodoTools(t *testing.T) {
+	list, err := memory.NewTodoList(filepath.Join(t.TempDir(), "memory.db"), "session_test")
+	require.NoError(t, err)
+	t.Cleanup(func() { list.Close() })
+
+	write := NewTodoWriteTool(list)
+	read := NewTodoReadTool(list)
+	ctx := context.Background()
+
+	result, err := read.Call(ctx, "")
+	require.NoError(t, err)
+	assert.Contains(t, result, "No todos")
+
+	result, err = write.Call(ctx, `{"todos": [{"content": "plan", "status": "done"}, {"content": "build", "status": "in_progress"}, {"content": "ship"}]}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "1 done, 1 in progress, 1 pending")
+	assert.Contains(t, result, "- [~] 2. build")
+
+	result, err = read.Call(ctx, "")
+	require.NoError(t, err)
+	assert.Contains(t, result, "- [x] 1. plan")
+	assert.Contains(t, result, "- [ ] 3. ship")
+
+	// A bare array is accepted too
+	_, err = write.Call(ctx, `[{"id": "a", "content": "only", "status": "done"}]`)
+	require.NoError(t, err)
+	todos, err := list.Get()
+	require.NoError(t, err)
+	assert.Equal(t, []memory.Todo{{ID: "a", Content: "only", Status: memory.TodoDone}}, todos)
+
+	for _, input := range []string{"", "not json", `{"items": []}`, `{"todos": [{"content": "x", "status": "later"}]}`} {
+		_, err := write.Call(ctx, input)
+		assert.Error(t, err, input)
+	}
+}
@@ -0,0 +1,427 @@
+package tools
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// devNull is the path used by unified diffs for created and deleted files
+const devNull = "/dev/null"
+
+// FilePatch contains the hunks of a unified diff for a single file
+type FilePatch struct {
+	// OldPath is the source path, or /dev/null for created files
+	OldPath string
+
+	// NewPath is the destination path, or /dev/null for deleted files
+	NewPath string
+
+	Hunks []Hunk
+}
+
+// IsCreate reports whether the patch creates a new file
+func (p FilePatch) IsCreate() bool {
+	return p.OldPath == devNull
+}
+
+// IsDelete reports whether the patch deletes a file
+func (p FilePatch) IsDelete() bool {
+	return p.NewPath == devNull
+}
+
+// Path returns the file the patch writes to, or removes when deleting
+func (p FilePatch) Path() string {
+	if p.IsDelete() {
+		return p.OldPath
+	}
+	return p.NewPath
+}
+
+// Hunk is a single @@ section of a unified diff
+type Hunk struct {
+	OldStart int
+	NewStart int
+
+	// OldCount and NewCount are the line counts from the hunk header
+	OldCount int
+	NewCount int
+
+	Lines []HunkLine
+
+	// OldNoEOL and NewNoEOL record "\ No newline at end of file" markers
+	OldNoEOL bool
+	NewNoEOL bool
+}
+
+// HunkLine is a context (' '), removed ('-') or added ('+') line
+type HunkLine struct {
+	Op   byte
+	Text string
+}
+
+// oldLines returns the lines the hunk expects to find in the original file
+func (h Hunk) oldLines() []string {
+	var lines []string
+	for _, l := range h.Lines {
+		if l.Op != '+' {
+			lines = append(lines, l.Text)
+		}
+	}
+	return lines
+}
+
+// ParseUnifiedDiff parses a unified diff, as produced by diff -u or git diff,
+// into per-file patches. Hunk line counts are not trusted to end a hunk; hunks
+// end at the next hunk or file header. They are used to tell "--- "/"+++ "
+// file headers from removed and added lines with the same prefix: a header
+// is only recognised once the current hunk's counts are used up.
+func ParseUnifiedDiff(diff string) ([]FilePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(diff, "\r\n", "\n"), "\n")
+	// Drop trailing blank lines left by the final newline or surrounding text
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	var patches []FilePatch
+	var current *FilePatch
+	var hunk *Hunk
+	// oldLeft and newLeft count the lines the current hunk header still expects
+	var oldLeft, newLeft int
+
+	flushHunk := func() {
+		if current != nil && hunk != nil {
+			current.Hunks = append(current.Hunks, *hunk)
+		}
+		hunk = nil
+	}
+	flushFile := func() {
+		flushHunk()
+		if current != nil {
+			patches = append(patches, *current)
+		}
+		current = nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") &&
+			(hunk == nil || (oldLeft <= 0 && newLeft <= 0)):
+			flushFile()
+			current = &FilePatch{
+				OldPath: diffHeaderPath(line[4:]),
+				NewPath: diffHeaderPath(lines[i+1][4:]),
+			}
+			stripGitPrefixes(current)
+			i++
+		case strings.HasPrefix(line, "@@"):
+			if current == nil {
+				return nil, fmt.Errorf("line %d: hunk without file header", i+1)
+			}
+			flushHunk()
+			h, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			hunk = &h
+			oldLeft, newLeft = h.OldCount, h.NewCount
+		case hunk != nil && strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file" applies to the preceding line
+			if n := len(hunk.Lines); n > 0 {
+				switch hunk.Lines[n-1].Op {
+				case '-':
+					hunk.OldNoEOL = true
+				case '+':
+					hunk.NewNoEOL = true
+				default:
+					hunk.OldNoEOL = true
+					hunk.NewNoEOL = true
+				}
+			}
+		case hunk != nil && line == "" && oldLeft > 0 && newLeft > 0:
+			// Editors and models often strip the leading space from blank context
+			// lines; once the hunk's counts are used up a blank line ends it
+			hunk.Lines = append(hunk.Lines, HunkLine{Op: ' '})
+			oldLeft--
+			newLeft--
+		case hunk != nil && line != "" && (line[0] == ' ' || line[0] == '-' || line[0] == '+'):
+			hunk.Lines = append(hunk.Lines, HunkLine{Op: line[0], Text: line[1:]})
+			if line[0] != '+' {
+				oldLeft--
+			}
+			if line[0] != '-' {
+				newLeft--
+			}
+		default:
+			// Anything else (diff --git, index, mode lines, commentary) ends the hunk
+			flushHunk()
+		}
+	}
+	flushFile()
+
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("no file headers (--- / +++) found in patch")
+	}
+	for _, p := range patches {
+		if p.OldPath == devNull && p.NewPath == devNull {
+			return nil, fmt.Errorf("patch has /dev/null as both source and destination")
+		}
+		if len(p.Hunks) == 0 {
+			return nil, fmt.Errorf("no hunks found for %s", p.Path())
+		}
+	}
+
+	return patches, nil
+}
+
+// diffHeaderPath extracts the path from a ---/+++ header, dropping timestamps
+func diffHeaderPath(header string) string {
+	if idx := strings.Index(header, "\t"); idx >= 0 {
+		header = header[:idx]
+	}
+	path := strings.TrimSpace(header)
+	if unquoted, err := strconv.Unquote(path); err == nil {
+		path = unquoted
+	}
+	return path
+}
+
+// stripGitPrefixes removes the a/ and b/ prefixes added by git diff
+func stripGitPrefixes(p *FilePatch) {
+	oldGit := p.OldPath == devNull || strings.HasPrefix(p.OldPath, "a/")
+	newGit := p.NewPath == devNull || strings.HasPrefix(p.NewPath, "b/")
+	if !oldGit || !newGit {
+		return
+	}
+	p.OldPath = strings.TrimPrefix(p.OldPath, "a/")
+	p.NewPath = strings.TrimPrefix(p.NewPath, "b/")
+}
+
+// parseHunkHeader parses "@@ -oldStart[,oldCount] +newStart[,newCount] @@"
+// into an empty hunk. An omitted count is 1.
+func parseHunkHeader(line string) (Hunk, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return Hunk{}, fmt.Errorf("malformed hunk header: %s", line)
+	}
+
+	parseRange := func(field string) (int, int, error) {
+		startText, countText, hasCount := strings.Cut(field[1:], ",")
+		start, err := strconv.Atoi(startText)
+		if err != nil {
+			return 0, 0, err
+		}
+		if !hasCount {
+			return start, 1, nil
+		}
+		count, err := strconv.Atoi(countText)
+		if err != nil || count < 0 {
+			return 0, 0, fmt.Errorf("invalid count %q", countText)
+		}
+		return start, count, nil
+	}
+
+	oldStart, oldCount, err := parseRange(fields[1])
+	if err != nil {
+		return Hunk{}, fmt.Errorf("malformed hunk header: %s", line)
+	}
+	newStart, newCount, err := parseRange(fields[2])
+	if err != nil {
+		return Hunk{}, fmt.Errorf("malformed hunk header: %s", line)
+	}
+	return Hunk{OldStart: oldStart, OldCount: oldCount, NewStart: newStart, NewCount: newCount}, nil
+}
+
+// HunkResult describes where and how a hunk was applied
+type HunkResult struct {
+	// Line is the 1-based line in the original file where the hunk matched
+	Line int
+
+	// Offset is the distance from the line given in the hunk header
+	Offset int
+
+	// Fuzz is the number of context lines ignored at each end to find a match
+	Fuzz int
+}
+
+// fileContent is a file split into lines with its trailing newline state
+type fileContent struct {
+	lines []string
+	eol   bool
+}
+
+// splitFileContent splits file data into lines
+func splitFileContent(data string) fileContent {
+	if data == "" {
+		return fileContent{eol: true}
+	}
+	eol := strings.HasSuffix(data, "\n")
+	return fileContent{
+		lines: strings.Split(strings.TrimSuffix(data, "\n"), "\n"),
+		eol:   eol,
+	}
+}
+
+// String joins the lines back into file data
+func (f fileContent) String() string {
+	if len(f.lines) == 0 {
+		return ""
+	}
+	data := strings.Join(f.lines, "\n")
+	if f.eol {
+		data += "\n"
+	}
+	return data
+}
+
+// applyHunks applies hunks in order to the original content. Each hunk is
+// searched for near its expected position, first exactly, then ignoring
+// trailing whitespace, then dropping up to maxFuzz context lines at each end.
+func applyHunks(original string, hunks []Hunk, maxFuzz int) (string, []HunkResult, error) {
+	src := splitFileContent(original)
+	out := fileContent{eol: src.eol}
+	results := make([]HunkResult, 0, len(hunks))
+
+	cursor := 0 // next unconsumed line of the original
+	offset := 0 // drift between header line numbers and actual positions
+
+	for n, h := range hunks {
+		pos, lines, leading, fuzz, ok := locateHunk(src.lines, h, cursor, offset, maxFuzz)
+		if !ok {
+			return "", nil, fmt.Errorf("hunk #%d (@@ -%d +%d @@) does not match the file", n+1, h.OldStart, h.NewStart)
+		}
+
+		offset = pos - leading - hunkStart(h)
+		results = append(results, HunkResult{Line: pos - leading + 1, Offset: offset, Fuzz: fuzz})
+
+		out.lines = append(out.lines, src.lines[cursor:pos]...)
+		i := pos
+		for _, l := range lines {
+			switch l.Op {
+			case ' ':
+				// Keep the original text so whitespace-tolerant matches are not rewritten
+				out.lines = append(out.lines, src.lines[i])
+				i++
+			case '-':
+				i++
+			case '+':
+				out.lines = append(out.lines, l.Text)
+			}
+		}
+		cursor = i
+
+		// Only a hunk that reaches the end of the file decides the final newline
+		if cursor == len(src.lines) {
+			if h.NewNoEOL {
+				out.eol = false
+			} else if h.OldNoEOL || !src.eol {
+				out.eol = true
+			}
+		}
+	}
+
+	out.lines = append(out.lines, src.lines[cursor:]...)
+	return out.String(), results, nil
+}
+
+// hunkStart returns the 0-based line where a hunk is expected to apply
+func hunkStart(h Hunk) int {
+	// Pure insertions name the line they follow
+	if len(h.oldLines()) == 0 {
+		return h.OldStart
+	}
+	return max(h.OldStart-1, 0)
+}
+
+// locateHunk finds where a hunk applies, returning the start position, the
+// hunk lines after fuzz trimming, the number of leading lines trimmed and
+// the fuzz used
+func locateHunk(file []string, h Hunk, minPos, offset, maxFuzz int) (int, []HunkLine, int, int, bool) {
+	if len(h.oldLines()) == 0 {
+		pos := max(minPos, min(hunkStart(h)+offset, len(file)))
+		return pos, h.Lines, 0, 0, true
+	}
+
+	for fuzz := 0; fuzz <= maxFuzz; fuzz++ {
+		lines, leading, trimmed := trimContext(h.Lines, fuzz)
+		if fuzz > 0 && !trimmed {
+			// No context left to drop, further fuzz cannot help
+			break
+		}
+
+		var old []string
+		for _, l := range lines {
+			if l.Op != '+' {
+				old = append(old, l.Text)
+			}
+		}
+		if len(old) == 0 {
+			// Never apply a hunk blindly once all of its context is gone
+			break
+		}
+
+		expected := hunkStart(h) + offset + leading
+		for _, equal := range []func(a, b string) bool{exactLineEqual, looseLineEqual} {
+			if pos, ok := searchLines(file, old, expected, minPos, equal); ok {
+				return pos, lines, leading, fuzz, true
+			}
+		}
+	}
+	return 0, nil, 0, 0, false
+}
+
+// trimContext drops up to fuzz context lines from each end of a hunk,
+// returning the number of leading lines dropped and whether any were
+func trimContext(lines []HunkLine, fuzz int) ([]HunkLine, int, bool) {
+	start, end := 0, len(lines)
+	for start < end && start < fuzz && lines[start].Op == ' ' {
+		start++
+	}
+	for end > start && len(lines)-end < fuzz && lines[end-1].Op == ' ' {
+		end--
+	}
+	return lines[start:end], start, start > 0 || end < len(lines)
+}
+
+// searchLines looks for needle in file at positions >= minPos, starting at
+// expected and moving outward
+func searchLines(file, needle []string, expected, minPos int, equal func(a, b string) bool) (int, bool) {
+	last := len(file) - len(needle)
+	if last < minPos {
+		return 0, false
+	}
+	expected = max(minPos, min(expected, last))
+
+	if matchesAt(file, needle, expected, equal) {
+		return expected, true
+	}
+	for delta := 1; expected-delta >= minPos || expected+delta <= last; delta++ {
+		if pos := expected - delta; pos >= minPos && matchesAt(file, needle, pos, equal) {
+			return pos, true
+		}
+		if pos := expected + delta; pos <= last && matchesAt(file, needle, pos, equal) {
+			return pos, true
+		}
+	}
+	return 0, false
+}
+
+// matchesAt reports whether needle matches file starting at pos
+func matchesAt(file, needle []string, pos int, equal func(a, b string) bool) bool {
+	for i, line := range needle {
+		if !equal(file[pos+i], line) {
+			return false
+		}
+	}
+	return true
+}
+
+func exactLineEqual(a, b string) bool {
+	return a == b
+}
+
+func looseLineEqual(a, b string) bool {
+	return strings.TrimRight(a, " \t\r") == strings.TrimRight(b, " \t\r")
+}
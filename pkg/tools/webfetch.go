@@ -1,20 +1,42 @@
 package tools
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
+	"net"
 	"net/http"
+	"net/netip"
 	"net/url"
 	"strings"
 	"time"
+	"unicode/utf8"
+
+	"github.com/killallgit/ryan/pkg/config"
 )
 
+// blockedPrefixes are address ranges not covered by the netip helpers that
+// should never be reachable from the web fetch tool
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("100.64.0.0/10"), // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // Benchmarking
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, can embed private IPv4
+}
+
 // WebFetchTool implements web content fetching with permission checking
 type WebFetchTool struct {
 	*SecuredTool
 	client      *http.Client
 	maxBodySize int64
+	maxTokens   int
+
+	// Private, loopback and link-local destinations explicitly allowed by configuration
+	allowedPrivateHosts    map[string]bool
+	allowedPrivateNetworks []netip.Prefix
 }
 
 // NewWebFetchTool creates a new web fetch tool
@@ -24,20 +46,150 @@ func NewWebFetchTool() *WebFetchTool {
 
 // NewWebFetchToolWithBypass creates a new web fetch tool with optional permission bypass
 func NewWebFetchToolWithBypass(bypass bool) *WebFetchTool {
-	return &WebFetchTool{
-		SecuredTool: NewSecuredToolWithBypass(bypass),
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-			// Disable following redirects automatically to check permissions
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				if len(via) >= 10 {
-					return fmt.Errorf("too many redirects")
-				}
-				return nil
-			},
+	t := &WebFetchTool{
+		SecuredTool:         NewSecuredToolWithBypass(bypass),
+		maxBodySize:         10 * 1024 * 1024, // 10MB limit
+		maxTokens:           8000,
+		allowedPrivateHosts: make(map[string]bool),
+	}
+
+	if config.Global != nil {
+		t.allowPrivateHosts(config.Global.Tools.Web.AllowedPrivateHosts)
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	t.client = &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			// Never use environment proxies: the dialer must see the real destination
+			Proxy:               nil,
+			DialContext:         t.safeDialContext(dialer),
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+		// Re-check scheme and ACL permissions on every redirect hop
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("too many redirects")
+			}
+			if err := t.validateURL(req.URL); err != nil {
+				return fmt.Errorf("redirect to %s rejected: %w", req.URL, err)
+			}
+			return nil
 		},
-		maxBodySize: 10 * 1024 * 1024, // 10MB limit
 	}
+
+	return t
+}
+
+// allowPrivateHosts permits host names, IP addresses or CIDR ranges that would
+// otherwise be blocked as private, loopback or link-local destinations
+func (t *WebFetchTool) allowPrivateHosts(hosts []string) {
+	for _, host := range hosts {
+		host = strings.TrimSpace(host)
+		if host == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(host); err == nil {
+			t.allowedPrivateNetworks = append(t.allowedPrivateNetworks, prefix.Masked())
+			continue
+		}
+		if addr, err := netip.ParseAddr(host); err == nil {
+			addr = addr.Unmap()
+			t.allowedPrivateNetworks = append(t.allowedPrivateNetworks, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		t.allowedPrivateHosts[strings.ToLower(host)] = true
+	}
+}
+
+// validateURL checks the scheme and ACL permissions for a URL
+func (t *WebFetchTool) validateURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported URL scheme: %s (only http/https allowed)", u.Scheme)
+	}
+
+	// Check ACL with domain:path format
+	aclInput := fmt.Sprintf("%s:%s", u.Host, u.Path)
+	if err := t.ValidateAccess("WebFetch", aclInput); err != nil {
+		// Also try with just the domain
+		if err := t.ValidateAccess("WebFetch", u.Host); err != nil {
+			return fmt.Errorf("permission denied for URL %s: %w", u, err)
+		}
+	}
+
+	return nil
+}
+
+// safeDialContext resolves the destination, rejects blocked addresses and dials
+// the vetted IP directly so DNS cannot change between the check and the connection
+func (t *WebFetchTool) safeDialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+
+		addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", host, err)
+		}
+
+		var lastErr error
+		for _, addr := range addrs {
+			addr = addr.Unmap()
+			if isBlockedAddr(addr) && !t.isPrivateAllowed(host, addr) {
+				lastErr = fmt.Errorf("destination %s (%s) is a private, loopback or link-local address; "+
+					"add it to tools.web.allowed_private_hosts to allow it", host, addr)
+				continue
+			}
+
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+
+		if lastErr == nil {
+			lastErr = fmt.Errorf("no addresses found for %s", host)
+		}
+		return nil, lastErr
+	}
+}
+
+// isPrivateAllowed reports whether a blocked destination was explicitly allowed
+func (t *WebFetchTool) isPrivateAllowed(host string, addr netip.Addr) bool {
+	if t.allowedPrivateHosts[strings.ToLower(host)] {
+		return true
+	}
+	for _, prefix := range t.allowedPrivateNetworks {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// isBlockedAddr reports whether an address is private, loopback, link-local
+// (including cloud metadata endpoints) or otherwise not public unicast
+func isBlockedAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() ||
+		addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() ||
+		addr.IsUnspecified() {
+		return true
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
 }
 
 // Name returns the tool name
@@ -47,15 +199,58 @@ func (t *WebFetchTool) Name() string {
 
 // Description returns the tool description
 func (t *WebFetchTool) Description() string {
-	return "Fetch content from a URL. Input: URL string"
+	return `Fetch content from a URL. HTML pages are converted to Markdown. Input: either a URL string, or a JSON object ` +
+		`{"url": "...", "selector": "CSS selector to extract", "main_content": true, "max_tokens": 4000, "raw": false}`
+}
+
+// WebFetchOptions contains the structured arguments accepted by the web fetch tool
+type WebFetchOptions struct {
+	// URL to fetch
+	URL string `json:"url"`
+
+	// Selector extracts only elements matching a CSS selector from HTML pages
+	Selector string `json:"selector,omitempty"`
+
+	// MainContent extracts only the page's main content from HTML pages
+	MainContent bool `json:"main_content,omitempty"`
+
+	// MaxTokens caps the size of the returned content
+	MaxTokens int `json:"max_tokens,omitempty"`
+
+	// Raw disables HTML to Markdown conversion
+	Raw bool `json:"raw,omitempty"`
+}
+
+// parseWebFetchInput accepts either a bare URL or a JSON object of WebFetchOptions
+func parseWebFetchInput(input string) (WebFetchOptions, error) {
+	trimmed := strings.TrimSpace(input)
+
+	opts := WebFetchOptions{URL: trimmed}
+	if strings.HasPrefix(trimmed, "{") {
+		if err := json.Unmarshal([]byte(trimmed), &opts); err != nil {
+			return opts, fmt.Errorf("invalid JSON input: %w", err)
+		}
+		opts.URL = strings.TrimSpace(opts.URL)
+	}
+
+	if opts.URL == "" {
+		return opts, fmt.Errorf("URL cannot be empty")
+	}
+
+	return opts, nil
 }
 
 // Call executes the web fetch operation
 func (t *WebFetchTool) Call(ctx context.Context, input string) (string, error) {
-	// Trim whitespace from input
-	urlStr := strings.TrimSpace(input)
-	if urlStr == "" {
-		return "", fmt.Errorf("URL cannot be empty")
+	opts, err := parseWebFetchInput(input)
+	if err != nil {
+		return "", err
+	}
+	urlStr := opts.URL
+
+	maxTokens := opts.MaxTokens
+	if maxTokens <= 0 || maxTokens > t.maxTokens {
+		maxTokens = t.maxTokens
 	}
 
 	// Parse URL
@@ -66,22 +261,15 @@ func (t *WebFetchTool) Call(ctx context.Context, input string) (string, error) {
 
 	// Ensure scheme is present
 	if u.Scheme == "" {
-		u.Scheme = "https"
+		u, err = url.Parse("https://" + urlStr)
+		if err != nil {
+			return "", fmt.Errorf("invalid URL: %w", err)
+		}
 		urlStr = u.String()
 	}
 
-	// Validate scheme
-	if u.Scheme != "http" && u.Scheme != "https" {
-		return "", fmt.Errorf("unsupported URL scheme: %s (only http/https allowed)", u.Scheme)
-	}
-
-	// Check ACL with domain:path format
-	aclInput := fmt.Sprintf("%s:%s", u.Host, u.Path)
-	if err := t.ValidateAccess("WebFetch", aclInput); err != nil {
-		// Also try with just the domain
-		if err := t.ValidateAccess("WebFetch", u.Host); err != nil {
-			return "", fmt.Errorf("permission denied for URL %s: %w", urlStr, err)
-		}
+	if err := t.validateURL(u); err != nil {
+		return "", err
 	}
 
 	// Create request with context
@@ -119,21 +307,72 @@ func (t *WebFetchTool) Call(ctx context.Context, input string) (string, error) {
 		return "", fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	// Check if we hit the size limit
-	if int64(len(body)) >= t.maxBodySize {
-		return string(body) + fmt.Sprintf("\n\n[Content truncated at %d MB]", t.maxBodySize/(1024*1024)), nil
+	// Report where the content actually came from after any redirects
+	finalURL := resp.Request.URL.String()
+
+	if len(body) == 0 {
+		return fmt.Sprintf("[Empty response from %s]", finalURL), nil
 	}
 
-	// Add metadata about the fetch
 	result := string(body)
-	if result == "" {
-		result = fmt.Sprintf("[Empty response from %s]", urlStr)
-	}
 
-	// Add content type info if it's not HTML or text
-	if !strings.Contains(contentType, "text/") && !strings.Contains(contentType, "application/json") {
+	// Convert HTML to Markdown unless raw output was requested
+	if isHTMLContent(contentType, body) && !opts.Raw {
+		markdown, title, err := HTMLToMarkdown(bytes.NewReader(body), HTMLToMarkdownOptions{
+			BaseURL:     resp.Request.URL,
+			Selector:    opts.Selector,
+			MainContent: opts.MainContent,
+		})
+		if err != nil {
+			return "", err
+		}
+		result = markdown
+		if title != "" {
+			result = fmt.Sprintf("# %s\n\n%s", title, result)
+		}
+	} else if !strings.Contains(contentType, "text/") && !strings.Contains(contentType, "application/json") {
+		// Add content type info if it's not HTML or text
 		result = fmt.Sprintf("[Content-Type: %s]\n\n%s", contentType, result)
 	}
 
-	return result, nil
+	// Cap the output to the token budget
+	result = truncateToTokens(result, maxTokens)
+
+	return fmt.Sprintf("[URL: %s]\n\n%s", finalURL, result), nil
+}
+
+// isHTMLContent reports whether a response body is an HTML document
+func isHTMLContent(contentType string, body []byte) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch mediaType {
+	case "text/html", "application/xhtml+xml":
+		return true
+	case "text/plain":
+		// Servers often label HTML as plain text; sniff the body to be sure
+		return strings.HasPrefix(http.DetectContentType(body), "text/html")
+	}
+	return false
+}
+
+// truncateToTokens cuts content to an approximate token budget (~4 characters per token)
+func truncateToTokens(content string, maxTokens int) string {
+	maxChars := maxTokens * 4
+	if maxTokens <= 0 || len(content) <= maxChars {
+		return content
+	}
+
+	cut := maxChars
+	// Avoid splitting a multi-byte character
+	for cut > 0 && !utf8.RuneStart(content[cut]) {
+		cut--
+	}
+	// Prefer breaking at a line boundary
+	if idx := strings.LastIndex(content[:cut], "\n"); idx > cut/2 {
+		cut = idx
+	}
+
+	return content[:cut] + fmt.Sprintf("\n\n[Content truncated at ~%d tokens]", maxTokens)
 }
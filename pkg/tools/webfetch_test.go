@@ -0,0 +1,260 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newWebFetchTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	article, err := os.ReadFile("testdata/webfetch/article.html")
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(article)
+	})
+	mux.HandleFunc("/data.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"name": "ryan"}`)
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body>")
+		for i := 0; i < 500; i++ {
+			fmt.Fprintf(w, "<p>Paragraph %d with some filler text.</p>", i)
+		}
+		fmt.Fprint(w, "</body></html>")
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newLoopbackWebFetchTool returns a tool that may reach local test servers
+func newLoopbackWebFetchTool(bypass bool) *WebFetchTool {
+	tool := NewWebFetchToolWithBypass(bypass)
+	tool.allowPrivateHosts([]string{"127.0.0.0/8", "::1"})
+	return tool
+}
+
+func TestWebFetchHTMLToMarkdown(t *testing.T) {
+	server := newWebFetchTestServer(t)
+	tool := newLoopbackWebFetchTool(true)
+	ctx := context.Background()
+
+	result, err := tool.Call(ctx, server.URL+"/article")
+	require.NoError(t, err)
+
+	// Title, headings, inline formatting and resolved links are kept
+	assert.True(t, strings.HasPrefix(result, "[URL: "+server.URL+"/article]\n\n# Ryan Docs"))
+	assert.Contains(t, result, "# Getting Started")
+	assert.Contains(t, result, "## Install")
+	assert.Contains(t, result, "Ryan is an **open source** assistant.")
+	assert.Contains(t, result, "[install guide]("+server.URL+"/docs/install)")
+
+	// Code blocks keep their language and formatting
+	assert.Contains(t, result, "```bash\ngo install github.com/killallgit/ryan@latest\nryan --help\n```")
+	assert.Contains(t, result, "Use `ryan -p` for headless mode.")
+
+	// Lists and tables
+	assert.Contains(t, result, "- Tools\n  - bash\n  - search\n- Memory")
+	assert.Contains(t, result, "1. First\n2. Second")
+	assert.Contains(t, result, "| Name | Default |\n| --- | --- |\n| timeout | 30 |")
+	assert.Contains(t, result, "> Expect missing features.")
+
+	// Scripts, styles and navigation are stripped
+	assert.NotContains(t, result, "tracking")
+	assert.NotContains(t, result, "analytics")
+	assert.NotContains(t, result, "color: red")
+	assert.NotContains(t, result, "About")
+	assert.NotContains(t, result, "<p>")
+}
+
+func TestWebFetchContentExtraction(t *testing.T) {
+	server := newWebFetchTestServer(t)
+	tool := newLoopbackWebFetchTool(true)
+	ctx := context.Background()
+
+	t.Run("main content", func(t *testing.T) {
+		result, err := tool.Call(ctx, fmt.Sprintf(`{"url": %q, "main_content": true}`, server.URL+"/article"))
+		require.NoError(t, err)
+		assert.Contains(t, result, "# Getting Started")
+		assert.NotContains(t, result, "Site banner")
+		assert.NotContains(t, result, "Copyright footer")
+	})
+
+	t.Run("css selector", func(t *testing.T) {
+		result, err := tool.Call(ctx, fmt.Sprintf(`{"url": %q, "selector": "table"}`, server.URL+"/article"))
+		require.NoError(t, err)
+		assert.Contains(t, result, "| timeout | 30 |")
+		assert.NotContains(t, result, "Getting Started")
+	})
+
+	t.Run("selector without matches", func(t *testing.T) {
+		_, err := tool.Call(ctx, fmt.Sprintf(`{"url": %q, "selector": "#missing"}`, server.URL+"/article"))
+		assert.Error(t, err)
+	})
+
+	t.Run("raw output", func(t *testing.T) {
+		result, err := tool.Call(ctx, fmt.Sprintf(`{"url": %q, "raw": true}`, server.URL+"/article"))
+		require.NoError(t, err)
+		assert.Contains(t, result, "<script>")
+	})
+}
+
+func TestWebFetchTokenBudget(t *testing.T) {
+	server := newWebFetchTestServer(t)
+	tool := newLoopbackWebFetchTool(true)
+	ctx := context.Background()
+
+	result, err := tool.Call(ctx, fmt.Sprintf(`{"url": %q, "max_tokens": 100}`, server.URL+"/large"))
+	require.NoError(t, err)
+	assert.Contains(t, result, "Paragraph 0")
+	assert.NotContains(t, result, "Paragraph 499")
+	assert.Contains(t, result, "[Content truncated at ~100 tokens]")
+	assert.LessOrEqual(t, len(result), 100*4+50+len(server.URL)+20)
+}
+
+func TestWebFetchNonHTML(t *testing.T) {
+	server := newWebFetchTestServer(t)
+	tool := newLoopbackWebFetchTool(true)
+
+	result, err := tool.Call(context.Background(), server.URL+"/data.json")
+	require.NoError(t, err)
+	assert.Equal(t, "[URL: "+server.URL+"/data.json]\n\n"+`{"name": "ryan"}`, result)
+}
+
+func TestIsHTMLContent(t *testing.T) {
+	page := []byte("<!DOCTYPE html><html><body>hi</body></html>")
+	tests := []struct {
+		contentType string
+		body        []byte
+		want        bool
+	}{
+		{"text/html", page, true},
+		{"Text/HTML; charset=utf-8", page, true},
+		{"application/xhtml+xml", page, true},
+		{"text/plain", page, true},
+		{"text/plain; charset=utf-8", page, true},
+		{"text/plain; charset=utf-8", []byte("just text"), false},
+		{"application/json", page, false},
+		{"text/htmlx", page, false},
+		{"not a media type;;", page, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, isHTMLContent(tt.contentType, tt.body), tt.contentType)
+	}
+}
+
+func TestWebFetchBlocksPrivateAddresses(t *testing.T) {
+	server := newWebFetchTestServer(t)
+	tool := NewWebFetchToolWithBypass(true)
+
+	_, err := tool.Call(context.Background(), server.URL+"/data.json")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tools.web.allowed_private_hosts")
+}
+
+func TestWebFetchRedirectChecks(t *testing.T) {
+	target := newWebFetchTestServer(t)
+	redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL+"/data.json", http.StatusFound)
+	}))
+	t.Cleanup(redirector.Close)
+
+	t.Run("final URL is reported", func(t *testing.T) {
+		tool := newLoopbackWebFetchTool(true)
+		result, err := tool.Call(context.Background(), redirector.URL+"/start")
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(result, "[URL: "+target.URL+"/data.json]"))
+	})
+
+	t.Run("redirect to a host outside the ACL is denied", func(t *testing.T) {
+		redirectorURL, err := url.Parse(redirector.URL)
+		require.NoError(t, err)
+
+		// Only the redirecting server is allowed by the ACL
+		home := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(home, ".ryan"), 0755))
+		settings := fmt.Sprintf(`{"permissions": {"allow": ["WebFetch(%s:*)"]}}`, redirectorURL.Host)
+		require.NoError(t, os.WriteFile(filepath.Join(home, ".ryan", "settings.json"), []byte(settings), 0644))
+		t.Setenv("HOME", home)
+
+		tool := newLoopbackWebFetchTool(false)
+		_, err = tool.Call(context.Background(), redirector.URL+"/start")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "redirect to "+target.URL+"/data.json rejected")
+	})
+}
+
+func TestIsBlockedAddr(t *testing.T) {
+	tests := []struct {
+		addr    string
+		blocked bool
+	}{
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"fe80::1", true},
+		{"fd00::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"224.0.0.1", true},
+		{"8.8.8.8", false},
+		{"1.1.1.1", false},
+		{"2606:4700:4700::1111", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			assert.Equal(t, tt.blocked, isBlockedAddr(netip.MustParseAddr(tt.addr)))
+		})
+	}
+}
+
+func TestWebFetchAllowPrivateHosts(t *testing.T) {
+	tool := NewWebFetchToolWithBypass(true)
+	tool.allowPrivateHosts([]string{"10.0.0.0/8", "192.168.1.5", "Intranet.local", ""})
+
+	assert.True(t, tool.isPrivateAllowed("x", netip.MustParseAddr("10.20.30.40")))
+	assert.True(t, tool.isPrivateAllowed("x", netip.MustParseAddr("192.168.1.5")))
+	assert.False(t, tool.isPrivateAllowed("x", netip.MustParseAddr("192.168.1.6")))
+	assert.True(t, tool.isPrivateAllowed("intranet.local", netip.MustParseAddr("172.16.0.1")))
+	assert.False(t, tool.isPrivateAllowed("x", netip.MustParseAddr("169.254.169.254")))
+}
+
+func TestParseWebFetchInput(t *testing.T) {
+	opts, err := parseWebFetchInput("  https://example.com  ")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", opts.URL)
+
+	opts, err = parseWebFetchInput(`{"url": "https://example.com", "selector": "main", "max_tokens": 10}`)
+	require.NoError(t, err)
+	assert.Equal(t, "main", opts.Selector)
+	assert.Equal(t, 10, opts.MaxTokens)
+
+	_, err = parseWebFetchInput("")
+	assert.Error(t, err)
+
+	_, err = parseWebFetchInput(`{"url": `)
+	assert.Error(t, err)
+}
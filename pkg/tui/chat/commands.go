@@ -0,0 +1,84 @@
+package chat
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/killallgit/ryan/pkg/checkpoint"
+)
+
+// slashCommands maps chat commands to their handlers
+var slashCommands = map[string]func(m chatModel, args []string) string{
+	"/rewind":  rewindCommand,
+	"/sources": sourcesCommand,
+}
+
+// handleSlashCommand runs a chat command if the input names one
+func handleSlashCommand(m chatModel, input string) (chatModel, bool) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return m, false
+	}
+
+	command, ok := slashCommands[fields[0]]
+	if !ok {
+		return m, false
+	}
+
+	m.nodes = append(m.nodes, MessageNode{
+		ID:        fmt.Sprintf("system-%d", time.Now().UnixNano()),
+		Type:      "system",
+		Content:   command(m, fields[1:]),
+		Timestamp: time.Now(),
+	})
+	return m, true
+}
+
+// rewindCommand lists checkpoints, or restores files to their state before a turn
+func rewindCommand(m chatModel, args []string) string {
+	var store *checkpoint.Store
+	if source, ok := m.agent.(interface{ GetCheckpoints() *checkpoint.Store }); ok {
+		store = source.GetCheckpoints()
+	}
+	if store == nil {
+		return "Checkpoints are not available in this session"
+	}
+
+	if len(args) == 0 {
+		turns, err := store.Turns()
+		if err != nil {
+			return fmt.Sprintf("Failed to list checkpoints: %v", err)
+		}
+		if len(turns) == 0 {
+			return "No file changes have been recorded in this session"
+		}
+		lines := []string{"Checkpoints (use /rewind <turn> to restore files to before that turn):"}
+		for _, turn := range turns {
+			lines = append(lines, "  "+turn.Summary())
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	turn, err := strconv.Atoi(args[0])
+	if err != nil || turn < 1 {
+		return "Usage: /rewind [turn]"
+	}
+
+	restored, err := store.Rewind(turn)
+	if err != nil {
+		return fmt.Sprintf("Rewind failed: %v", err)
+	}
+	return fmt.Sprintf("Restored %d file(s) to before turn %d:\n  %s", len(restored), turn, strings.Join(restored, "\n  "))
+}
+
+// commandResult refreshes the view after a chat command
+func commandResult(m chatModel) (tea.Model, tea.Cmd) {
+	m.textarea.Reset()
+	m.textarea.SetHeight(1)
+	m.updateViewportHeight()
+	m.updateViewportContent()
+	return m, nil
+}
@@ -59,8 +59,8 @@ func (m *chatModel) calculateTextAreaHeight() int {
 func (m *chatModel) updateViewportHeight() {
 	if m.height > 0 {
 		textAreaHeight := m.calculateTextAreaHeight()
-		// Account for status bar (1 line) and spacing
-		newHeight := m.height - textAreaHeight - 4
+		// Account for todo panel, status bar (1 line) and spacing
+		newHeight := m.height - textAreaHeight - m.todoPanelHeight() - 4
 		if newHeight < 1 {
 			newHeight = 1
 		}
@@ -83,8 +83,8 @@ func (m *chatModel) handleWindowResize(width, height int) {
 
 	// Update viewport dimensions
 	m.viewport.Width = width
-	// Account for textarea, status bar, and spacing
-	newHeight := height - textAreaHeight - 4
+	// Account for textarea, todo panel, status bar, and spacing
+	newHeight := height - textAreaHeight - m.todoPanelHeight() - 4
 	if newHeight < 1 {
 		newHeight = 1
 	}
@@ -28,6 +28,11 @@ func handleKeyMsg(m chatModel, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		if m.textarea.Value() != "" {
 			userInput := m.textarea.Value()
 
+			// Chat commands are handled locally and never sent to the model
+			if updated, handled := handleSlashCommand(m, userInput); handled {
+				return commandResult(updated)
+			}
+
 			// Add message to chat history
 			if m.chatManager != nil {
 				m.chatManager.AddMessage(chat.RoleUser, userInput)
@@ -1,6 +1,10 @@
 package chat
 
-import "time"
+import (
+	"time"
+
+	"github.com/killallgit/ryan/pkg/retrieval"
+)
 
 // MessageNode represents a display element
 type MessageNode struct {
@@ -10,4 +14,5 @@ type MessageNode struct {
 	Timestamp   time.Time
 	StreamID    string // Link to stream if applicable
 	IsStreaming bool
+	Citations   []retrieval.Citation // Retrieved passages behind an assistant response
 }
@@ -7,6 +7,8 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/killallgit/ryan/pkg/agent"
 	"github.com/killallgit/ryan/pkg/chat"
+	"github.com/killallgit/ryan/pkg/memory"
+	"github.com/killallgit/ryan/pkg/retrieval"
 	"github.com/killallgit/ryan/pkg/stream/tui"
 	"github.com/killallgit/ryan/pkg/tui/chat/status"
 	"github.com/killallgit/ryan/pkg/tui/theme"
@@ -18,6 +20,9 @@ type StreamChunk struct {
 	Content  string
 	IsEnd    bool
 	Error    error
+
+	// Citations carries the sources of the response, sent just before the end
+	Citations []retrieval.Citation
 }
 
 // chunkMsg wraps StreamChunk for Bubble Tea messaging
@@ -56,6 +61,9 @@ type chatModel struct {
 	// Token tracking
 	lastTokensSent int
 	lastTokensRecv int
+
+	// Agent's todo list shown above the status bar
+	todos []memory.Todo
 }
 
 func NewChatModel(streamManager *tui.Manager, chatManager *chat.Manager, agent agent.Agent) chatModel {
@@ -85,7 +93,7 @@ func NewChatModel(streamManager *tui.Manager, chatManager *chat.Manager, agent a
 	// Initialize status bar
 	statusBar := status.NewStatusModel()
 
-	return chatModel{
+	m := chatModel{
 		textarea:      ta,
 		messages:      []string{},
 		messageIndex:  -1,
@@ -109,4 +117,9 @@ func NewChatModel(streamManager *tui.Manager, chatManager *chat.Manager, agent a
 		lastTokensSent: 0,
 		lastTokensRecv: 0,
 	}
+
+	// Show todos carried over from a continued session
+	m.syncTodos()
+
+	return m
 }
@@ -42,6 +42,9 @@ func (m chatModel) renderNodes() string {
 		// Apply width constraint for word wrapping and add top padding
 		style = style.Width(availableWidth).PaddingTop(1)
 		nodeContent = style.Render(node.Content)
+		if sources := renderSources(node.Citations); sources != "" {
+			nodeContent += "\n" + sources
+		}
 
 		rendered = append(rendered, nodeContent)
 	}
@@ -0,0 +1,66 @@
+package chat
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/killallgit/ryan/pkg/retrieval"
+	"github.com/killallgit/ryan/pkg/tui/theme"
+)
+
+// renderSources renders footnotes for the passages a response cites
+func renderSources(citations []retrieval.Citation) string {
+	var lines []string
+	for _, citation := range citations {
+		if citation.Cited {
+			lines = append(lines, theme.Styles.ToolOutputPrefix.Render(
+				fmt.Sprintf("[%d] %s", citation.Number, citation.Location())))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n")
+}
+
+// lastCitations returns the sources of the most recent response that had any
+func (m chatModel) lastCitations() []retrieval.Citation {
+	for i := len(m.nodes) - 1; i >= 0; i-- {
+		if len(m.nodes[i].Citations) > 0 {
+			return m.nodes[i].Citations
+		}
+	}
+	return nil
+}
+
+// sourcesCommand lists the passages behind the last response, or expands one of them
+func sourcesCommand(m chatModel, args []string) string {
+	citations := m.lastCitations()
+	if len(citations) == 0 {
+		return "No sources were retrieved for the last response"
+	}
+
+	if len(args) == 0 {
+		lines := []string{"Sources of the last response (use /sources <n> to read one):"}
+		for _, citation := range citations {
+			mark := " "
+			if citation.Cited {
+				mark = "*"
+			}
+			lines = append(lines, fmt.Sprintf(" %s[%d] %s (score %.2f)", mark, citation.Number, citation.Location(), citation.Score))
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	n, err := strconv.Atoi(strings.Trim(args[0], "[]"))
+	if err != nil {
+		return "Usage: /sources [n]"
+	}
+	for _, citation := range citations {
+		if citation.Number == n {
+			return fmt.Sprintf("[%d] %s (chunk %s)\n\n%s", citation.Number, citation.Location(), citation.ChunkID, citation.Excerpt)
+		}
+	}
+	return fmt.Sprintf("No source [%d] in the last response", n)
+}
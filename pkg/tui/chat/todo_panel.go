@@ -0,0 +1,76 @@
+package chat
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/killallgit/ryan/pkg/memory"
+	"github.com/killallgit/ryan/pkg/tui/theme"
+)
+
+// maxTodoPanelItems limits how many todos are shown before collapsing the rest
+const maxTodoPanelItems = 8
+
+// syncTodos copies the agent's todo list from its execution state, resizing
+// the viewport when the panel changes
+func (m *chatModel) syncTodos() {
+	if m.agent == nil {
+		return
+	}
+
+	todos := m.agent.GetExecutionState().Todos
+	if reflect.DeepEqual(todos, m.todos) {
+		return
+	}
+
+	m.todos = todos
+	m.updateViewportHeight()
+}
+
+// todoPanelHeight returns the number of lines used by the todo panel
+func (m chatModel) todoPanelHeight() int {
+	if len(m.todos) == 0 {
+		return 0
+	}
+	return strings.Count(m.renderTodoPanel(), "\n") + 1
+}
+
+// renderTodoPanel renders the agent's todo list as a checklist
+func (m chatModel) renderTodoPanel() string {
+	if len(m.todos) == 0 {
+		return ""
+	}
+
+	done := 0
+	for _, todo := range m.todos {
+		if todo.Status == memory.TodoDone {
+			done++
+		}
+	}
+
+	lines := []string{m.styles.SystemMessage.Render(fmt.Sprintf("Todos (%d/%d)", done, len(m.todos)))}
+	for i, todo := range m.todos {
+		if i == maxTodoPanelItems {
+			lines = append(lines, theme.Styles.ToolOutputPrefix.Render(fmt.Sprintf("  … %d more", len(m.todos)-i)))
+			break
+		}
+
+		switch todo.Status {
+		case memory.TodoDone:
+			lines = append(lines, fmt.Sprintf("  %s %s",
+				theme.Styles.ToolSuccess.Render("✓"),
+				theme.Styles.ToolOutputPrefix.Render(todo.Content)))
+		case memory.TodoInProgress:
+			lines = append(lines, fmt.Sprintf("  %s %s",
+				theme.Styles.ToolIndicator.Render("●"),
+				theme.Styles.ToolName.Render(todo.Content)))
+		default:
+			lines = append(lines, fmt.Sprintf("  %s %s",
+				theme.Styles.ToolOutputPrefix.Render("○"),
+				m.styles.AssistantMessage.Render(todo.Content)))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/killallgit/ryan/pkg/agent"
 	"github.com/killallgit/ryan/pkg/chat"
 	"github.com/killallgit/ryan/pkg/process"
+	"github.com/killallgit/ryan/pkg/retrieval"
 	"github.com/killallgit/ryan/pkg/stream/tui"
 	"github.com/killallgit/ryan/pkg/tui/chat/status"
 )
@@ -92,6 +93,7 @@ func (m chatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			m.isStreaming = false
 			m.currentStream = ""
+			m.syncTodos()
 			m.updateViewportContent()
 
 			// Update status bar
@@ -111,6 +113,9 @@ func (m chatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.statusBar = statusModel.(status.StatusModel)
 				}
 				m.nodes[i].Content += msg.Content
+				if len(msg.Citations) > 0 {
+					m.nodes[i].Citations = msg.Citations
+				}
 				break
 			}
 		}
@@ -134,6 +139,9 @@ func (m chatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.lastTokensSent = msg.sent
 		m.lastTokensRecv = msg.recv
 
+		// Pick up todo list changes made by the agent
+		m.syncTodos()
+
 		// Continue polling if streaming
 		if m.isStreaming {
 			return m, pollTokenStats(m.agent)
@@ -195,6 +203,8 @@ type channelStreamHandler struct {
 	chunkChan chan<- StreamChunk
 }
 
+var _ agent.CitationHandler = (*channelStreamHandler)(nil)
+
 func (h *channelStreamHandler) OnChunk(chunk []byte) error {
 	h.chunkChan <- StreamChunk{
 		StreamID: h.streamID,
@@ -212,6 +222,13 @@ func (h *channelStreamHandler) OnComplete(finalContent string) error {
 	return nil
 }
 
+func (h *channelStreamHandler) OnCitations(citations []retrieval.Citation) {
+	h.chunkChan <- StreamChunk{
+		StreamID:  h.streamID,
+		Citations: citations,
+	}
+}
+
 func (h *channelStreamHandler) OnError(err error) {
 	h.chunkChan <- StreamChunk{
 		StreamID: h.streamID,
@@ -5,6 +5,16 @@ import (
 )
 
 func (m chatModel) View() string {
+	if len(m.todos) > 0 {
+		return lipgloss.JoinVertical(
+			lipgloss.Left,
+			m.viewport.View(),
+			m.renderTodoPanel(),
+			m.statusBar.View(),
+			m.textarea.View(),
+		)
+	}
+
 	return lipgloss.JoinVertical(
 		lipgloss.Left,
 		m.viewport.View(),
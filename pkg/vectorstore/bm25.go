@@ -0,0 +1,269 @@
+package vectorstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/killallgit/ryan/pkg/embeddings"
+)
+
+// BM25 ranking parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// bm25Version is bumped when the on-disk format of the keyword index changes
+const bm25Version = 1
+
+// BM25Index ranks documents by keyword relevance. It catches exact identifiers
+// such as NewPermissionManagerWithBypass that embedding search tends to miss.
+type BM25Index struct {
+	docs        map[string]*bm25Doc
+	postings    map[string]map[string]int // term -> document ID -> term frequency
+	totalLength int
+	mu          sync.RWMutex
+}
+
+type bm25Doc struct {
+	Content  string            `json:"content"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	length   int
+}
+
+// bm25File is the persisted form of the index; postings are rebuilt on load
+type bm25File struct {
+	Version   int                 `json:"version"`
+	Documents map[string]*bm25Doc `json:"documents"`
+}
+
+// NewBM25Index creates an empty keyword index
+func NewBM25Index() *BM25Index {
+	return &BM25Index{
+		docs:     make(map[string]*bm25Doc),
+		postings: make(map[string]map[string]int),
+	}
+}
+
+// LoadBM25Index reads an index saved with Save, returning an empty one if the file does not exist
+func LoadBM25Index(path string) (*BM25Index, error) {
+	index := NewBM25Index()
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyword index: %w", err)
+	}
+
+	var file bm25File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse keyword index %s: %w", path, err)
+	}
+	if file.Version != bm25Version {
+		return nil, fmt.Errorf("keyword index %s has version %d, expected %d; run ryan index --clear", path, file.Version, bm25Version)
+	}
+	for id, doc := range file.Documents {
+		index.add(id, doc)
+	}
+	return index, nil
+}
+
+// Save writes the index atomically
+func (ix *BM25Index) Save(path string) error {
+	ix.mu.RLock()
+	data, err := json.Marshal(bm25File{Version: bm25Version, Documents: ix.docs})
+	ix.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode keyword index: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create keyword index directory: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write keyword index: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write keyword index: %w", err)
+	}
+	return nil
+}
+
+// Add indexes documents, replacing any with the same ID
+func (ix *BM25Index) Add(documents []Document) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	for _, doc := range documents {
+		metadata := make(map[string]string, len(doc.Metadata))
+		for k, v := range doc.Metadata {
+			metadata[k] = fmt.Sprint(v)
+		}
+		ix.remove(doc.ID)
+		ix.add(doc.ID, &bm25Doc{Content: doc.Content, Metadata: metadata})
+	}
+}
+
+// Delete removes documents by their IDs
+func (ix *BM25Index) Delete(ids []string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	for _, id := range ids {
+		ix.remove(id)
+	}
+}
+
+// Reset removes every document
+func (ix *BM25Index) Reset() {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.docs = make(map[string]*bm25Doc)
+	ix.postings = make(map[string]map[string]int)
+	ix.totalLength = 0
+}
+
+// Len returns the number of indexed documents
+func (ix *BM25Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.docs)
+}
+
+// Search returns up to k documents matching filter, best first. Scores are
+// relative to the best match, which scores 1.
+func (ix *BM25Index) Search(query string, k int, filter Filter) []SearchResult {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	if k <= 0 || len(ix.docs) == 0 {
+		return nil
+	}
+
+	n := float64(len(ix.docs))
+	avgLength := float64(ix.totalLength) / n
+	scores := make(map[string]float64)
+	seen := make(map[string]bool)
+	for _, term := range tokenize(query) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		postings := ix.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+		df := float64(len(postings))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for id, tf := range postings {
+			freq := float64(tf)
+			norm := 1 - bm25B + bm25B*float64(ix.docs[id].length)/avgLength
+			scores[id] += idf * freq * (bm25K1 + 1) / (freq + bm25K1*norm)
+		}
+	}
+
+	ids := make([]string, 0, len(scores))
+	for id := range scores {
+		doc := ix.docs[id]
+		if !filter.IsEmpty() && !filter.Matches(doc.metadata()) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	if len(ids) > k {
+		ids = ids[:k]
+	}
+
+	results := make([]SearchResult, len(ids))
+	for i, id := range ids {
+		score := float32(scores[id] / scores[ids[0]])
+		results[i] = SearchResult{
+			Document: Document{ID: id, Content: ix.docs[id].Content, Metadata: ix.docs[id].metadata()},
+			Score:    score,
+			Distance: 1 - score,
+		}
+	}
+	return results
+}
+
+func (ix *BM25Index) add(id string, doc *bm25Doc) {
+	terms := tokenize(doc.Content)
+	doc.length = len(terms)
+	ix.docs[id] = doc
+	ix.totalLength += doc.length
+	for _, term := range terms {
+		postings := ix.postings[term]
+		if postings == nil {
+			postings = make(map[string]int)
+			ix.postings[term] = postings
+		}
+		postings[id]++
+	}
+}
+
+func (ix *BM25Index) remove(id string) {
+	doc, ok := ix.docs[id]
+	if !ok {
+		return
+	}
+	for _, term := range tokenize(doc.Content) {
+		if postings := ix.postings[term]; postings != nil {
+			delete(postings, id)
+			if len(postings) == 0 {
+				delete(ix.postings, term)
+			}
+		}
+	}
+	ix.totalLength -= doc.length
+	delete(ix.docs, id)
+}
+
+func (d *bm25Doc) metadata() map[string]interface{} {
+	metadata := make(map[string]interface{}, len(d.Metadata))
+	for k, v := range d.Metadata {
+		metadata[k] = v
+	}
+	return metadata
+}
+
+// tokenize lowercases text into words. Identifiers are kept whole and also
+// split into their camelCase and snake_case parts, so "NewPermissionManager"
+// matches both itself and "permission manager".
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+
+	var tokens []string
+	for _, word := range words {
+		word = strings.Trim(word, "_")
+		if word == "" {
+			continue
+		}
+		tokens = append(tokens, strings.ToLower(word))
+		if parts := embeddings.SplitIdentifier(word); len(parts) > 1 {
+			for _, part := range parts {
+				tokens = append(tokens, strings.ToLower(part))
+			}
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,117 @@
+package vectorstore
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/killallgit/ryan/pkg/embeddings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t,
+		[]string{"newpermissionmanagerwithbypass", "new", "permission", "manager", "with", "bypass"},
+		tokenize("NewPermissionManagerWithBypass"))
+	assert.Equal(t,
+		[]string{"parse", "httpserver", "http", "server", "max_retries", "max", "retries", "v2", "v", "2"},
+		tokenize("parse(HTTPServer, max_retries) v2"))
+	assert.Equal(t, []string{"über", "straße"}, tokenize("Über Straße"))
+}
+
+func bm25TestDocs() []Document {
+	return []Document{
+		{ID: "bypass", Content: "func NewPermissionManagerWithBypass(bypass bool) *PermissionManager", Metadata: map[string]interface{}{"source": "pkg/tools/permissions.go"}},
+		{ID: "manager", Content: "func NewPermissionManager() *PermissionManager creates a permission manager", Metadata: map[string]interface{}{"source": "pkg/tools/permissions.go"}},
+		{ID: "doc", Content: "Permissions decide which tools the agent may run without asking", Metadata: map[string]interface{}{"source": "docs/permissions.md"}},
+	}
+}
+
+func TestBM25Search(t *testing.T) {
+	index := NewBM25Index()
+	index.Add(bm25TestDocs())
+
+	results := index.Search("NewPermissionManagerWithBypass", 3, Filter{})
+	require.NotEmpty(t, results)
+	assert.Equal(t, "bypass", results[0].Document.ID, "the exact identifier ranks first")
+	assert.Equal(t, float32(1), results[0].Score)
+	assert.Equal(t, "pkg/tools/permissions.go", results[0].Document.Metadata["source"])
+
+	results = index.Search("permission manager", 3, Filter{}.WithPathPrefix("source", "docs"))
+	assert.Empty(t, results, "the docs page only mentions permissions")
+
+	results = index.Search("tools the agent may run", 1, Filter{})
+	require.Len(t, results, 1)
+	assert.Equal(t, "doc", results[0].Document.ID)
+
+	assert.Empty(t, index.Search("nothing matches this", 3, Filter{}))
+}
+
+func TestBM25AddReplacesAndDeletes(t *testing.T) {
+	index := NewBM25Index()
+	index.Add(bm25TestDocs())
+	index.Add([]Document{{ID: "doc", Content: "rewritten page about sandboxes"}})
+	assert.Equal(t, 3, index.Len())
+	assert.Empty(t, index.Search("asking", 3, Filter{}), "replaced content is no longer indexed")
+
+	index.Delete([]string{"bypass", "missing"})
+	assert.Equal(t, 2, index.Len())
+	assert.Empty(t, index.Search("bypass", 3, Filter{}))
+
+	index.Reset()
+	assert.Equal(t, 0, index.Len())
+}
+
+func TestBM25SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.bm25.json")
+	index := NewBM25Index()
+	index.Add(bm25TestDocs())
+	require.NoError(t, index.Save(path))
+
+	loaded, err := LoadBM25Index(path)
+	require.NoError(t, err)
+	assert.Equal(t, index.Search("permission manager", 3, Filter{}), loaded.Search("permission manager", 3, Filter{}))
+
+	empty, err := LoadBM25Index(filepath.Join(t.TempDir(), "missing.json"))
+	require.NoError(t, err)
+	assert.Equal(t, 0, empty.Len())
+}
+
+func TestKeywordStore(t *testing.T) {
+	dir := t.TempDir()
+	path := KeywordIndexPath(dir, "test")
+	ctx := context.Background()
+
+	chromemStore, err := NewChromemStore(ChromemConfig{CollectionName: "test", PersistDirectory: dir, Embedder: embeddings.NewMockEmbedder(32)})
+	require.NoError(t, err)
+	store, err := NewKeywordStore(chromemStore, path)
+	require.NoError(t, err)
+
+	require.NoError(t, store.AddDocuments(ctx, bm25TestDocs()))
+	require.NoError(t, store.DeleteDocuments(ctx, []string{"manager"}))
+	count, err := store.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	results, err := store.KeywordSearch(ctx, "permission manager tools", 5, Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bypass", "doc"}, resultIDs(results))
+	require.NoError(t, store.Close())
+	assert.FileExists(t, path)
+
+	// Reopening restores the keyword index from disk
+	chromemStore, err = NewChromemStore(ChromemConfig{CollectionName: "test", PersistDirectory: dir, Embedder: embeddings.NewMockEmbedder(32)})
+	require.NoError(t, err)
+	store, err = NewKeywordStore(chromemStore, path)
+	require.NoError(t, err)
+	results, err = store.KeywordSearch(ctx, "bypass", 5, Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bypass"}, resultIDs(results))
+
+	require.NoError(t, store.Clear(ctx))
+	assert.NoFileExists(t, path)
+	results, err = store.KeywordSearch(ctx, "bypass", 5, Filter{})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
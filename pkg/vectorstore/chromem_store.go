@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"runtime"
+	"sort"
 	"sync"
 
 	"github.com/killallgit/ryan/pkg/embeddings"
@@ -15,7 +16,9 @@ type ChromemStore struct {
 	db         *chromem.DB
 	collection *chromem.Collection
 	embedder   embeddings.Embedder
-	docIDs     map[string]bool // Track document IDs for clearing
+	name       string
+	metadata   map[string]string
+	embedFunc  chromem.EmbeddingFunc
 	mu         sync.RWMutex
 }
 
@@ -57,7 +60,7 @@ func NewChromemStore(config ChromemConfig) (*ChromemStore, error) {
 	}
 
 	// Create embedding function adapter
-	embeddingFunc := func(ctx context.Context, text string) ([]float32, error) {
+	var embeddingFunc chromem.EmbeddingFunc = func(ctx context.Context, text string) ([]float32, error) {
 		return config.Embedder.EmbedText(ctx, text)
 	}
 
@@ -75,12 +78,20 @@ func NewChromemStore(config ChromemConfig) (*ChromemStore, error) {
 		db:         db,
 		collection: collection,
 		embedder:   config.Embedder,
-		docIDs:     make(map[string]bool),
+		name:       config.CollectionName,
+		metadata:   config.Metadata,
+		embedFunc:  embeddingFunc,
 	}, nil
 }
 
 // AddDocuments adds documents to the vector store
 func (s *ChromemStore) AddDocuments(ctx context.Context, documents []Document) error {
+	// Embed up front so the embedder sees the whole batch rather than one call per document
+	vectors, err := embedDocuments(ctx, s.embedder, documents)
+	if err != nil {
+		return err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -101,7 +112,7 @@ func (s *ChromemStore) AddDocuments(ctx context.Context, documents []Document) e
 			ID:        doc.ID,
 			Content:   doc.Content,
 			Metadata:  metadata,
-			Embedding: doc.Vector,
+			Embedding: vectors[i],
 		}
 	}
 
@@ -110,11 +121,6 @@ func (s *ChromemStore) AddDocuments(ctx context.Context, documents []Document) e
 		return fmt.Errorf("failed to add documents: %w", err)
 	}
 
-	// Track document IDs
-	for _, doc := range documents {
-		s.docIDs[doc.ID] = true
-	}
-
 	return nil
 }
 
@@ -123,15 +129,12 @@ func (s *ChromemStore) DeleteDocuments(ctx context.Context, ids []string) error
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Delete documents by IDs
-	err := s.collection.Delete(ctx, nil, nil, ids...)
-	if err == nil {
-		// Remove from tracked IDs
-		for _, id := range ids {
-			delete(s.docIDs, id)
-		}
+	if len(ids) == 0 {
+		return nil
 	}
-	return err
+
+	// Delete documents by IDs
+	return s.collection.Delete(ctx, nil, nil, ids...)
 }
 
 // SimilaritySearch performs a similarity search
@@ -141,11 +144,30 @@ func (s *ChromemStore) SimilaritySearch(ctx context.Context, query string, k int
 
 // SimilaritySearchWithScore performs a similarity search with scores
 func (s *ChromemStore) SimilaritySearchWithScore(ctx context.Context, query string, k int, scoreThreshold float32) ([]SearchResult, error) {
+	return s.SimilaritySearchWithFilter(ctx, query, k, scoreThreshold, Filter{})
+}
+
+// SimilaritySearchWithFilter performs a similarity search over documents matching filter.
+// Equality conditions run inside chromem; the other conditions are applied
+// to the ranked results, so all candidates are ranked when they are present.
+func (s *ChromemStore) SimilaritySearchWithFilter(ctx context.Context, query string, k int, scoreThreshold float32, filter Filter) ([]SearchResult, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	want := k
+	postFilter := len(filter.NotEquals) > 0 || len(filter.In) > 0 || len(filter.PathPrefix) > 0
+
+	// chromem rejects k larger than the collection, so clamp it
+	count := s.collection.Count()
+	if postFilter || k > count {
+		k = count
+	}
+	if k <= 0 || want <= 0 {
+		return nil, nil
+	}
+
 	// Perform query
-	chromemResults, err := s.collection.Query(ctx, query, k, nil, nil)
+	chromemResults, err := s.collection.Query(ctx, query, k, filter.Equals, nil)
 	if err != nil {
 		return nil, fmt.Errorf("query failed: %w", err)
 	}
@@ -166,6 +188,9 @@ func (s *ChromemStore) SimilaritySearchWithScore(ctx context.Context, query stri
 		for k, v := range cr.Metadata {
 			metadata[k] = v
 		}
+		if postFilter && !filter.Matches(metadata) {
+			continue
+		}
 
 		results = append(results, SearchResult{
 			Document: Document{
@@ -177,34 +202,63 @@ func (s *ChromemStore) SimilaritySearchWithScore(ctx context.Context, query stri
 			Score:    score,
 			Distance: 1 - score, // Convert similarity to distance
 		})
+		if len(results) == want {
+			break
+		}
 	}
 
 	return results, nil
 }
 
+// Documents returns every document of the collection with its vector
+func (s *ChromemStore) Documents(ctx context.Context) ([]Document, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := s.collection.Count()
+	if count == 0 {
+		return nil, nil
+	}
+
+	// chromem has no way to list documents, so rank all of them against an
+	// arbitrary unit vector of the collection's dimensions
+	probe := make([]float32, s.embedder.GetDimensions())
+	if len(probe) == 0 {
+		return nil, fmt.Errorf("embedder reports no dimensions")
+	}
+	probe[0] = 1
+	chromemResults, err := s.collection.QueryEmbedding(ctx, probe, count, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+
+	documents := make([]Document, len(chromemResults))
+	for i, cr := range chromemResults {
+		metadata := make(map[string]interface{}, len(cr.Metadata))
+		for k, v := range cr.Metadata {
+			metadata[k] = v
+		}
+		documents[i] = Document{ID: cr.ID, Content: cr.Content, Metadata: metadata, Vector: cr.Embedding}
+	}
+	sort.Slice(documents, func(a, b int) bool { return documents[a].ID < documents[b].ID })
+	return documents, nil
+}
+
 // Clear removes all documents from the store
 func (s *ChromemStore) Clear(ctx context.Context) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Use tracked IDs to delete all documents
-	if len(s.docIDs) == 0 {
-		return nil // Already empty
+	// Recreate the collection so documents loaded from disk are removed too
+	if err := s.db.DeleteCollection(s.name); err != nil {
+		return fmt.Errorf("failed to delete collection: %w", err)
 	}
-
-	// Extract IDs
-	ids := make([]string, 0, len(s.docIDs))
-	for id := range s.docIDs {
-		ids = append(ids, id)
-	}
-
-	// Delete all documents by their IDs
-	err := s.collection.Delete(ctx, nil, nil, ids...)
-	if err == nil {
-		// Clear tracked IDs
-		s.docIDs = make(map[string]bool)
+	collection, err := s.db.CreateCollection(s.name, s.metadata, s.embedFunc)
+	if err != nil {
+		return fmt.Errorf("failed to recreate collection: %w", err)
 	}
-	return err
+	s.collection = collection
+	return nil
 }
 
 // Count returns the number of documents in the store
@@ -2,6 +2,7 @@ package vectorstore
 
 import (
 	"fmt"
+	"path/filepath"
 
 	"github.com/killallgit/ryan/pkg/config"
 	"github.com/killallgit/ryan/pkg/embeddings"
@@ -12,7 +13,7 @@ type Config struct {
 	// Enabled determines if vector store is enabled
 	Enabled bool
 
-	// Provider is the vector store provider (e.g., "chromem")
+	// Provider is the vector store provider ("chromem" or "sqlite")
 	Provider string
 
 	// CollectionName is the name of the collection
@@ -24,6 +25,12 @@ type Config struct {
 	// Embedding configuration
 	Embedding embeddings.Config
 
+	// Quantize has the sqlite provider scan int8 vectors before rescoring the best exactly
+	Quantize bool
+
+	// KeywordIndex keeps a BM25 index next to the vectors for hybrid search
+	KeywordIndex bool
+
 	// Retrieval configuration
 	Retrieval RetrieverConfig
 }
@@ -37,9 +44,18 @@ type PersistenceConfig struct {
 	Path string
 }
 
+// EmbeddingCachePath returns the embedding cache kept in a persistence directory
+func EmbeddingCachePath(dir string) string {
+	return filepath.Join(dir, "embeddings.db")
+}
+
 // LoadConfig loads vector store configuration from global config
 func LoadConfig() Config {
 	settings := config.Get()
+	cachePath := ""
+	if settings.VectorStore.Persistence.Enabled && settings.VectorStore.Embedding.Cache {
+		cachePath = EmbeddingCachePath(settings.VectorStore.Persistence.Path)
+	}
 	return Config{
 		Enabled:        settings.VectorStore.Enabled,
 		Provider:       settings.VectorStore.Provider,
@@ -49,11 +65,17 @@ func LoadConfig() Config {
 			Path:    settings.VectorStore.Persistence.Path,
 		},
 		Embedding: embeddings.Config{
-			Provider: settings.VectorStore.Embedding.Provider,
-			Model:    settings.VectorStore.Embedding.Model,
-			Endpoint: settings.VectorStore.Embedding.Endpoint,
-			APIKey:   settings.VectorStore.Embedding.APIKey,
+			Provider:    settings.VectorStore.Embedding.Provider,
+			Model:       settings.VectorStore.Embedding.Model,
+			Endpoint:    settings.VectorStore.Embedding.Endpoint,
+			APIKey:      settings.VectorStore.Embedding.APIKey,
+			Dimensions:  settings.VectorStore.Embedding.Dimensions,
+			BatchSize:   settings.VectorStore.Embedding.BatchSize,
+			Concurrency: settings.VectorStore.Embedding.Concurrency,
+			CachePath:   cachePath,
 		},
+		Quantize:     settings.VectorStore.SQLite.Quantize,
+		KeywordIndex: settings.VectorStore.Retrieval.Hybrid.Enabled,
 		Retrieval: RetrieverConfig{
 			K:              settings.VectorStore.Retrieval.K,
 			ScoreThreshold: settings.VectorStore.Retrieval.ScoreThreshold,
@@ -67,6 +89,7 @@ func NewVectorStore(config Config, embedder embeddings.Embedder) (VectorStore, e
 		return nil, fmt.Errorf("vector store is not enabled")
 	}
 
+	var store VectorStore
 	switch config.Provider {
 	case "chromem":
 		chromemConfig := ChromemConfig{
@@ -79,8 +102,38 @@ func NewVectorStore(config Config, embedder embeddings.Embedder) (VectorStore, e
 			chromemConfig.PersistDirectory = config.Persistence.Path
 		}
 
-		return NewChromemStore(chromemConfig)
+		chromemStore, err := NewChromemStore(chromemConfig)
+		if err != nil {
+			return nil, err
+		}
+		store = chromemStore
+	case "sqlite":
+		sqliteConfig := SQLiteConfig{
+			CollectionName: config.CollectionName,
+			Embedder:       embedder,
+			Quantize:       config.Quantize,
+		}
+
+		if config.Persistence.Enabled {
+			sqliteConfig.Path = SQLitePath(config.Persistence.Path)
+		}
+
+		sqliteStore, err := NewSQLiteStore(sqliteConfig)
+		if err != nil {
+			return nil, err
+		}
+		store = sqliteStore
 	default:
 		return nil, fmt.Errorf("unsupported vector store provider: %s", config.Provider)
 	}
+
+	if !config.KeywordIndex {
+		return store, nil
+	}
+
+	indexPath := ""
+	if config.Persistence.Enabled {
+		indexPath = KeywordIndexPath(config.Persistence.Path, config.CollectionName)
+	}
+	return NewKeywordStore(store, indexPath)
 }
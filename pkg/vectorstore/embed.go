@@ -0,0 +1,42 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/killallgit/ryan/pkg/embeddings"
+)
+
+// embedDocuments returns the vector of each document, embedding those without
+// one in a single call so the embedder can batch them
+func embedDocuments(ctx context.Context, embedder embeddings.Embedder, documents []Document) ([][]float32, error) {
+	vectors := make([][]float32, len(documents))
+	var missing []int
+	var texts []string
+	for i, doc := range documents {
+		if len(doc.Vector) > 0 {
+			vectors[i] = doc.Vector
+			continue
+		}
+		missing = append(missing, i)
+		texts = append(texts, doc.Content)
+	}
+	if len(texts) == 0 {
+		return vectors, nil
+	}
+	if embedder == nil {
+		return nil, fmt.Errorf("embedder is required to embed documents")
+	}
+
+	embedded, err := embedder.EmbedTexts(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed documents: %w", err)
+	}
+	if len(embedded) != len(texts) {
+		return nil, fmt.Errorf("embedder returned %d vectors for %d documents", len(embedded), len(texts))
+	}
+	for j, i := range missing {
+		vectors[i] = embedded[j]
+	}
+	return vectors, nil
+}
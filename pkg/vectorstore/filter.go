@@ -0,0 +1,160 @@
+package vectorstore
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Filter restricts a search to documents whose metadata matches every condition.
+// Values are compared as strings, the form stores keep metadata in.
+type Filter struct {
+	// Equals requires metadata[key] to equal the value
+	Equals map[string]string
+
+	// NotEquals requires metadata[key] to be missing or differ from the value
+	NotEquals map[string]string
+
+	// In requires metadata[key] to be one of the values
+	In map[string][]string
+
+	// PathPrefix requires metadata[key] to be the path or lie under it,
+	// so "pkg/tools" matches "pkg/tools/bash.go" but not "pkg/toolset.go"
+	PathPrefix map[string]string
+}
+
+// FilterFromMap builds a filter from loosely typed values such as decoded JSON:
+// lists become In conditions and everything else an Equals condition
+func FilterFromMap(values map[string]interface{}) Filter {
+	var filter Filter
+	for key, value := range values {
+		switch v := value.(type) {
+		case []string:
+			filter = filter.WithIn(key, v...)
+		case []interface{}:
+			options := make([]string, len(v))
+			for i, option := range v {
+				options[i] = fmt.Sprint(option)
+			}
+			filter = filter.WithIn(key, options...)
+		default:
+			filter = filter.WithEquals(key, fmt.Sprint(v))
+		}
+	}
+	return filter
+}
+
+// WithEquals returns a copy of the filter that also requires metadata[key] == value
+func (f Filter) WithEquals(key, value string) Filter {
+	f.Equals = withEntry(f.Equals, key, value)
+	return f
+}
+
+// WithNotEquals returns a copy of the filter that also requires metadata[key] != value
+func (f Filter) WithNotEquals(key, value string) Filter {
+	f.NotEquals = withEntry(f.NotEquals, key, value)
+	return f
+}
+
+// WithIn returns a copy of the filter that also requires metadata[key] to be one of values
+func (f Filter) WithIn(key string, values ...string) Filter {
+	f.In = withEntry(f.In, key, values)
+	return f
+}
+
+// WithPathPrefix returns a copy of the filter that also requires metadata[key] to lie under prefix
+func (f Filter) WithPathPrefix(key, prefix string) Filter {
+	f.PathPrefix = withEntry(f.PathPrefix, key, prefix)
+	return f
+}
+
+// IsEmpty reports whether the filter matches every document
+func (f Filter) IsEmpty() bool {
+	return len(f.Equals) == 0 && len(f.NotEquals) == 0 && len(f.In) == 0 && len(f.PathPrefix) == 0
+}
+
+// Matches reports whether metadata satisfies every condition of the filter
+func (f Filter) Matches(metadata map[string]interface{}) bool {
+	value := func(key string) (string, bool) {
+		v, ok := metadata[key]
+		if !ok {
+			return "", false
+		}
+		if s, ok := v.(string); ok {
+			return s, true
+		}
+		return fmt.Sprint(v), true
+	}
+
+	for key, want := range f.Equals {
+		if got, ok := value(key); !ok || got != want {
+			return false
+		}
+	}
+	for key, unwanted := range f.NotEquals {
+		if got, ok := value(key); ok && got == unwanted {
+			return false
+		}
+	}
+	for key, options := range f.In {
+		got, ok := value(key)
+		if !ok || !contains(options, got) {
+			return false
+		}
+	}
+	for key, prefix := range f.PathPrefix {
+		got, ok := value(key)
+		if !ok || !underPath(got, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// String describes the filter, e.g. for logs
+func (f Filter) String() string {
+	var parts []string
+	for key, value := range f.Equals {
+		parts = append(parts, fmt.Sprintf("%s=%s", key, value))
+	}
+	for key, value := range f.NotEquals {
+		parts = append(parts, fmt.Sprintf("%s!=%s", key, value))
+	}
+	for key, values := range f.In {
+		parts = append(parts, fmt.Sprintf("%s in [%s]", key, strings.Join(values, ", ")))
+	}
+	for key, prefix := range f.PathPrefix {
+		parts = append(parts, fmt.Sprintf("%s under %s", key, prefix))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, " and ")
+}
+
+// underPath reports whether path is prefix or lies in the directory prefix
+func underPath(path, prefix string) bool {
+	prefix = strings.TrimSuffix(strings.TrimPrefix(prefix, "./"), "/")
+	path = strings.TrimPrefix(path, "./")
+	if prefix == "" || prefix == "." {
+		return true
+	}
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+func contains(options []string, value string) bool {
+	for _, option := range options {
+		if option == value {
+			return true
+		}
+	}
+	return false
+}
+
+// withEntry copies m with key set, so filters can be extended without aliasing
+func withEntry[V any](m map[string]V, key string, value V) map[string]V {
+	copied := make(map[string]V, len(m)+1)
+	for k, v := range m {
+		copied[k] = v
+	}
+	copied[key] = value
+	return copied
+}
@@ -0,0 +1,155 @@
+package vectorstore
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/killallgit/ryan/pkg/embeddings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+func TestFilterMatches(t *testing.T) {
+	metadata := map[string]interface{}{"source": "pkg/tools/bash.go", "language": "go", "start_line": 10}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"empty", Filter{}, true},
+		{"equals", Filter{}.WithEquals("language", "go"), true},
+		{"equals non-string value", Filter{}.WithEquals("start_line", "10"), true},
+		{"equals mismatch", Filter{}.WithEquals("language", "markdown"), false},
+		{"missing key", Filter{}.WithEquals("kind", "function"), false},
+		{"not equals", Filter{}.WithNotEquals("language", "markdown"), true},
+		{"not equals match", Filter{}.WithNotEquals("language", "go"), false},
+		{"not equals missing key", Filter{}.WithNotEquals("kind", "function"), true},
+		{"in", Filter{}.WithIn("language", "markdown", "go"), true},
+		{"not in", Filter{}.WithIn("language", "markdown", "text"), false},
+		{"path prefix", Filter{}.WithPathPrefix("source", "pkg/tools"), true},
+		{"path prefix with slash", Filter{}.WithPathPrefix("source", "./pkg/tools/"), true},
+		{"exact path", Filter{}.WithPathPrefix("source", "pkg/tools/bash.go"), true},
+		{"sibling directory", Filter{}.WithPathPrefix("source", "pkg/to"), false},
+		{"all conditions", Filter{}.WithEquals("language", "go").WithPathPrefix("source", "pkg"), true},
+		{"one condition fails", Filter{}.WithEquals("language", "go").WithPathPrefix("source", "cmd"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.filter.Matches(metadata))
+		})
+	}
+}
+
+func TestFilterBuildersDoNotAlias(t *testing.T) {
+	base := Filter{}.WithEquals("language", "go")
+	narrowed := base.WithEquals("kind", "function")
+
+	assert.Len(t, base.Equals, 1)
+	assert.Len(t, narrowed.Equals, 2)
+	assert.Equal(t, "kind=function and language=go", narrowed.String())
+}
+
+func TestFilterFromMap(t *testing.T) {
+	filter := FilterFromMap(map[string]interface{}{
+		"language": []interface{}{"go", "markdown"},
+		"kind":     "function",
+		"line":     float64(3),
+	})
+	assert.Equal(t, map[string]string{"kind": "function", "line": "3"}, filter.Equals)
+	assert.Equal(t, map[string][]string{"language": {"go", "markdown"}}, filter.In)
+	assert.True(t, FilterFromMap(nil).IsEmpty())
+}
+
+func filterTestDocs() []Document {
+	return []Document{
+		{ID: "bash", Content: "bash tool", Metadata: map[string]interface{}{"source": "pkg/tools/bash.go", "language": "go"}},
+		{ID: "git", Content: "git tool", Metadata: map[string]interface{}{"source": "pkg/tools/git.go", "language": "go"}},
+		{ID: "toolset", Content: "tool set", Metadata: map[string]interface{}{"source": "pkg/toolset.go", "language": "go"}},
+		{ID: "readme", Content: "tools readme", Metadata: map[string]interface{}{"source": "pkg/tools/README.md", "language": "markdown"}},
+		{ID: "guide", Content: "guide", Metadata: map[string]interface{}{"source": "docs/guide.md", "language": "markdown"}},
+	}
+}
+
+func resultIDs(results []SearchResult) []string {
+	ids := make([]string, len(results))
+	for i, result := range results {
+		ids[i] = result.Document.ID
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestChromemSimilaritySearchWithFilter(t *testing.T) {
+	store, err := NewChromemStore(ChromemConfig{Embedder: embeddings.NewMockEmbedder(32)})
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, store.AddDocuments(ctx, filterTestDocs()))
+
+	tests := []struct {
+		name   string
+		k      int
+		filter Filter
+		want   []string
+	}{
+		{"only code under pkg/tools", 10, Filter{}.WithEquals("language", "go").WithPathPrefix("source", "pkg/tools"), []string{"bash", "git"}},
+		{"only docs", 10, Filter{}.WithEquals("language", "markdown"), []string{"guide", "readme"}},
+		{"not equals", 10, Filter{}.WithEquals("language", "go").WithNotEquals("source", "pkg/toolset.go"), []string{"bash", "git"}},
+		{"in set", 10, Filter{}.WithIn("source", "docs/guide.md", "pkg/toolset.go"), []string{"guide", "toolset"}},
+		{"k applies after filtering", 1, Filter{}.WithPathPrefix("source", "docs"), []string{"guide"}},
+		{"no match", 10, Filter{}.WithEquals("language", "rust"), []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := store.SimilaritySearchWithFilter(ctx, "tools", tt.k, 0, tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, resultIDs(results))
+		})
+	}
+
+	results, err := store.SimilaritySearchWithFilter(ctx, "tools", 2, 0, Filter{}.WithIn("language", "go", "markdown"))
+	require.NoError(t, err)
+	assert.Len(t, results, 2, "post-filtered searches still honour k")
+}
+
+func TestVectorStoreRetrieverAppliesFilters(t *testing.T) {
+	store, err := NewChromemStore(ChromemConfig{Embedder: embeddings.NewMockEmbedder(32)})
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, store.AddDocuments(ctx, filterTestDocs()))
+
+	retriever := NewVectorStoreRetriever(store, RetrieverConfig{
+		K:       10,
+		Filters: map[string]interface{}{"language": "markdown"},
+	})
+	results, err := retriever.GetRelevantDocumentsWithScore(ctx, "tools")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"guide", "readme"}, resultIDs(results))
+}
+
+func TestMockSimilaritySearchWithFilter(t *testing.T) {
+	store := NewMockVectorStore()
+	ctx := context.Background()
+	_, err := store.AddDocuments(ctx, []schema.Document{
+		{PageContent: "a", Metadata: map[string]any{"source": "pkg/tools/a.go"}},
+		{PageContent: "b", Metadata: map[string]any{"source": "docs/b.md"}},
+		{PageContent: "c", Metadata: map[string]any{"source": "pkg/tools/c.go"}},
+	})
+	require.NoError(t, err)
+
+	filter := Filter{}.WithPathPrefix("source", "pkg/tools")
+	docs, err := store.SimilaritySearchWithFilter(ctx, "q", 5, filter)
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+	assert.Equal(t, "c", docs[0].PageContent)
+
+	docs, err = store.SimilaritySearch(ctx, "q", 5, vectorstores.WithFilters(Filter{}.WithEquals("source", "docs/b.md")))
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Equal(t, "b", docs[0].PageContent)
+}
@@ -33,6 +33,9 @@ type VectorStore interface {
 	// SimilaritySearchWithScore performs a similarity search with scores
 	SimilaritySearchWithScore(ctx context.Context, query string, k int, scoreThreshold float32) ([]SearchResult, error)
 
+	// SimilaritySearchWithFilter performs a similarity search over documents whose metadata matches filter
+	SimilaritySearchWithFilter(ctx context.Context, query string, k int, scoreThreshold float32, filter Filter) ([]SearchResult, error)
+
 	// Clear removes all documents from the store
 	Clear(ctx context.Context) error
 
@@ -43,6 +46,26 @@ type VectorStore interface {
 	Close() error
 }
 
+// KeywordSearcher is implemented by stores that can also rank documents by keyword relevance
+type KeywordSearcher interface {
+	// KeywordSearch returns up to k documents matching filter, best first
+	KeywordSearch(ctx context.Context, query string, k int, filter Filter) ([]SearchResult, error)
+}
+
+// DocumentLister is implemented by stores that can enumerate their documents, which snapshots need
+type DocumentLister interface {
+	// Documents returns every document of the collection with its vector
+	Documents(ctx context.Context) ([]Document, error)
+}
+
+// DocumentWalker is implemented by stores that can stream their documents,
+// so an export does not hold the whole collection in memory
+type DocumentWalker interface {
+	// WalkDocuments calls fn with every document of the collection and its
+	// vector, stopping at the first error fn returns
+	WalkDocuments(ctx context.Context, fn func(Document) error) error
+}
+
 // Retriever defines the interface for document retrieval
 type Retriever interface {
 	// GetRelevantDocuments retrieves relevant documents for a query
@@ -63,7 +86,7 @@ type RetrieverConfig struct {
 	// Maximum context length for augmentation
 	MaxContextLength int
 
-	// Metadata filters
+	// Metadata filters; lists match any of their values (see FilterFromMap)
 	Filters map[string]interface{}
 }
 
@@ -86,7 +109,7 @@ func NewVectorStoreRetriever(store VectorStore, config RetrieverConfig) *VectorS
 
 // GetRelevantDocuments retrieves relevant documents for a query
 func (r *VectorStoreRetriever) GetRelevantDocuments(ctx context.Context, query string) ([]Document, error) {
-	results, err := r.store.SimilaritySearchWithScore(ctx, query, r.config.K, r.config.ScoreThreshold)
+	results, err := r.GetRelevantDocumentsWithScore(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -100,5 +123,5 @@ func (r *VectorStoreRetriever) GetRelevantDocuments(ctx context.Context, query s
 
 // GetRelevantDocumentsWithScore retrieves documents with similarity scores
 func (r *VectorStoreRetriever) GetRelevantDocumentsWithScore(ctx context.Context, query string) ([]SearchResult, error) {
-	return r.store.SimilaritySearchWithScore(ctx, query, r.config.K, r.config.ScoreThreshold)
+	return r.store.SimilaritySearchWithFilter(ctx, query, r.config.K, r.config.ScoreThreshold, FilterFromMap(r.config.Filters))
 }
@@ -0,0 +1,131 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/killallgit/ryan/pkg/logger"
+)
+
+// KeywordStore keeps a BM25 index in step with a vector store so the same
+// documents can be searched by meaning and by keyword
+type KeywordStore struct {
+	VectorStore
+	index *BM25Index
+	path  string // empty for in-memory only
+	dirty bool
+	mu    sync.Mutex
+}
+
+// KeywordIndexPath returns where the keyword index of a collection is kept, next to its vectors
+func KeywordIndexPath(storeDir, collection string) string {
+	return filepath.Join(storeDir, collection+".bm25.json")
+}
+
+// NewKeywordStore wraps store with a keyword index persisted at path (empty for in-memory only).
+// The index is written when the store is closed.
+func NewKeywordStore(store VectorStore, path string) (*KeywordStore, error) {
+	index := NewBM25Index()
+	if path != "" {
+		var err error
+		if index, err = LoadBM25Index(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if count, err := store.Count(context.Background()); err == nil && count != index.Len() {
+		logger.Warn("Keyword index has %d of %d documents; run ryan index --clear and re-index to rebuild it", index.Len(), count)
+	}
+
+	return &KeywordStore{VectorStore: store, index: index, path: path}, nil
+}
+
+// AddDocuments adds documents to the vector store and the keyword index
+func (s *KeywordStore) AddDocuments(ctx context.Context, documents []Document) error {
+	if err := s.VectorStore.AddDocuments(ctx, documents); err != nil {
+		return err
+	}
+	s.index.Add(documents)
+	s.markDirty()
+	return nil
+}
+
+// DeleteDocuments removes documents from the vector store and the keyword index
+func (s *KeywordStore) DeleteDocuments(ctx context.Context, ids []string) error {
+	if err := s.VectorStore.DeleteDocuments(ctx, ids); err != nil {
+		return err
+	}
+	s.index.Delete(ids)
+	s.markDirty()
+	return nil
+}
+
+// Clear removes all documents from the vector store and the keyword index
+func (s *KeywordStore) Clear(ctx context.Context) error {
+	if err := s.VectorStore.Clear(ctx); err != nil {
+		return err
+	}
+	s.index.Reset()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dirty = false
+	if s.path != "" {
+		if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove keyword index: %w", err)
+		}
+	}
+	return nil
+}
+
+// Documents returns every document of the wrapped store with its vector
+func (s *KeywordStore) Documents(ctx context.Context) ([]Document, error) {
+	lister, ok := s.VectorStore.(DocumentLister)
+	if !ok {
+		return nil, errNotListable
+	}
+	return lister.Documents(ctx)
+}
+
+// WalkDocuments streams the documents of the wrapped store with their vectors
+func (s *KeywordStore) WalkDocuments(ctx context.Context, fn func(Document) error) error {
+	return walkDocuments(ctx, s.VectorStore, fn)
+}
+
+// KeywordSearch ranks documents matching filter by BM25 keyword relevance
+func (s *KeywordStore) KeywordSearch(ctx context.Context, query string, k int, filter Filter) ([]SearchResult, error) {
+	return s.index.Search(query, k, filter), nil
+}
+
+// Flush writes the keyword index if it changed since it was last written
+func (s *KeywordStore) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dirty || s.path == "" {
+		return nil
+	}
+	if err := s.index.Save(s.path); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
+
+// Close writes the keyword index and closes the vector store
+func (s *KeywordStore) Close() error {
+	flushErr := s.Flush()
+	if err := s.VectorStore.Close(); err != nil {
+		return err
+	}
+	return flushErr
+}
+
+func (s *KeywordStore) markDirty() {
+	s.mu.Lock()
+	s.dirty = true
+	s.mu.Unlock()
+}
@@ -53,20 +53,28 @@ func (m *MockVectorStore) AddDocuments(ctx context.Context, docs []schema.Docume
 	return ids, nil
 }
 
-// SimilaritySearch performs a mock similarity search
+// SimilaritySearch performs a mock similarity search. A Filter passed with
+// vectorstores.WithFilters restricts the documents that can be returned.
 func (m *MockVectorStore) SimilaritySearch(ctx context.Context, query string, numDocuments int, options ...vectorstores.Option) ([]schema.Document, error) {
+	var opts vectorstores.Options
+	for _, option := range options {
+		option(&opts)
+	}
+	filter, _ := opts.Filters.(Filter)
+	return m.SimilaritySearchWithFilter(ctx, query, numDocuments, filter)
+}
+
+// SimilaritySearchWithFilter returns the most recent documents whose metadata matches filter
+func (m *MockVectorStore) SimilaritySearchWithFilter(ctx context.Context, query string, numDocuments int, filter Filter) ([]schema.Document, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	// For mock, just return the most recent documents
-	if numDocuments > len(m.documents) {
-		numDocuments = len(m.documents)
-	}
-
 	// Return documents in reverse order (most recent first)
-	results := make([]schema.Document, 0, numDocuments)
+	results := []schema.Document{}
 	for i := len(m.documents) - 1; i >= 0 && len(results) < numDocuments; i-- {
-		results = append(results, m.documents[i])
+		if filter.Matches(m.documents[i].Metadata) {
+			results = append(results, m.documents[i])
+		}
 	}
 
 	return results, nil
@@ -0,0 +1,270 @@
+package vectorstore
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// snapshotVersion is bumped when the snapshot format changes
+const snapshotVersion = 1
+
+// snapshotBatchSize is how many documents are added to the store at a time on import
+const snapshotBatchSize = 256
+
+// maxSnapshotLine bounds one document line of a snapshot
+const maxSnapshotLine = 64 << 20
+
+var errNotListable = errors.New("vector store cannot list its documents for export")
+
+// SnapshotHeader describes the contents of a snapshot and the embedder that produced its vectors
+type SnapshotHeader struct {
+	Version           int             `json:"version"`
+	Created           time.Time       `json:"created"`
+	Collection        string          `json:"collection"`
+	Store             string          `json:"store"` // Provider exported from, for information only
+	EmbeddingProvider string          `json:"embedding_provider"`
+	EmbeddingModel    string          `json:"embedding_model"`
+	Dimensions        int             `json:"dimensions"`
+	Documents         int             `json:"documents"`
+	Manifest          json.RawMessage `json:"manifest,omitempty"` // Index manifest, opaque to the store
+}
+
+// CheckCompatible reports whether vectors in the snapshot can be searched with
+// queries embedded by the given embedder. Dimensions must always agree; a
+// different provider or model is allowed only when force is set, since its
+// vectors live in a different space even at the same size.
+func (h SnapshotHeader) CheckCompatible(provider, model string, dimensions int, force bool) error {
+	if h.Dimensions != dimensions {
+		return fmt.Errorf("snapshot has %d-dimensional vectors but the configured embedder produces %d", h.Dimensions, dimensions)
+	}
+	if force {
+		return nil
+	}
+	if h.EmbeddingProvider != provider || modelName(h.EmbeddingModel) != modelName(model) {
+		return fmt.Errorf("snapshot was embedded with %s, but %s is configured", h.EmbedderName(), describeEmbedder(provider, model))
+	}
+	return nil
+}
+
+// EmbedderName names the embedder the snapshot was made with, such as "ollama/nomic-embed-text"
+func (h SnapshotHeader) EmbedderName() string {
+	return describeEmbedder(h.EmbeddingProvider, h.EmbeddingModel)
+}
+
+// modelName drops the default tag, so "nomic-embed-text" and "nomic-embed-text:latest" compare equal
+func modelName(model string) string {
+	return strings.TrimSuffix(model, ":latest")
+}
+
+func describeEmbedder(provider, model string) string {
+	if model == "" {
+		return provider
+	}
+	return provider + "/" + model
+}
+
+// snapshotDocument is one document line of a snapshot
+type snapshotDocument struct {
+	ID       string                 `json:"id"`
+	Content  string                 `json:"content"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	Vector   []byte                 `json:"vector"` // Little-endian float32s
+}
+
+// ExportSnapshot writes every document of store with its vector to w as a
+// gzip-compressed stream of JSON lines: the header, then one line per
+// document. The version, creation time, dimensions and document count of
+// header are filled in; the written header is returned. Stores that can
+// stream their documents are read twice, once to fill in the header and once
+// to write the documents, rather than held in memory.
+func ExportSnapshot(ctx context.Context, store VectorStore, w io.Writer, header SnapshotHeader) (SnapshotHeader, error) {
+	header.Version = snapshotVersion
+	header.Created = time.Now().UTC()
+	header.Documents = 0
+	err := walkDocuments(ctx, store, func(doc Document) error {
+		if len(doc.Vector) == 0 {
+			return fmt.Errorf("document %s has no vector", doc.ID)
+		}
+		if header.Dimensions == 0 {
+			header.Dimensions = len(doc.Vector)
+		}
+		if len(doc.Vector) != header.Dimensions {
+			return fmt.Errorf("document %s has %d dimensions, expected %d", doc.ID, len(doc.Vector), header.Dimensions)
+		}
+		header.Documents++
+		return nil
+	})
+	if err != nil {
+		return header, err
+	}
+
+	gz := gzip.NewWriter(w)
+	encoder := json.NewEncoder(gz)
+	if err := encoder.Encode(header); err != nil {
+		return header, fmt.Errorf("failed to write snapshot header: %w", err)
+	}
+	written := 0
+	err = walkDocuments(ctx, store, func(doc Document) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if len(doc.Vector) != header.Dimensions {
+			return fmt.Errorf("document %s has %d dimensions, expected %d", doc.ID, len(doc.Vector), header.Dimensions)
+		}
+		line := snapshotDocument{ID: doc.ID, Content: doc.Content, Metadata: doc.Metadata, Vector: encodeVector(doc.Vector)}
+		if err := encoder.Encode(line); err != nil {
+			return fmt.Errorf("failed to write document %s: %w", doc.ID, err)
+		}
+		written++
+		return nil
+	})
+	if err != nil {
+		return header, err
+	}
+	if written != header.Documents {
+		return header, fmt.Errorf("collection changed during export: wrote %d of %d documents", written, header.Documents)
+	}
+	if err := gz.Close(); err != nil {
+		return header, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return header, nil
+}
+
+// walkDocuments calls fn with every document of store, streaming them when the store supports it
+func walkDocuments(ctx context.Context, store VectorStore, fn func(Document) error) error {
+	if walker, ok := store.(DocumentWalker); ok {
+		return walker.WalkDocuments(ctx, fn)
+	}
+	lister, ok := store.(DocumentLister)
+	if !ok {
+		return errNotListable
+	}
+	documents, err := lister.Documents(ctx)
+	if err != nil {
+		return err
+	}
+	for _, doc := range documents {
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportSnapshot adds the documents of the snapshot in r to store with their
+// vectors, so nothing is embedded again. check is called with the header
+// before any document is added and stops the import if it returns an error.
+// If the import fails part way, the documents it added are removed again.
+func ImportSnapshot(ctx context.Context, store VectorStore, r io.Reader, check func(SnapshotHeader) error) (SnapshotHeader, error) {
+	var added []string
+	header, err := readSnapshot(ctx, r, check, func(batch []Document) error {
+		if err := store.AddDocuments(ctx, batch); err != nil {
+			return fmt.Errorf("failed to import documents: %w", err)
+		}
+		for _, doc := range batch {
+			added = append(added, doc.ID)
+		}
+		return nil
+	})
+	if err != nil && len(added) > 0 {
+		// The import context may be what failed, so clean up without it
+		if deleteErr := store.DeleteDocuments(context.Background(), added); deleteErr != nil {
+			return header, fmt.Errorf("%w (and failed to remove the %d imported documents: %v)", err, len(added), deleteErr)
+		}
+	}
+	return header, err
+}
+
+// VerifySnapshot reads the whole snapshot in r without importing it, so a
+// truncated or corrupt file is found before an existing index is discarded
+func VerifySnapshot(ctx context.Context, r io.Reader) (SnapshotHeader, error) {
+	return readSnapshot(ctx, r, nil, nil)
+}
+
+// readSnapshot decodes the snapshot in r, passing its documents to add in
+// batches when add is set. Every document is checked against the header.
+func readSnapshot(ctx context.Context, r io.Reader, check func(SnapshotHeader) error, add func([]Document) error) (SnapshotHeader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return SnapshotHeader{}, fmt.Errorf("not a snapshot: %w", err)
+	}
+	defer gz.Close()
+
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSnapshotLine)
+
+	var header SnapshotHeader
+	if !scanner.Scan() {
+		return header, fmt.Errorf("not a snapshot: %w", scannerErr(scanner))
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return header, fmt.Errorf("not a snapshot: %w", err)
+	}
+	if header.Version != snapshotVersion {
+		return header, fmt.Errorf("snapshot has version %d, expected %d", header.Version, snapshotVersion)
+	}
+	if check != nil {
+		if err := check(header); err != nil {
+			return header, err
+		}
+	}
+
+	read := 0
+	batch := make([]Document, 0, snapshotBatchSize)
+	flush := func() error {
+		if len(batch) == 0 || add == nil {
+			batch = batch[:0]
+			return nil
+		}
+		if err := add(batch); err != nil {
+			return err
+		}
+		batch = batch[:0]
+		return nil
+	}
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return header, err
+		}
+		var line snapshotDocument
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return header, fmt.Errorf("failed to read document %d of snapshot: %w", read+1, err)
+		}
+		vector := decodeVector(line.Vector)
+		if len(vector) != header.Dimensions {
+			return header, fmt.Errorf("document %s has %d dimensions, expected %d", line.ID, len(vector), header.Dimensions)
+		}
+		read++
+		batch = append(batch, Document{ID: line.ID, Content: line.Content, Metadata: line.Metadata, Vector: vector})
+		if len(batch) == snapshotBatchSize {
+			if err := flush(); err != nil {
+				return header, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return header, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	// A truncated snapshot is rejected before its last batch is added
+	if read != header.Documents {
+		return header, fmt.Errorf("snapshot is truncated: read %d of %d documents", read, header.Documents)
+	}
+	if err := flush(); err != nil {
+		return header, err
+	}
+	return header, nil
+}
+
+func scannerErr(scanner *bufio.Scanner) error {
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.ErrUnexpectedEOF
+}
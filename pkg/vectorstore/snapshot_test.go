@@ -0,0 +1,148 @@
+package vectorstore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/killallgit/ryan/pkg/embeddings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotAcrossBackends(t *testing.T) {
+	embedder := embeddings.NewMockEmbedder(64)
+	ctx := context.Background()
+	docs := filterTestDocs()
+
+	source, err := NewChromemStore(ChromemConfig{CollectionName: "test", Embedder: embedder})
+	require.NoError(t, err)
+	require.NoError(t, source.AddDocuments(ctx, docs))
+
+	var buf bytes.Buffer
+	header, err := ExportSnapshot(ctx, source, &buf, SnapshotHeader{
+		Collection:        "test",
+		EmbeddingProvider: "ollama",
+		EmbeddingModel:    "nomic-embed-text",
+		Manifest:          json.RawMessage(`{"version":1}`),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, len(docs), header.Documents)
+	assert.Equal(t, 64, header.Dimensions)
+
+	// The target has no embedder, so every vector must come from the snapshot
+	target, err := NewSQLiteStore(SQLiteConfig{CollectionName: "test"})
+	require.NoError(t, err)
+	defer target.Close()
+
+	var checked SnapshotHeader
+	imported, err := ImportSnapshot(ctx, target, bytes.NewReader(buf.Bytes()), func(h SnapshotHeader) error {
+		checked = h
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, header.Documents, imported.Documents)
+	assert.Equal(t, "ollama/nomic-embed-text", checked.EmbedderName())
+	assert.JSONEq(t, `{"version":1}`, string(checked.Manifest))
+
+	exported, err := source.Documents(ctx)
+	require.NoError(t, err)
+	got, err := target.Documents(ctx)
+	require.NoError(t, err)
+	require.Len(t, got, len(exported))
+	for i := range exported {
+		assert.Equal(t, exported[i].ID, got[i].ID)
+		assert.Equal(t, exported[i].Content, got[i].Content)
+		assert.Equal(t, exported[i].Metadata, got[i].Metadata)
+		assert.InDeltaSlice(t, exported[i].Vector, got[i].Vector, 1e-6)
+	}
+}
+
+func TestImportSnapshotStopsOnCheck(t *testing.T) {
+	ctx := context.Background()
+	source, err := NewSQLiteStore(SQLiteConfig{CollectionName: "test", Embedder: embeddings.NewMockEmbedder(8)})
+	require.NoError(t, err)
+	defer source.Close()
+	require.NoError(t, source.AddDocuments(ctx, filterTestDocs()))
+
+	var buf bytes.Buffer
+	_, err = ExportSnapshot(ctx, source, &buf, SnapshotHeader{EmbeddingProvider: "local"})
+	require.NoError(t, err)
+
+	target, err := NewSQLiteStore(SQLiteConfig{CollectionName: "test"})
+	require.NoError(t, err)
+	defer target.Close()
+
+	_, err = ImportSnapshot(ctx, target, bytes.NewReader(buf.Bytes()), func(h SnapshotHeader) error {
+		return h.CheckCompatible("local", "", 16, false)
+	})
+	assert.ErrorContains(t, err, "8-dimensional")
+	count, err := target.Count(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count, "nothing is imported when the check fails")
+
+	// A truncated snapshot is reported rather than half imported silently
+	_, err = ImportSnapshot(ctx, target, bytes.NewReader(buf.Bytes()[:buf.Len()/2]), nil)
+	assert.Error(t, err)
+
+	_, err = ImportSnapshot(ctx, target, bytes.NewReader([]byte("not gzip")), nil)
+	assert.ErrorContains(t, err, "not a snapshot")
+}
+
+func TestImportSnapshotCountsDocuments(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	fmt.Fprintln(gz, `{"version":1,"dimensions":2,"documents":2}`)
+	fmt.Fprintln(gz, `{"id":"a","content":"a","vector":"AACAPwAAAAA="}`)
+	require.NoError(t, gz.Close())
+
+	target, err := NewSQLiteStore(SQLiteConfig{CollectionName: "test"})
+	require.NoError(t, err)
+	defer target.Close()
+
+	_, err = ImportSnapshot(context.Background(), target, &buf, nil)
+	assert.ErrorContains(t, err, "read 1 of 2")
+}
+
+func TestImportSnapshotRemovesPartialImport(t *testing.T) {
+	// More documents than one batch, with a corrupt line after the first batch
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	documents := snapshotBatchSize + 10
+	fmt.Fprintf(gz, `{"version":1,"dimensions":2,"documents":%d}`+"\n", documents)
+	for i := 0; i < documents; i++ {
+		if i == documents-5 {
+			fmt.Fprintln(gz, `{"id": broken`)
+			continue
+		}
+		fmt.Fprintf(gz, `{"id":"doc-%d","content":"c","vector":"AACAPwAAAAA="}`+"\n", i)
+	}
+	require.NoError(t, gz.Close())
+
+	ctx := context.Background()
+	_, err := VerifySnapshot(ctx, bytes.NewReader(buf.Bytes()))
+	assert.ErrorContains(t, err, fmt.Sprintf("document %d of snapshot", documents-4))
+
+	target, err := NewSQLiteStore(SQLiteConfig{CollectionName: "test"})
+	require.NoError(t, err)
+	defer target.Close()
+	require.NoError(t, target.AddDocuments(ctx, []Document{{ID: "kept", Content: "kept", Vector: []float32{0, 1}}}))
+
+	_, err = ImportSnapshot(ctx, target, bytes.NewReader(buf.Bytes()), nil)
+	assert.Error(t, err)
+	count, err := target.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count, "documents added before the failure are removed")
+}
+
+func TestSnapshotCheckCompatible(t *testing.T) {
+	header := SnapshotHeader{EmbeddingProvider: "ollama", EmbeddingModel: "nomic-embed-text", Dimensions: 768}
+
+	assert.NoError(t, header.CheckCompatible("ollama", "nomic-embed-text:latest", 768, false))
+	assert.ErrorContains(t, header.CheckCompatible("ollama", "mxbai-embed-large", 768, false), "embedded with ollama/nomic-embed-text")
+	assert.NoError(t, header.CheckCompatible("ollama", "mxbai-embed-large", 768, true))
+	assert.Error(t, header.CheckCompatible("ollama", "nomic-embed-text", 1024, true), "dimensions must agree even when forced")
+}
@@ -0,0 +1,545 @@
+package vectorstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/killallgit/ryan/pkg/embeddings"
+	_ "github.com/mattn/go-sqlite3" // sqlite3 driver
+)
+
+const (
+	// defaultSQLiteBatchSize is how many documents are written per transaction
+	defaultSQLiteBatchSize = 256
+
+	// quantizedCandidateFactor is how many candidates per result the quantized
+	// scan keeps for exact rescoring
+	quantizedCandidateFactor = 4
+)
+
+// SQLiteStore implements VectorStore on a single SQLite database file. Several
+// collections can share one file; each document row holds its content,
+// metadata and vector, plus an int8 copy of the vector for quantized scans.
+type SQLiteStore struct {
+	db         *sql.DB
+	embedder   embeddings.Embedder
+	collection string
+	batchSize  int
+	quantize   bool
+	mu         sync.RWMutex
+}
+
+// SQLiteConfig contains configuration for SQLiteStore
+type SQLiteConfig struct {
+	// Path is the database file (empty for in-memory only)
+	Path string
+
+	// CollectionName is the name of the collection to use
+	CollectionName string
+
+	// Embedder to use for creating embeddings. Without one the store can
+	// still list, count and delete documents and store pre-computed vectors.
+	Embedder embeddings.Embedder
+
+	// BatchSize is how many documents are written per transaction (default 256)
+	BatchSize int
+
+	// Quantize ranks documents by their int8 vectors first and rescores only
+	// the best candidates exactly, reading a quarter of the vector data
+	Quantize bool
+}
+
+// SQLitePath returns the database file kept in a persistence directory
+func SQLitePath(dir string) string {
+	return filepath.Join(dir, "vectors.db")
+}
+
+// NewSQLiteStore opens or creates a SQLiteStore
+func NewSQLiteStore(config SQLiteConfig) (*SQLiteStore, error) {
+	if config.CollectionName == "" {
+		config.CollectionName = "default"
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultSQLiteBatchSize
+	}
+
+	dsn := "file::memory:"
+	if config.Path != "" {
+		if err := os.MkdirAll(filepath.Dir(config.Path), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create vector database directory: %w", err)
+		}
+		dsn = fmt.Sprintf("file:%s?mode=rwc&_journal_mode=WAL&_busy_timeout=5000", config.Path)
+	}
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vector database: %w", err)
+	}
+	if config.Path == "" {
+		// Every connection to :memory: is a separate database
+		db.SetMaxOpenConns(1)
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS documents (
+		collection TEXT NOT NULL,
+		id TEXT NOT NULL,
+		content TEXT NOT NULL,
+		metadata TEXT NOT NULL,
+		embedding BLOB NOT NULL,
+		quantized BLOB NOT NULL,
+		PRIMARY KEY (collection, id)
+	)`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create documents table: %w", err)
+	}
+
+	return &SQLiteStore{
+		db:         db,
+		embedder:   config.Embedder,
+		collection: config.CollectionName,
+		batchSize:  config.BatchSize,
+		quantize:   config.Quantize,
+	}, nil
+}
+
+// AddDocuments adds documents to the vector store, replacing any with the same ID.
+// Documents without a vector are embedded first; rows are written in batches,
+// each batch in one transaction.
+func (s *SQLiteStore) AddDocuments(ctx context.Context, documents []Document) error {
+	if len(documents) == 0 {
+		return nil
+	}
+
+	vectors, err := embedDocuments(ctx, s.embedder, documents)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for start := 0; start < len(documents); start += s.batchSize {
+		end := min(start+s.batchSize, len(documents))
+		if err := s.insert(ctx, documents[start:end], vectors[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStore) insert(ctx context.Context, documents []Document, vectors [][]float32) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO documents (collection, id, content, metadata, embedding, quantized)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (collection, id) DO UPDATE SET
+			content = excluded.content,
+			metadata = excluded.metadata,
+			embedding = excluded.embedding,
+			quantized = excluded.quantized`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for i, doc := range documents {
+		// Metadata is kept as strings, as in the chromem store, so filters compare the same way
+		metadata := make(map[string]string, len(doc.Metadata))
+		for k, v := range doc.Metadata {
+			if str, ok := v.(string); ok {
+				metadata[k] = str
+			} else {
+				metadata[k] = fmt.Sprintf("%v", v)
+			}
+		}
+		encoded, err := json.Marshal(metadata)
+		if err != nil {
+			return fmt.Errorf("failed to encode metadata of %s: %w", doc.ID, err)
+		}
+
+		vector := normalize(vectors[i])
+		if _, err := stmt.ExecContext(ctx, s.collection, doc.ID, doc.Content, string(encoded),
+			encodeVector(vector), quantizeVector(vector)); err != nil {
+			return fmt.Errorf("failed to store document %s: %w", doc.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit documents: %w", err)
+	}
+	return nil
+}
+
+// DeleteDocuments removes documents by their IDs
+func (s *SQLiteStore) DeleteDocuments(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `DELETE FROM documents WHERE collection = ? AND id = ?`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare delete: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, id := range ids {
+		if _, err := stmt.ExecContext(ctx, s.collection, id); err != nil {
+			return fmt.Errorf("failed to delete document %s: %w", id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit delete: %w", err)
+	}
+	return nil
+}
+
+// SimilaritySearch performs a similarity search
+func (s *SQLiteStore) SimilaritySearch(ctx context.Context, query string, k int) ([]SearchResult, error) {
+	return s.SimilaritySearchWithScore(ctx, query, k, 0)
+}
+
+// SimilaritySearchWithScore performs a similarity search with scores
+func (s *SQLiteStore) SimilaritySearchWithScore(ctx context.Context, query string, k int, scoreThreshold float32) ([]SearchResult, error) {
+	return s.SimilaritySearchWithFilter(ctx, query, k, scoreThreshold, Filter{})
+}
+
+// SimilaritySearchWithFilter performs a similarity search over documents matching filter.
+// Equality conditions run in SQL; the rest are checked on each scanned row.
+func (s *SQLiteStore) SimilaritySearchWithFilter(ctx context.Context, query string, k int, scoreThreshold float32, filter Filter) ([]SearchResult, error) {
+	if k <= 0 {
+		return nil, nil
+	}
+
+	if s.embedder == nil {
+		return nil, fmt.Errorf("embedder is required to search")
+	}
+	embedding, err := s.embedder.EmbedText(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	queryVector := normalize(embedding)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	column := "embedding"
+	if s.quantize {
+		column = "quantized"
+	}
+	where, args := s.where(filter)
+	rows, err := s.db.QueryContext(ctx, `SELECT id, metadata, `+column+` FROM documents WHERE `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	type candidate struct {
+		id       string
+		metadata map[string]interface{}
+		score    float32
+	}
+	var candidates []candidate
+	for rows.Next() {
+		var c candidate
+		var metadata string
+		var blob []byte
+		if err := rows.Scan(&c.id, &metadata, &blob); err != nil {
+			return nil, fmt.Errorf("failed to read document: %w", err)
+		}
+		if c.metadata, err = decodeMetadata(metadata); err != nil {
+			return nil, fmt.Errorf("failed to decode metadata of %s: %w", c.id, err)
+		}
+		if !filter.Matches(c.metadata) {
+			continue
+		}
+
+		if s.quantize {
+			c.score = quantizedDot(queryVector, blob)
+		} else {
+			c.score = dot(queryVector, decodeVector(blob))
+		}
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	sort.SliceStable(candidates, func(a, b int) bool { return candidates[a].score > candidates[b].score })
+	keep := k
+	if s.quantize {
+		keep = k * quantizedCandidateFactor
+	}
+	if len(candidates) > keep {
+		candidates = candidates[:keep]
+	}
+
+	// Load the full rows of the best candidates, rescoring quantized ones exactly
+	lookup, err := s.db.PrepareContext(ctx, `SELECT content, embedding FROM documents WHERE collection = ? AND id = ?`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare lookup: %w", err)
+	}
+	defer lookup.Close()
+
+	results := make([]SearchResult, 0, len(candidates))
+	for _, c := range candidates {
+		var content string
+		var blob []byte
+		if err := lookup.QueryRowContext(ctx, s.collection, c.id).Scan(&content, &blob); err != nil {
+			return nil, fmt.Errorf("failed to read document %s: %w", c.id, err)
+		}
+		vector := decodeVector(blob)
+		score := dot(queryVector, vector)
+		if scoreThreshold > 0 && score < scoreThreshold {
+			continue
+		}
+		results = append(results, SearchResult{
+			Document: Document{
+				ID:       c.id,
+				Content:  content,
+				Metadata: c.metadata,
+				Vector:   vector,
+			},
+			Score:    score,
+			Distance: 1 - score,
+		})
+	}
+
+	sort.SliceStable(results, func(a, b int) bool { return results[a].Score > results[b].Score })
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results, nil
+}
+
+// where builds the row condition for the collection and the filter's equality conditions
+func (s *SQLiteStore) where(filter Filter) (string, []interface{}) {
+	conditions := []string{"collection = ?"}
+	args := []interface{}{s.collection}
+
+	add := func(condition string, values map[string]string) {
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			conditions = append(conditions, condition)
+			args = append(args, `$."`+strings.ReplaceAll(key, `"`, `\"`)+`"`, values[key])
+		}
+	}
+	add("json_extract(metadata, ?) = ?", filter.Equals)
+	// IS NOT keeps documents without the key, as Filter.Matches does
+	add("json_extract(metadata, ?) IS NOT ?", filter.NotEquals)
+	return strings.Join(conditions, " AND "), args
+}
+
+// List returns the documents of the collection matching filter, without their vectors
+func (s *SQLiteStore) List(ctx context.Context, filter Filter) ([]Document, error) {
+	return s.list(ctx, filter, false)
+}
+
+// Documents returns every document of the collection with its vector
+func (s *SQLiteStore) Documents(ctx context.Context) ([]Document, error) {
+	return s.list(ctx, Filter{}, true)
+}
+
+// WalkDocuments calls fn with every document of the collection and its vector,
+// in ID order, reading them one at a time
+func (s *SQLiteStore) WalkDocuments(ctx context.Context, fn func(Document) error) error {
+	return s.walk(ctx, Filter{}, true, fn)
+}
+
+func (s *SQLiteStore) list(ctx context.Context, filter Filter, withVectors bool) ([]Document, error) {
+	var documents []Document
+	err := s.walk(ctx, filter, withVectors, func(doc Document) error {
+		documents = append(documents, doc)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+func (s *SQLiteStore) walk(ctx context.Context, filter Filter, withVectors bool, fn func(Document) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	columns := "id, content, metadata"
+	if withVectors {
+		columns += ", embedding"
+	}
+	where, args := s.where(filter)
+	rows, err := s.db.QueryContext(ctx, `SELECT `+columns+` FROM documents WHERE `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to list documents: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var doc Document
+		var metadata string
+		var embedding []byte
+		dest := []interface{}{&doc.ID, &doc.Content, &metadata}
+		if withVectors {
+			dest = append(dest, &embedding)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return fmt.Errorf("failed to read document: %w", err)
+		}
+		if withVectors {
+			doc.Vector = decodeVector(embedding)
+		}
+		if doc.Metadata, err = decodeMetadata(metadata); err != nil {
+			return fmt.Errorf("failed to decode metadata of %s: %w", doc.ID, err)
+		}
+		if !filter.Matches(doc.Metadata) {
+			continue
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list documents: %w", err)
+	}
+	return nil
+}
+
+// Clear removes all documents of the collection
+func (s *SQLiteStore) Clear(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM documents WHERE collection = ?`, s.collection); err != nil {
+		return fmt.Errorf("failed to clear collection: %w", err)
+	}
+	return nil
+}
+
+// Count returns the number of documents in the collection
+func (s *SQLiteStore) Count(ctx context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var count int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM documents WHERE collection = ?`, s.collection).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count documents: %w", err)
+	}
+	return count, nil
+}
+
+// Close closes the database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+func decodeMetadata(encoded string) (map[string]interface{}, error) {
+	var stored map[string]string
+	if err := json.Unmarshal([]byte(encoded), &stored); err != nil {
+		return nil, err
+	}
+	metadata := make(map[string]interface{}, len(stored))
+	for k, v := range stored {
+		metadata[k] = v
+	}
+	return metadata, nil
+}
+
+// normalize returns v scaled to unit length, so cosine similarity is a dot product
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	norm := float32(math.Sqrt(sum))
+	normalized := make([]float32, len(v))
+	for i, x := range v {
+		normalized[i] = x / norm
+	}
+	return normalized
+}
+
+// dot returns the dot product of two vectors, or 0 when their dimensions differ
+func dot(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+func encodeVector(v []float32) []byte {
+	buf := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(x))
+	}
+	return buf
+}
+
+func decodeVector(buf []byte) []float32 {
+	v := make([]float32, len(buf)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return v
+}
+
+// quantizeVector stores a unit vector as a float32 scale followed by one int8 per dimension
+func quantizeVector(v []float32) []byte {
+	var scale float32
+	for _, x := range v {
+		scale = max(scale, float32(math.Abs(float64(x))))
+	}
+	buf := make([]byte, 4+len(v))
+	binary.LittleEndian.PutUint32(buf, math.Float32bits(scale/127))
+	if scale == 0 {
+		return buf
+	}
+	for i, x := range v {
+		buf[4+i] = byte(int8(math.Round(float64(x / scale * 127))))
+	}
+	return buf
+}
+
+// quantizedDot approximates the dot product of v with a quantized vector
+func quantizedDot(v []float32, quantized []byte) float32 {
+	if len(quantized) != 4+len(v) {
+		return 0
+	}
+	step := math.Float32frombits(binary.LittleEndian.Uint32(quantized))
+	var sum float32
+	for i, x := range v {
+		sum += x * float32(int8(quantized[4+i]))
+	}
+	return sum * step
+}
@@ -0,0 +1,124 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/killallgit/ryan/pkg/embeddings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vectors.db")
+	embedder := embeddings.NewMockEmbedder(64)
+	ctx := context.Background()
+	docs := filterTestDocs()
+
+	store, err := NewSQLiteStore(SQLiteConfig{Path: path, CollectionName: "test", Embedder: embedder, BatchSize: 2})
+	require.NoError(t, err)
+
+	require.NoError(t, store.AddDocuments(ctx, docs))
+	count, err := store.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, len(docs), count)
+
+	// Adding an existing ID replaces the document
+	require.NoError(t, store.AddDocuments(ctx, []Document{{ID: docs[0].ID, Content: "replaced", Metadata: map[string]interface{}{"language": "text"}}}))
+	count, err = store.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, len(docs), count, "upserts do not add rows")
+
+	results, err := store.SimilaritySearch(ctx, "replaced", 1)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, docs[0].ID, results[0].Document.ID)
+	assert.Equal(t, "replaced", results[0].Document.Content)
+	assert.InDelta(t, 1, results[0].Score, 1e-5)
+	assert.Equal(t, "text", results[0].Document.Metadata["language"])
+
+	// Another collection in the same file is independent
+	other, err := NewSQLiteStore(SQLiteConfig{Path: path, CollectionName: "other", Embedder: embedder})
+	require.NoError(t, err)
+	count, err = other.Count(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	require.NoError(t, other.Close())
+
+	require.NoError(t, store.DeleteDocuments(ctx, []string{docs[1].ID}))
+	require.NoError(t, store.Close())
+
+	// Counts survive a reopen, and so does Clear
+	store, err = NewSQLiteStore(SQLiteConfig{Path: path, CollectionName: "test", Embedder: embedder})
+	require.NoError(t, err)
+	defer store.Close()
+	count, err = store.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, len(docs)-1, count)
+
+	require.NoError(t, store.Clear(ctx))
+	count, err = store.Count(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	results, err = store.SimilaritySearch(ctx, "anything", 3)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestSQLiteStoreFilter(t *testing.T) {
+	for _, quantize := range []bool{false, true} {
+		t.Run(fmt.Sprintf("quantize=%v", quantize), func(t *testing.T) {
+			store, err := NewSQLiteStore(SQLiteConfig{Embedder: embeddings.NewMockEmbedder(64), Quantize: quantize})
+			require.NoError(t, err)
+			defer store.Close()
+			ctx := context.Background()
+			require.NoError(t, store.AddDocuments(ctx, filterTestDocs()))
+
+			results, err := store.SimilaritySearchWithFilter(ctx, "code", 10, 0, Filter{}.WithEquals("language", "go"))
+			require.NoError(t, err)
+			assert.Equal(t, []string{"bash", "git", "toolset"}, resultIDs(results))
+
+			results, err = store.SimilaritySearchWithFilter(ctx, "code", 10, 0, Filter{}.WithPathPrefix("source", "pkg/tools"))
+			require.NoError(t, err)
+			assert.Equal(t, []string{"bash", "git", "readme"}, resultIDs(results))
+
+			results, err = store.SimilaritySearchWithFilter(ctx, "code", 10, 0, Filter{}.WithNotEquals("language", "go").WithNotEquals("missing", "x"))
+			require.NoError(t, err)
+			assert.ElementsMatch(t, []string{"readme", "guide"}, resultIDs(results), "documents without the key are kept")
+
+			results, err = store.SimilaritySearchWithFilter(ctx, "code", 1, 0, Filter{}.WithEquals("language", "markdown").WithPathPrefix("source", "docs"))
+			require.NoError(t, err)
+			assert.Equal(t, []string{"guide"}, resultIDs(results))
+		})
+	}
+}
+
+func TestQuantizedSearchMatchesExact(t *testing.T) {
+	embedder := embeddings.NewMockEmbedder(128)
+	ctx := context.Background()
+
+	var docs []Document
+	for i := 0; i < 200; i++ {
+		docs = append(docs, Document{ID: fmt.Sprintf("doc-%d", i), Content: fmt.Sprintf("document number %d", i)})
+	}
+
+	exact, err := NewSQLiteStore(SQLiteConfig{Embedder: embedder})
+	require.NoError(t, err)
+	defer exact.Close()
+	quantized, err := NewSQLiteStore(SQLiteConfig{Embedder: embedder, Quantize: true})
+	require.NoError(t, err)
+	defer quantized.Close()
+	require.NoError(t, exact.AddDocuments(ctx, docs))
+	require.NoError(t, quantized.AddDocuments(ctx, docs))
+
+	want, err := exact.SimilaritySearch(ctx, "document number 42", 5)
+	require.NoError(t, err)
+	got, err := quantized.SimilaritySearch(ctx, "document number 42", 5)
+	require.NoError(t, err)
+	require.Len(t, got, 5)
+	assert.Equal(t, want[0].Document.ID, got[0].Document.ID)
+	assert.Equal(t, resultIDs(want), resultIDs(got))
+	assert.InDelta(t, want[0].Score, got[0].Score, 1e-6, "candidates are rescored exactly")
+}